@@ -9,10 +9,11 @@ import (
 	"syscall"
 	"time"
 
+	"TestAlchemy/internal/jobs"
 	"TestAlchemy/internal/server"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+func gracefulShutdown(apiServer *http.Server, workers *jobs.Pool, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -30,6 +31,14 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
 
+	// Let running jobs finish, cancelling and requeueing them if they take
+	// longer than the 30 seconds a process manager typically allows
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := workers.Drain(ctx); err != nil {
+		log.Printf("Job workers forced to stop with error: %v", err)
+	}
+
 	log.Println("Server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
@@ -38,13 +47,14 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 
 func main() {
 
-	server := server.NewServer()
+	server, workers := server.NewServer()
+	workers.Start()
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
 	// Run graceful shutdown in a separate goroutine
-	go gracefulShutdown(server, done)
+	go gracefulShutdown(server, workers, done)
 
 	err := server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
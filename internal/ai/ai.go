@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+)
+
+// ErrInvalidResponse is returned when a provider answers with something
+// that is not a usable test case.
+var ErrInvalidResponse = errors.New("invalid response from AI provider")
+
+// Image is a screenshot or mockup sent along with a prompt.
+type Image struct {
+	Data      []byte
+	MediaType string
+}
+
+// StepDraft is a generated test step.
+type StepDraft struct {
+	Action         string `json:"action"`
+	ExpectedResult string `json:"expected_result"`
+}
+
+// TestCaseDraft is a generated test case. It is not saved; the user reviews
+// it before creating the test case.
+type TestCaseDraft struct {
+	Title         string      `json:"title"`
+	Description   string      `json:"description"`
+	Preconditions string      `json:"preconditions"`
+	UserLevel     string      `json:"user_level"`
+	Priority      string      `json:"priority"`
+	Steps         []StepDraft `json:"steps"`
+	Tags          []string    `json:"tags"`
+}
+
+// FieldRequest asks for a better version of one field of a test case.
+type FieldRequest struct {
+	// Field names the field, such as "title" or "expected result"
+	Field string
+	// Value is the current value of the field, possibly empty
+	Value string
+	// TestCase is the whole test case, for context
+	TestCase TestCaseDraft
+}
+
+// Provider generates test case content.
+type Provider interface {
+	// GenerateTestCase drafts a complete test case from an image of the
+	// feature under test and a short description. The image is optional.
+	GenerateTestCase(ctx context.Context, image Image, description string) (TestCaseDraft, error)
+	// EnhanceField suggests a rewrite of one field of a test case.
+	EnhanceField(ctx context.Context, req FieldRequest) (string, error)
+}
+
+// New returns a provider for the OpenAI-compatible API at AI_BASE_URL when
+// it is set, and otherwise the deterministic fake provider.
+func New() Provider {
+	baseURL := os.Getenv("AI_BASE_URL")
+	if baseURL == "" {
+		log.Println("AI_BASE_URL is not set, using the fake AI provider")
+		return NewFake()
+	}
+
+	model := os.Getenv("AI_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return NewOpenAI(baseURL, os.Getenv("AI_API_KEY"), model)
+}
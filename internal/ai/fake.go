@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Fake is a deterministic provider for tests and for running without an AI
+// service. Generated test cases come from the description alone: the first
+// sentence becomes the title and every line or sentence a step.
+type Fake struct{}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// GenerateTestCase implements Provider
+func (f *Fake) GenerateTestCase(ctx context.Context, image Image, description string) (TestCaseDraft, error) {
+	if err := ctx.Err(); err != nil {
+		return TestCaseDraft{}, err
+	}
+
+	sentences := splitSentences(description)
+	title := "Generated test case"
+	if len(sentences) > 0 {
+		title = truncate(sentences[0], 80)
+	}
+
+	draft := TestCaseDraft{
+		Title:       title,
+		Description: strings.TrimSpace(description),
+		Priority:    "medium",
+		Tags:        []string{"generated"},
+	}
+	if len(image.Data) > 0 {
+		draft.Preconditions = fmt.Sprintf("The screen shown in image %.6x is open.", sha256.Sum256(image.Data))
+	}
+	for _, sentence := range sentences {
+		draft.Steps = append(draft.Steps, StepDraft{
+			Action:         sentence,
+			ExpectedResult: "The application responds as described.",
+		})
+	}
+	if len(draft.Steps) == 0 {
+		draft.Steps = []StepDraft{{Action: "Open the feature under test", ExpectedResult: "The feature is shown."}}
+	}
+	return draft, nil
+}
+
+// EnhanceField implements Provider. It tidies the value: whitespace is
+// collapsed, the first letter capitalised, and sentences other than titles
+// end with a full stop. Empty values get a placeholder naming the test case.
+func (f *Fake) EnhanceField(ctx context.Context, req FieldRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	value := strings.Join(strings.Fields(req.Value), " ")
+	if value == "" {
+		value = fmt.Sprintf("Describe the %s of %q", req.Field, req.TestCase.Title)
+	}
+	runes := []rune(value)
+	runes[0] = unicode.ToUpper(runes[0])
+	value = string(runes)
+
+	if req.Field == "title" {
+		return strings.TrimRight(value, "."), nil
+	}
+	if !strings.HasSuffix(value, ".") && !strings.HasSuffix(value, "!") && !strings.HasSuffix(value, "?") {
+		value += "."
+	}
+	return value, nil
+}
+
+// splitSentences splits text on line breaks and sentence-ending punctuation.
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+	flush := func() {
+		if sentence := strings.TrimSpace(current.String()); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+	for _, r := range text {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+		if r == '.' || r == '!' || r == '?' {
+			flush()
+		}
+	}
+	flush()
+
+	for i, sentence := range sentences {
+		sentences[i] = strings.TrimRightFunc(sentence, func(r rune) bool {
+			return unicode.IsPunct(r) && r != ')'
+		})
+	}
+	return sentences
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
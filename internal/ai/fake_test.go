@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFakeGenerateTestCase(t *testing.T) {
+	provider := NewFake()
+	image := Image{Data: []byte("png"), MediaType: "image/png"}
+	description := "Users log in with their email. They see the dashboard!\nLogging out returns to the login page."
+
+	draft, err := provider.GenerateTestCase(context.Background(), image, description)
+	if err != nil {
+		t.Fatalf("GenerateTestCase() error = %v", err)
+	}
+	if draft.Title != "Users log in with their email" {
+		t.Errorf("unexpected title %q", draft.Title)
+	}
+	if len(draft.Steps) != 3 || draft.Steps[2].Action != "Logging out returns to the login page" {
+		t.Errorf("unexpected steps %+v", draft.Steps)
+	}
+	if !strings.HasPrefix(draft.Preconditions, "The screen shown in image ") {
+		t.Errorf("expected preconditions to mention the image, got %q", draft.Preconditions)
+	}
+
+	again, err := provider.GenerateTestCase(context.Background(), image, description)
+	if err != nil {
+		t.Fatalf("GenerateTestCase() error = %v", err)
+	}
+	if !reflect.DeepEqual(draft, again) {
+		t.Error("expected the fake provider to be deterministic")
+	}
+}
+
+func TestFakeGenerateTestCaseWithoutDescription(t *testing.T) {
+	draft, err := NewFake().GenerateTestCase(context.Background(), Image{}, "")
+	if err != nil {
+		t.Fatalf("GenerateTestCase() error = %v", err)
+	}
+	if draft.Title == "" || len(draft.Steps) != 1 {
+		t.Errorf("expected a placeholder test case, got %+v", draft)
+	}
+}
+
+func TestFakeEnhanceField(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+		want  string
+	}{
+		{field: "title", value: "  login   with email. ", want: "Login with email"},
+		{field: "expected result", value: "the dashboard is shown", want: "The dashboard is shown."},
+		{field: "preconditions", value: "", want: `Describe the preconditions of "Login".`},
+	}
+
+	for _, tt := range tests {
+		got, err := NewFake().EnhanceField(context.Background(), FieldRequest{
+			Field:    tt.field,
+			Value:    tt.value,
+			TestCase: TestCaseDraft{Title: "Login"},
+		})
+		if err != nil {
+			t.Fatalf("EnhanceField() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("EnhanceField(%q, %q) = %q, want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const generatePrompt = `You write manual test cases for software testers.
+Given a description of a feature and, optionally, a screenshot of it, write one test case.
+Answer with a single JSON object and nothing else, using these fields:
+{"title": string, "description": string, "preconditions": string, "user_level": string,
+"priority": "low" | "medium" | "high" | "critical",
+"steps": [{"action": string, "expected_result": string}], "tags": [string]}
+Steps are short imperative actions, each with the result the tester should observe.`
+
+const enhancePrompt = `You review manual test cases for software testers.
+Rewrite the requested field of the test case so it is clear, specific and concise,
+keeping its meaning and language. Write it from scratch if it is empty.
+Answer with a single JSON object and nothing else: {"value": string}`
+
+// OpenAI is a provider for the chat completions API of OpenAI and of
+// compatible servers, such as local model servers.
+type OpenAI struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAI returns a provider for the API at baseURL, for example
+// "https://api.openai.com/v1". The API key may be empty for local servers.
+func NewOpenAI(baseURL, apiKey, model string) *OpenAI {
+	return &OpenAI{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GenerateTestCase implements Provider
+func (p *OpenAI) GenerateTestCase(ctx context.Context, image Image, description string) (TestCaseDraft, error) {
+	parts := []contentPart{{Type: "text", Text: "Feature description:\n" + description}}
+	if len(image.Data) > 0 {
+		url := fmt.Sprintf("data:%s;base64,%s", image.MediaType, base64.StdEncoding.EncodeToString(image.Data))
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}})
+	}
+
+	content, err := p.complete(ctx, []chatMessage{
+		{Role: "system", Content: generatePrompt},
+		{Role: "user", Content: parts},
+	})
+	if err != nil {
+		return TestCaseDraft{}, err
+	}
+
+	var draft TestCaseDraft
+	if err := json.Unmarshal([]byte(jsonObject(content)), &draft); err != nil {
+		return TestCaseDraft{}, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if strings.TrimSpace(draft.Title) == "" {
+		return TestCaseDraft{}, fmt.Errorf("%w: the test case has no title", ErrInvalidResponse)
+	}
+	return draft, nil
+}
+
+// EnhanceField implements Provider
+func (p *OpenAI) EnhanceField(ctx context.Context, req FieldRequest) (string, error) {
+	testCase, err := json.Marshal(req.TestCase)
+	if err != nil {
+		return "", err
+	}
+	prompt := fmt.Sprintf("Test case:\n%s\n\nField to rewrite: %s\nCurrent value:\n%s", testCase, req.Field, req.Value)
+
+	content, err := p.complete(ctx, []chatMessage{
+		{Role: "system", Content: enhancePrompt},
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var answer struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(jsonObject(content)), &answer); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if strings.TrimSpace(answer.Value) == "" {
+		return "", fmt.Errorf("%w: the suggestion is empty", ErrInvalidResponse)
+	}
+	return answer.Value, nil
+}
+
+// complete sends a chat completion request asking for a JSON answer and
+// returns the content of the first choice.
+func (p *OpenAI) complete(ctx context.Context, messages []chatMessage) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:          p.model,
+		Messages:       messages,
+		Temperature:    0.2,
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("AI provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read AI provider response: %w", err)
+	}
+	var result chatResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("%w: status %d", ErrInvalidResponse, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != nil {
+			return "", fmt.Errorf("AI provider returned status %d: %s", resp.StatusCode, result.Error.Message)
+		}
+		return "", fmt.Errorf("AI provider returned status %d", resp.StatusCode)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("%w: no choices", ErrInvalidResponse)
+	}
+	return result.Choices[0].Message.Content, nil
+}
+
+// jsonObject returns the JSON object in a model answer, dropping any text
+// or Markdown code fence around it, which some local models add.
+func jsonObject(content string) string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return content
+	}
+	return content[start : end+1]
+}
@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func chatServer(t *testing.T, status int, content string, check func(r *http.Request, req chatRequest)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if check != nil {
+			check(r, req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"message": content}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"message": map[string]string{"content": content}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIGenerateTestCase(t *testing.T) {
+	answer := "```json\n" + `{"title": "Login", "priority": "high", "steps": [{"action": "Submit", "expected_result": "Dashboard"}]}` + "\n```"
+	server := chatServer(t, http.StatusOK, answer, func(r *http.Request, req chatRequest) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		if req.Model != "local-model" || len(req.Messages) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		parts, _ := json.Marshal(req.Messages[1].Content)
+		if !strings.Contains(string(parts), "data:image/png;base64,") {
+			t.Errorf("expected the image to be sent as a data URL, got %s", parts)
+		}
+	})
+
+	provider := NewOpenAI(server.URL+"/v1/", "secret", "local-model")
+	draft, err := provider.GenerateTestCase(context.Background(), Image{Data: []byte("png"), MediaType: "image/png"}, "Log in")
+	if err != nil {
+		t.Fatalf("GenerateTestCase() error = %v", err)
+	}
+	if draft.Title != "Login" || draft.Priority != "high" || len(draft.Steps) != 1 || draft.Steps[0].ExpectedResult != "Dashboard" {
+		t.Errorf("unexpected draft %+v", draft)
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		content string
+		invalid bool
+	}{
+		{name: "error status", status: http.StatusUnauthorized, content: "bad key"},
+		{name: "not JSON", status: http.StatusOK, content: "I cannot help with that", invalid: true},
+		{name: "no title", status: http.StatusOK, content: `{"steps": []}`, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := chatServer(t, tt.status, tt.content, nil)
+			_, err := NewOpenAI(server.URL, "", "model").GenerateTestCase(context.Background(), Image{}, "Log in")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrInvalidResponse) != tt.invalid {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenAIEnhanceField(t *testing.T) {
+	server := chatServer(t, http.StatusOK, `{"value": "Submit valid credentials"}`, func(r *http.Request, req chatRequest) {
+		prompt, _ := req.Messages[1].Content.(string)
+		if !strings.Contains(prompt, "Field to rewrite: step action") || !strings.Contains(prompt, "submit creds") {
+			t.Errorf("unexpected prompt %q", prompt)
+		}
+	})
+
+	got, err := NewOpenAI(server.URL, "", "model").EnhanceField(context.Background(), FieldRequest{
+		Field:    "step action",
+		Value:    "submit creds",
+		TestCase: TestCaseDraft{Title: "Login"},
+	})
+	if err != nil {
+		t.Fatalf("EnhanceField() error = %v", err)
+	}
+	if got != "Submit valid credentials" {
+		t.Errorf("unexpected suggestion %q", got)
+	}
+}
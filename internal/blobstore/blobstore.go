@@ -0,0 +1,42 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+)
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores binary objects, such as uploaded attachments, by key.
+// Keys are slash-separated paths like "projects/<id>/attachments/<id>".
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns an S3-compatible store when S3_ENDPOINT is set, and otherwise
+// a store in the local directory BLOB_DIR, "tmp/blobs" by default.
+func New() BlobStore {
+	if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return NewS3(endpoint, region, os.Getenv("S3_BUCKET"), os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"))
+	}
+
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = "tmp/blobs"
+	}
+	log.Printf("S3_ENDPOINT is not set, storing blobs in %s", dir)
+	return NewLocal(dir)
+}
@@ -0,0 +1,88 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores blobs as files below a directory.
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+// Put implements BlobStore. The blob is written to a temporary file first,
+// so a failed upload never leaves a partial blob behind.
+func (s *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if written != size {
+		return fmt.Errorf("failed to write blob: expected %d bytes, got %d", size, written)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get implements BlobStore
+func (s *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete implements BlobStore
+func (s *Local) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file below the store directory, rejecting keys that
+// would escape it.
+func (s *Local) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || !fs.ValidPath(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
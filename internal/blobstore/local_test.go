@@ -0,0 +1,30 @@
+package blobstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	testBlobStore(t, NewLocal(t.TempDir()))
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	store := NewLocal(t.TempDir())
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../b"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
+
+func TestLocalRejectsShortWrites(t *testing.T) {
+	store := NewLocal(t.TempDir())
+	if err := store.Put(context.Background(), "a", strings.NewReader("abc"), 10, ""); err == nil {
+		t.Fatal("expected an error for a short body")
+	}
+	if _, err := store.Get(context.Background(), "a"); err != ErrNotFound {
+		t.Errorf("expected no blob after a failed Put, got %v", err)
+	}
+}
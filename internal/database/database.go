@@ -39,6 +39,10 @@ type Service interface {
 
 	// Delete deletes a record from the database
 	Delete(ctx context.Context, value interface{}) error
+
+	// List retrieves a page of records into dest, a pointer to a slice,
+	// using cursor pagination
+	List(ctx context.Context, dest interface{}, query ListQuery) (*Page, error)
 }
 
 type service struct {
@@ -63,31 +67,65 @@ func New() Service {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		username, password, host, port, dbname)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := Open(dsn)
 	if err != nil {
 		log.Fatal(err)
 	}
+	dbInstance = db.(*service)
+	return dbInstance
+}
+
+// Open connects to the database with the given DSN and migrates its schema.
+// Unlike New, it returns a new connection on every call; tests use it to
+// connect to a database container.
+func Open(dsn string) (Service, error) {
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Accounts created before email verification existed are trusted
+	grandfatherUsers := db.Migrator().HasTable(&models.User{}) && !db.Migrator().HasColumn(&models.User{}, "EmailVerified")
 
 	// Auto-migrate the schema
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Project{},
+		&models.ProjectMember{},
+		&models.TestCase{},
+		&models.TestStep{},
+		&models.Tag{},
+		&models.Attachment{},
+		&models.TestRun{},
+		&models.TestRunCase{},
+		&models.TestRunStep{},
+		&models.TestResult{},
+		&models.TestCaseRevision{},
+		&models.TestCaseRevisionStep{},
+		&models.AuditEvent{},
+		&models.RecoveryCode{},
+		&models.AccessToken{},
+	)
 	if err != nil {
-		log.Fatalf("Failed to migrate database schema: %v", err)
+		return nil, fmt.Errorf("failed to migrate database schema: %v", err)
+	}
+	if grandfatherUsers {
+		if err := db.Model(&models.User{}).Where("1 = 1").Update("email_verified", true).Error; err != nil {
+			return nil, fmt.Errorf("failed to mark existing users as verified: %v", err)
+		}
 	}
 
 	// Configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	sqlDB.SetMaxIdleConns(50)
 	sqlDB.SetMaxOpenConns(50)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	dbInstance = &service{
-		db: db,
-	}
-	return dbInstance
+	return &service{db: db}, nil
 }
 
 // Health checks the health of the database connection
@@ -2,13 +2,20 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"reflect"
 	"testing"
 	"time"
 
+	"TestAlchemy/internal/models"
+
+	"github.com/google/uuid"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/mysql"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/gorm"
 )
 
 func mustStartMySQLContainer() (func(context.Context) error, error) {
@@ -94,3 +101,62 @@ func TestClose(t *testing.T) {
 		t.Fatalf("expected Close() to return nil")
 	}
 }
+
+func TestList(t *testing.T) {
+	srv := New()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		user := &models.User{
+			UserID:       uuid.New(),
+			Email:        fmt.Sprintf("list-%d@example.com", i),
+			PasswordHash: "hash",
+		}
+		if err := srv.Create(ctx, user); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	onlyListUsers := func(db *gorm.DB) *gorm.DB {
+		return db.Where("email LIKE ?", "list-%")
+	}
+
+	var emails []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("expected pagination to finish within 3 pages")
+		}
+		var users []models.User
+		page, err := srv.List(ctx, &users, ListQuery{
+			Scopes:    []func(*gorm.DB) *gorm.DB{onlyListUsers},
+			SortField: "email",
+			SortDesc:  true,
+			Cursor:    cursor,
+			Limit:     2,
+		})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		for _, user := range users {
+			emails = append(emails, user.Email)
+		}
+		if !page.HasMore {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	expected := []string{"list-4@example.com", "list-3@example.com", "list-2@example.com", "list-1@example.com", "list-0@example.com"}
+	if !reflect.DeepEqual(emails, expected) {
+		t.Fatalf("expected %v, got %v", expected, emails)
+	}
+
+	var users []models.User
+	if _, err := srv.List(ctx, &users, ListQuery{SortField: "password"}); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("expected ErrInvalidQuery for an unknown sort field, got %v", err)
+	}
+	if _, err := srv.List(ctx, &users, ListQuery{Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("expected ErrInvalidQuery for a malformed cursor, got %v", err)
+	}
+}
@@ -72,6 +72,12 @@ func NewKeyDB() KeyDBService {
 	return keydbInstance
 }
 
+// NewKeyDBClient returns a KeyDB service using an existing client. Unlike
+// NewKeyDB, it is not shared; tests use it to connect to a KeyDB container.
+func NewKeyDBClient(client *redis.Client) KeyDBService {
+	return &keydbService{client: client}
+}
+
 // Health implements KeyDBService
 func (s *keydbService) Health() map[string]string {
 	status := make(map[string]string)
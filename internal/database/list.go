@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// ErrInvalidQuery is returned by List for unknown sort fields and malformed cursors.
+var ErrInvalidQuery = errors.New("invalid list query")
+
+// ListQuery describes a page of records to retrieve with Service.List.
+type ListQuery struct {
+	// Scopes add conditions, joins or preloads to the query
+	Scopes []func(*gorm.DB) *gorm.DB
+	// SortField is the column or field name to sort by. Ties are broken by
+	// the primary key, which is also the default sort field.
+	SortField string
+	SortDesc  bool
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+	// Limit is the page size, DefaultPageSize when zero and at most MaxPageSize
+	Limit int
+}
+
+// Page describes where a page returned by Service.List ends.
+type Page struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// cursor is the position of the last record of a page: its sort value and
+// its primary key.
+type cursor struct {
+	Value json.RawMessage `json:"v"`
+	Key   json.RawMessage `json:"k"`
+}
+
+// List implements Service
+func (s *service) List(ctx context.Context, dest interface{}, query ListQuery) (*Page, error) {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(dest); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %v", err)
+	}
+	table := stmt.Schema.Table
+	primary := stmt.Schema.PrioritizedPrimaryField
+	if primary == nil {
+		return nil, errors.New("list requires a model with a single primary key")
+	}
+
+	sortField := primary
+	if query.SortField != "" {
+		sortField = stmt.Schema.LookUpField(query.SortField)
+		if sortField == nil || sortField.DBName == "" {
+			return nil, fmt.Errorf("%w: cannot sort by %s", ErrInvalidQuery, query.SortField)
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	sortColumn := clause.Column{Table: table, Name: sortField.DBName}
+	primaryColumn := clause.Column{Table: table, Name: primary.DBName}
+
+	db := s.db.WithContext(ctx).Model(dest).Scopes(query.Scopes...)
+	if query.Cursor != "" {
+		value, key, err := decodeCursor(query.Cursor, sortField, primary)
+		if err != nil {
+			return nil, err
+		}
+		if sortField == primary {
+			db = db.Where(after(primaryColumn, key, query.SortDesc))
+		} else {
+			db = db.Where(clause.Or(
+				after(sortColumn, value, query.SortDesc),
+				clause.And(clause.Eq{Column: sortColumn, Value: value}, after(primaryColumn, key, query.SortDesc)),
+			))
+		}
+	}
+	if sortField != primary {
+		db = db.Order(clause.OrderByColumn{Column: sortColumn, Desc: query.SortDesc})
+	}
+	db = db.Order(clause.OrderByColumn{Column: primaryColumn, Desc: query.SortDesc})
+
+	// Fetch one extra record to know whether there is a next page
+	if err := db.Limit(limit + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{}
+	records := reflect.Indirect(reflect.ValueOf(dest))
+	if records.Len() > limit {
+		records.SetLen(limit)
+		next, err := encodeCursor(ctx, records.Index(limit-1), sortField, primary)
+		if err != nil {
+			return nil, err
+		}
+		page.HasMore = true
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+func after(column clause.Column, value interface{}, desc bool) clause.Expression {
+	if desc {
+		return clause.Lt{Column: column, Value: value}
+	}
+	return clause.Gt{Column: column, Value: value}
+}
+
+func encodeCursor(ctx context.Context, record reflect.Value, sortField, primary *schema.Field) (string, error) {
+	value, _ := sortField.ValueOf(ctx, record)
+	key, _ := primary.ValueOf(ctx, record)
+
+	var c cursor
+	var err error
+	if c.Value, err = json.Marshal(value); err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+	if c.Key, err = json.Marshal(key); err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort value and primary key stored in a cursor,
+// decoded into the Go types of their fields.
+func decodeCursor(encoded string, sortField, primary *schema.Field) (interface{}, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	value := reflect.New(sortField.FieldType)
+	if err := json.Unmarshal(c.Value, value.Interface()); err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	key := reflect.New(primary.FieldType)
+	if err := json.Unmarshal(c.Key, key.Interface()); err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return value.Elem().Interface(), key.Elem().Interface(), nil
+}
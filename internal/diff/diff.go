@@ -0,0 +1,115 @@
+// Package diff compares texts word by word.
+package diff
+
+import "unicode"
+
+// Operation types
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxTokens bounds the quadratic comparison. Longer texts are reported as a
+// whole deletion followed by a whole insertion.
+const maxTokens = 4000
+
+// Op is a run of text that is kept, inserted or deleted.
+type Op struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Words returns the operations that turn a into b, comparing words together
+// with the whitespace that follows them. Concatenating the text of the Equal and Delete
+// operations gives a, and that of the Equal and Insert operations gives b.
+func Words(a, b string) []Op {
+	x, y := tokenize(a), tokenize(b)
+	if len(x) > maxTokens || len(y) > maxTokens {
+		return compact([]Op{{Delete, a}, {Insert, b}})
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []Op
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			ops = append(ops, Op{Equal, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Op{Delete, x[i]})
+			i++
+		default:
+			ops = append(ops, Op{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		ops = append(ops, Op{Delete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		ops = append(ops, Op{Insert, y[j]})
+	}
+	return compact(ops)
+}
+
+// Changed reports whether the operations contain any insertion or deletion.
+func Changed(ops []Op) bool {
+	for _, op := range ops {
+		if op.Type != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits text into words, each with the whitespace that follows
+// it. Leading whitespace is a token of its own.
+func tokenize(text string) []string {
+	var tokens []string
+	start, inSpace := 0, false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > start && inSpace && !space {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// compact merges adjacent operations of the same type and drops empty ones.
+func compact(ops []Op) []Op {
+	merged := []Op{}
+	for _, op := range ops {
+		if op.Text == "" {
+			continue
+		}
+		if n := len(merged); n > 0 && merged[n-1].Type == op.Type {
+			merged[n-1].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}
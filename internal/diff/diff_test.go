@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Op
+	}{
+		{name: "equal", a: "Open the page", b: "Open the page", want: []Op{{Equal, "Open the page"}}},
+		{name: "both empty", a: "", b: "", want: []Op{}},
+		{name: "from empty", a: "", b: "Open it", want: []Op{{Insert, "Open it"}}},
+		{
+			name: "replaced word",
+			a:    "Open the page",
+			b:    "Open the login page",
+			want: []Op{{Equal, "Open the "}, {Insert, "login "}, {Equal, "page"}},
+		},
+		{
+			name: "changed words",
+			a:    "click submit quickly",
+			b:    "Click the submit button",
+			want: []Op{{Delete, "click "}, {Insert, "Click the "}, {Equal, "submit "}, {Delete, "quickly"}, {Insert, "button"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q, %q) = %+v, want %+v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordsReconstructs(t *testing.T) {
+	a := "Enter  a valid email\nand the password."
+	b := "Enter a valid e-mail address\n\nand the password, then submit."
+
+	var before, after strings.Builder
+	for _, op := range Words(a, b) {
+		if op.Type != Insert {
+			before.WriteString(op.Text)
+		}
+		if op.Type != Delete {
+			after.WriteString(op.Text)
+		}
+	}
+	if before.String() != a || after.String() != b {
+		t.Errorf("operations do not reconstruct the texts: %q, %q", before.String(), after.String())
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Words("same text", "same text")) {
+		t.Error("expected equal texts to be unchanged")
+	}
+	if !Changed(Words("same text", "other text")) {
+		t.Error("expected different texts to be changed")
+	}
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"TestAlchemy/internal/models"
+)
+
+// CSV writes test cases as CSV, flushing after every test case so large
+// exports stream instead of piling up in memory.
+type CSV struct {
+	w           *csv.Writer
+	layout      Layout
+	wroteHeader bool
+}
+
+func NewCSV(w io.Writer, layout Layout) *CSV {
+	return &CSV{
+		w:      csv.NewWriter(w),
+		layout: layout,
+	}
+}
+
+// Add implements Exporter
+func (e *CSV) Add(testCase *models.TestCase, author string) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	records := rows(testCase, author, e.layout)
+	for _, record := range records {
+		for i, cell := range record {
+			record[i] = escapeFormula(cell)
+		}
+	}
+	if err := e.w.WriteAll(records); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Close implements Exporter
+func (e *CSV) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// escapeFormula prefixes cells that spreadsheets would run as formulas with
+// a quote, so an exported test case cannot execute anything when opened.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func (e *CSV) writeHeader() error {
+	if e.wroteHeader {
+		return nil
+	}
+	e.wroteHeader = true
+	return e.w.Write(header(e.layout))
+}
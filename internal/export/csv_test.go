@@ -0,0 +1,122 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func sampleTestCase() *models.TestCase {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &models.TestCase{
+		TestCaseID: uuid.MustParse("7d444840-9dc0-11d1-b245-5ffdce74fad2"),
+		Title:      "Login",
+		Priority:   models.PriorityHigh,
+		Status:     models.StatusReady,
+		Tags:       []models.Tag{{Name: "smoke"}, {Name: "auth"}},
+		Steps: []models.TestStep{
+			{Position: 1, Action: "Open the login page", ExpectedResult: "The form is shown"},
+			{Position: 2, Action: "Submit valid credentials", ExpectedResult: "The dashboard is shown"},
+		},
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+}
+
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	return records
+}
+
+func TestCSVStepsAsRows(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := NewCSV(&buf, StepsAsRows)
+	if err := exporter.Add(sampleTestCase(), "qa@example.com"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records := readCSV(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 step rows, got %d rows", len(records))
+	}
+	if got := records[0][len(records[0])-2]; got != "Action" {
+		t.Errorf("expected the header to end with the step columns, got %q", got)
+	}
+	row := records[2]
+	if row[1] != "Login" || row[7] != "smoke, auth" || row[8] != "qa@example.com" {
+		t.Errorf("unexpected test case columns %v", row)
+	}
+	if row[11] != "2" || row[12] != "Submit valid credentials" || row[13] != "The dashboard is shown" {
+		t.Errorf("unexpected step columns %v", row[11:])
+	}
+}
+
+func TestCSVStepsJoined(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := NewCSV(&buf, StepsJoined)
+	if err := exporter.Add(sampleTestCase(), "qa@example.com"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records := readCSV(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected a header and 1 row, got %d rows", len(records))
+	}
+	if got, want := records[1][11], "1. Open the login page\n2. Submit valid credentials"; got != want {
+		t.Errorf("expected steps cell %q, got %q", want, got)
+	}
+}
+
+func TestCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewCSV(&buf, StepsAsRows).Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if records := readCSV(t, buf.Bytes()); len(records) != 1 {
+		t.Fatalf("expected only the header, got %d rows", len(records))
+	}
+}
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	testCase := sampleTestCase()
+	testCase.Title = "=HYPERLINK(\"http://example.com\")"
+	testCase.Description = "@SUM(A1:A2)"
+	testCase.Steps[0].Action = "+1"
+	testCase.Steps[0].ExpectedResult = "-1"
+	testCase.Steps[1].Action = "Enter 1+1=2"
+
+	var buf bytes.Buffer
+	exporter := NewCSV(&buf, StepsAsRows)
+	if err := exporter.Add(testCase, "qa@example.com"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records := readCSV(t, buf.Bytes())
+	row := records[1]
+	if row[1] != "'=HYPERLINK(\"http://example.com\")" || row[2] != "'@SUM(A1:A2)" {
+		t.Errorf("expected the formulas to be escaped, got %v", row[1:3])
+	}
+	if row[12] != "'+1" || row[13] != "'-1" {
+		t.Errorf("expected the step formulas to be escaped, got %v", row[12:])
+	}
+	if got := records[2][12]; got != "Enter 1+1=2" {
+		t.Errorf("expected other cells to be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/models"
+)
+
+// Layout controls how the steps of a test case are laid out.
+type Layout string
+
+const (
+	// StepsAsRows writes one row per step, repeating the test case columns
+	StepsAsRows Layout = "rows"
+	// StepsJoined writes one row per test case with the steps joined in a cell
+	StepsJoined Layout = "joined"
+)
+
+// ParseLayout returns the layout named by value, StepsAsRows when empty.
+func ParseLayout(value string) (Layout, error) {
+	switch Layout(value) {
+	case "", StepsAsRows:
+		return StepsAsRows, nil
+	case StepsJoined:
+		return StepsJoined, nil
+	default:
+		return "", fmt.Errorf("unknown steps layout %q", value)
+	}
+}
+
+// Exporter writes test cases to a document.
+type Exporter interface {
+	// Add writes a test case written by author
+	Add(testCase *models.TestCase, author string) error
+	// Close finishes the document, flushing anything buffered
+	Close() error
+}
+
+var caseColumns = []string{
+	"Test Case ID", "Title", "Description", "Preconditions", "User Level",
+	"Priority", "Status", "Tags", "Author", "Created At", "Updated At",
+}
+
+// header returns the column names for the layout.
+func header(layout Layout) []string {
+	columns := append([]string(nil), caseColumns...)
+	if layout == StepsJoined {
+		return append(columns, "Steps", "Expected Results")
+	}
+	return append(columns, "Step", "Action", "Expected Result")
+}
+
+// caseCells returns the test case columns of a row.
+func caseCells(testCase *models.TestCase, author string) []string {
+	return []string{
+		testCase.TestCaseID.String(),
+		testCase.Title,
+		testCase.Description,
+		testCase.Preconditions,
+		testCase.UserLevel,
+		testCase.Priority,
+		testCase.Status,
+		tagNames(testCase),
+		author,
+		testCase.CreatedAt.UTC().Format(time.RFC3339),
+		testCase.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// rows returns the rows of a test case for the layout. A case without steps
+// still gets a row.
+func rows(testCase *models.TestCase, author string, layout Layout) [][]string {
+	if layout == StepsJoined {
+		actions := make([]string, len(testCase.Steps))
+		expected := make([]string, len(testCase.Steps))
+		for i, step := range testCase.Steps {
+			actions[i] = fmt.Sprintf("%d. %s", step.Position, step.Action)
+			expected[i] = fmt.Sprintf("%d. %s", step.Position, step.ExpectedResult)
+		}
+		row := append(caseCells(testCase, author), strings.Join(actions, "\n"), strings.Join(expected, "\n"))
+		return [][]string{row}
+	}
+
+	if len(testCase.Steps) == 0 {
+		return [][]string{append(caseCells(testCase, author), "", "", "")}
+	}
+	result := make([][]string, 0, len(testCase.Steps))
+	for _, step := range testCase.Steps {
+		row := append(caseCells(testCase, author), fmt.Sprint(step.Position), step.Action, step.ExpectedResult)
+		result = append(result, row)
+	}
+	return result
+}
+
+func tagNames(testCase *models.TestCase) string {
+	names := make([]string, len(testCase.Tags))
+	for i, tag := range testCase.Tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ", ")
+}
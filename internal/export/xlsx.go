@@ -0,0 +1,269 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"TestAlchemy/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	summarySheet  = "Summary"
+	untaggedSheet = "Untagged"
+)
+
+// XLSX writes test cases as an Excel workbook with one worksheet per tag. A
+// test case with several tags appears on each of their worksheets, and
+// untagged cases are collected on their own worksheet. The first worksheet
+// summarises the test cases by status and priority.
+//
+// The workbook is built in memory and written out by Close.
+type XLSX struct {
+	w      io.Writer
+	layout Layout
+	file   *excelize.File
+
+	headerStyle int
+	cellStyle   int
+
+	sheets     map[string]string // sheet name by lower-cased tag name
+	nextRow    map[string]int
+	byStatus   map[string]int
+	byPriority map[string]int
+	total      int
+}
+
+func NewXLSX(w io.Writer, layout Layout) (*XLSX, error) {
+	file := excelize.NewFile()
+
+	headerStyle, err := file.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"C2410C"}},
+		Alignment: &excelize.Alignment{Vertical: "center", WrapText: true},
+		Border: []excelize.Border{
+			{Type: "bottom", Color: "7C2D12", Style: 2},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	cellStyle, err := file.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.SetSheetName("Sheet1", summarySheet); err != nil {
+		return nil, err
+	}
+
+	return &XLSX{
+		w:           w,
+		layout:      layout,
+		file:        file,
+		headerStyle: headerStyle,
+		cellStyle:   cellStyle,
+		sheets:      map[string]string{},
+		nextRow:     map[string]int{},
+		byStatus:    map[string]int{},
+		byPriority:  map[string]int{},
+	}, nil
+}
+
+// Add implements Exporter
+func (e *XLSX) Add(testCase *models.TestCase, author string) error {
+	e.total++
+	e.byStatus[testCase.Status]++
+	e.byPriority[testCase.Priority]++
+
+	// An empty group collects the untagged test cases
+	groups := []string{""}
+	if len(testCase.Tags) > 0 {
+		groups = groups[:0]
+		for _, tag := range testCase.Tags {
+			groups = append(groups, tag.Name)
+		}
+	}
+
+	for _, group := range groups {
+		sheet, err := e.sheet(group)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows(testCase, author, e.layout) {
+			if err := e.writeRow(sheet, row, e.cellStyle); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements Exporter
+func (e *XLSX) Close() error {
+	defer e.file.Close()
+
+	if err := e.writeSummary(); err != nil {
+		return err
+	}
+	e.file.SetActiveSheet(0)
+	return e.file.Write(e.w)
+}
+
+// sheet returns the worksheet of a tag group, creating it on first use.
+func (e *XLSX) sheet(group string) (string, error) {
+	key := strings.ToLower(group)
+	if sheet, ok := e.sheets[key]; ok {
+		return sheet, nil
+	}
+
+	name := group
+	if name == "" {
+		name = untaggedSheet
+	}
+	sheet := e.uniqueSheetName(name)
+	if _, err := e.file.NewSheet(sheet); err != nil {
+		return "", err
+	}
+	e.sheets[key] = sheet
+
+	columns := header(e.layout)
+	if err := e.writeRow(sheet, columns, e.headerStyle); err != nil {
+		return "", err
+	}
+	err := e.file.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for i, column := range columns {
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return "", err
+		}
+		if err := e.file.SetColWidth(sheet, name, name, columnWidth(column)); err != nil {
+			return "", err
+		}
+	}
+	return sheet, nil
+}
+
+func (e *XLSX) writeRow(sheet string, values []string, style int) error {
+	e.nextRow[sheet]++
+	row := e.nextRow[sheet]
+
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = value
+	}
+	start, err := excelize.CoordinatesToCellName(1, row)
+	if err != nil {
+		return err
+	}
+	end, err := excelize.CoordinatesToCellName(len(values), row)
+	if err != nil {
+		return err
+	}
+	if err := e.file.SetSheetRow(sheet, start, &cells); err != nil {
+		return err
+	}
+	return e.file.SetCellStyle(sheet, start, end, style)
+}
+
+func (e *XLSX) writeSummary() error {
+	if err := e.writeRow(summarySheet, []string{"Test cases", fmt.Sprint(e.total)}, e.headerStyle); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		title  string
+		order  []string
+		counts map[string]int
+	}{
+		{"Status", models.Statuses, e.byStatus},
+		{"Priority", models.Priorities, e.byPriority},
+	}
+	for _, section := range sections {
+		e.nextRow[summarySheet]++
+		if err := e.writeRow(summarySheet, []string{section.title, "Count"}, e.headerStyle); err != nil {
+			return err
+		}
+		for _, value := range section.order {
+			if err := e.writeCount(value, section.counts[value]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := e.file.SetColWidth(summarySheet, "A", "A", 24); err != nil {
+		return err
+	}
+	return e.file.SetColWidth(summarySheet, "B", "B", 12)
+}
+
+func (e *XLSX) writeCount(label string, count int) error {
+	e.nextRow[summarySheet]++
+	row := e.nextRow[summarySheet]
+	cell, err := excelize.CoordinatesToCellName(1, row)
+	if err != nil {
+		return err
+	}
+	return e.file.SetSheetRow(summarySheet, cell, &[]interface{}{label, count})
+}
+
+// uniqueSheetName turns a tag name into a valid worksheet name that is not
+// used yet. Worksheet names are at most 31 characters, cannot contain
+// []:*?/\ and are compared case-insensitively.
+func (e *XLSX) uniqueSheetName(group string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, group)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Tag"
+	}
+
+	taken := map[string]bool{strings.ToLower(summarySheet): true}
+	for _, sheet := range e.sheets {
+		taken[strings.ToLower(sheet)] = true
+	}
+
+	candidate := truncate(name, 31)
+	for i := 2; taken[strings.ToLower(candidate)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		candidate = truncate(name, 31-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
+
+func columnWidth(column string) float64 {
+	switch column {
+	case "Description", "Preconditions", "Steps", "Expected Results", "Action", "Expected Result":
+		return 50
+	case "Title":
+		return 36
+	case "Test Case ID":
+		return 38
+	default:
+		return 16
+	}
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := NewXLSX(&buf, StepsJoined)
+	if err != nil {
+		t.Fatalf("NewXLSX() error = %v", err)
+	}
+
+	tagged := sampleTestCase()
+	untagged := sampleTestCase()
+	untagged.Tags = nil
+	untagged.Status = models.StatusDraft
+	for _, testCase := range []*models.TestCase{tagged, untagged} {
+		if err := exporter.Add(testCase, "qa@example.com"); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("failed to open workbook: %v", err)
+	}
+	defer file.Close()
+
+	expected := []string{"Summary", "smoke", "auth", "Untagged"}
+	if sheets := file.GetSheetList(); !reflect.DeepEqual(sheets, expected) {
+		t.Fatalf("expected sheets %v, got %v", expected, sheets)
+	}
+
+	steps, err := file.GetCellValue("smoke", "L2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps != "1. Open the login page\n2. Submit valid credentials" {
+		t.Errorf("unexpected steps cell %q", steps)
+	}
+
+	rows, err := file.GetRows("Summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]string{}
+	for _, row := range rows {
+		if len(row) == 2 {
+			counts[row[0]] = row[1]
+		}
+	}
+	if counts["Test cases"] != "2" || counts[models.StatusReady] != "1" || counts[models.StatusDraft] != "1" || counts[models.PriorityHigh] != "2" {
+		t.Errorf("unexpected summary %v", counts)
+	}
+}
+
+func TestUniqueSheetName(t *testing.T) {
+	exporter, err := NewXLSX(&bytes.Buffer{}, StepsAsRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.file.Close()
+
+	tests := []struct {
+		group string
+		want  string
+	}{
+		{"module/login", "module-login"},
+		{"summary", "summary (2)"},
+		{"a-very-long-tag-name-that-does-not-fit", "a-very-long-tag-name-that-does-"},
+	}
+	for _, tt := range tests {
+		if got := exporter.uniqueSheetName(tt.group); got != tt.want {
+			t.Errorf("uniqueSheetName(%q) = %q, want %q", tt.group, got, tt.want)
+		}
+	}
+}
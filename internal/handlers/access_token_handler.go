@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type AccessTokenHandler struct {
+	accessTokenService *services.AccessTokenService
+}
+
+func NewAccessTokenHandler(accessTokenService *services.AccessTokenService) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		accessTokenService: accessTokenService,
+	}
+}
+
+func (h *AccessTokenHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	accessTokens, err := h.accessTokenService.ListAccessTokens(c.Request().Context(), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, accessTokens)
+}
+
+// Create returns the new token in clear text, which is the only time it
+// is shown.
+func (h *AccessTokenHandler) Create(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	var input services.CreateAccessTokenInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	accessToken, err := h.accessTokenService.CreateAccessToken(c.Request().Context(), userID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, accessToken)
+}
+
+func (h *AccessTokenHandler) Revoke(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	accessTokenID, err := uuidParam(c, "tokenId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.accessTokenService.RevokeAccessToken(c.Request().Context(), userID, accessTokenID); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
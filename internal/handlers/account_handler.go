@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"TestAlchemy/cmd/web"
+	"TestAlchemy/internal/services"
+	"TestAlchemy/internal/session"
+	"github.com/labstack/echo/v4"
+)
+
+type AccountHandler struct {
+	userService          *services.UserService
+	passwordResetService *services.PasswordResetService
+	mfaService           *services.MFAService
+	sessionStore         *session.Store
+}
+
+func NewAccountHandler(userService *services.UserService, passwordResetService *services.PasswordResetService, mfaService *services.MFAService, sessionStore *session.Store) *AccountHandler {
+	return &AccountHandler{
+		userService:          userService,
+		passwordResetService: passwordResetService,
+		mfaService:           mfaService,
+		sessionStore:         sessionStore,
+	}
+}
+
+// ChangePassword changes the password of the current user. The session is
+// rotated, so the response sets a new session cookie.
+func (h *AccountHandler) ChangePassword(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	var input services.ChangePasswordInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	sessionID, err := h.userService.ChangePassword(c.Request().Context(), userID, currentSessionID(c), input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	c.SetCookie(h.sessionStore.Cookie(sessionID))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ForgotPassword emails a password reset token. It always answers 202 so
+// that it cannot be used to find out which addresses have an account.
+func (h *AccountHandler) ForgotPassword(c echo.Context) error {
+	var input services.ForgotPasswordInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.passwordResetService.RequestReset(c.Request().Context(), input); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password with a token sent by ForgotPassword.
+// Every session of the user is revoked, so they have to log in again.
+func (h *AccountHandler) ResetPassword(c echo.Context) error {
+	var input services.ResetPasswordInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.passwordResetService.ResetPassword(c.Request().Context(), input); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// VerifyEmail verifies the address of a user with the token emailed on
+// registration.
+func (h *AccountHandler) VerifyEmail(c echo.Context) error {
+	var input services.VerifyEmailInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.userService.VerifyEmail(c.Request().Context(), input); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// VerifyEmailPage shows the page the verification email links to, which
+// posts the token to VerifyEmailForm.
+func (h *AccountHandler) VerifyEmailPage(c echo.Context) error {
+	return render(c, http.StatusOK, web.VerifyEmail(c.QueryParam("token")))
+}
+
+// VerifyEmailForm verifies the address of a user from the page shown by
+// VerifyEmailPage.
+func (h *AccountHandler) VerifyEmailForm(c echo.Context) error {
+	input := services.VerifyEmailInput{Token: c.FormValue("token")}
+	if err := h.userService.VerifyEmail(c.Request().Context(), input); err != nil {
+		return pageError(c, "Your address could not be verified", err)
+	}
+
+	return render(c, http.StatusOK, web.Notice("Your address is verified", "You can now log in to Test Alchemy."))
+}
+
+// ResetPasswordPage shows the page the password reset email links to, which
+// posts the token and the new password to ResetPasswordForm.
+func (h *AccountHandler) ResetPasswordPage(c echo.Context) error {
+	return render(c, http.StatusOK, web.ResetPassword(c.QueryParam("token"), ""))
+}
+
+// ResetPasswordForm sets a new password from the page shown by
+// ResetPasswordPage. Refused passwords show the form again.
+func (h *AccountHandler) ResetPasswordForm(c echo.Context) error {
+	input := services.ResetPasswordInput{Token: c.FormValue("token"), Password: c.FormValue("password")}
+	err := h.passwordResetService.ResetPassword(c.Request().Context(), input)
+	if errors.Is(err, services.ErrInvalidInput) {
+		return render(c, http.StatusBadRequest, web.ResetPassword(input.Token, pageMessage(err)))
+	}
+	if err != nil {
+		return pageError(c, "Your password could not be reset", err)
+	}
+
+	return render(c, http.StatusOK, web.Notice("Your password is reset", "Log in to Test Alchemy with your new password."))
+}
+
+// ResendVerification emails a new verification token. Like ForgotPassword
+// it answers 202 whether or not the address has an account.
+func (h *AccountHandler) ResendVerification(c echo.Context) error {
+	var input services.ResendVerificationInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.userService.ResendVerification(c.Request().Context(), input); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (h *AccountHandler) MFAStatus(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	status, err := h.mfaService.Status(c.Request().Context(), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// StartTOTP returns a new TOTP secret and its otpauth URI, to be confirmed
+// with ConfirmTOTP.
+func (h *AccountHandler) StartTOTP(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	enrolment, err := h.mfaService.StartTOTP(c.Request().Context(), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, enrolment)
+}
+
+// ConfirmTOTP enables two-factor authentication and returns the recovery
+// codes, which are not shown again.
+func (h *AccountHandler) ConfirmTOTP(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	var input services.MFACodeInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(c.Request().Context(), userID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, codes)
+}
+
+func (h *AccountHandler) DisableMFA(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	var input services.DisableMFAInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.mfaService.DisableMFA(c.Request().Context(), userID, input); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AccountHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	var input services.RecoveryCodesInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(c.Request().Context(), userID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, codes)
+}
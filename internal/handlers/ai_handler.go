@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"TestAlchemy/internal/ai"
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type AIHandler struct {
+	aiService  *services.AIService
+	jobService *services.JobService
+}
+
+func NewAIHandler(aiService *services.AIService, jobService *services.JobService) *AIHandler {
+	return &AIHandler{
+		aiService:  aiService,
+		jobService: jobService,
+	}
+}
+
+// GenerateTestCase drafts a test case from a multipart form with a
+// description and an optional image. The draft is returned for review and
+// is saved by creating a test case from it.
+func (h *AIHandler) GenerateTestCase(c echo.Context) error {
+	return h.generate(c, false)
+}
+
+// EnqueueGeneration takes the same form as GenerateTestCase but generates
+// the draft in the background. It responds with the job, whose result is
+// the draft once it has succeeded.
+func (h *AIHandler) EnqueueGeneration(c echo.Context) error {
+	return h.generate(c, true)
+}
+
+func (h *AIHandler) generate(c echo.Context, async bool) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var image ai.Image
+	if file, err := c.FormFile("image"); err == nil {
+		if file.Size > services.MaxImageSize {
+			return errorJSON(c, http.StatusRequestEntityTooLarge, "image must be at most 5 MB")
+		}
+		src, err := file.Open()
+		if err != nil {
+			return errorJSON(c, http.StatusBadRequest, "cannot read image")
+		}
+		defer src.Close()
+		if image.Data, err = io.ReadAll(src); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "cannot read image")
+		}
+		// Sniff the type rather than trusting the one sent by the client
+		image.MediaType = http.DetectContentType(image.Data)
+	} else if !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart) {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	description := c.FormValue("description")
+	if async {
+		job, err := h.jobService.EnqueueGeneration(ctx, userID, projectID, image, description)
+		if err != nil {
+			return serviceError(c, err)
+		}
+		return c.JSON(http.StatusAccepted, job)
+	}
+
+	draft, err := h.aiService.GenerateTestCase(ctx, userID, projectID, image, description)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, draft)
+}
+
+// SuggestField returns an AI rewrite of one field of a test case with a word
+// diff against the current value. Nothing changes until the suggestion is
+// accepted.
+func (h *AIHandler) SuggestField(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.SuggestionInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	suggestion, err := h.aiService.SuggestField(c.Request().Context(), userID, projectID, testCaseID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, suggestion)
+}
+
+func (h *AIHandler) AcceptSuggestion(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	suggestionID, err := uuidParam(c, "suggestionId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	testCase, err := h.aiService.AcceptSuggestion(c.Request().Context(), userID, projectID, testCaseID, suggestionID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, testCase)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type AttachmentHandler struct {
+	attachmentService *services.AttachmentService
+	auditService      *services.AuditService
+}
+
+func NewAttachmentHandler(attachmentService *services.AttachmentService, auditService *services.AuditService) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+		auditService:      auditService,
+	}
+}
+
+// Upload attaches the file of a multipart form to a test case, or to one of
+// its steps when the form has a step_id.
+func (h *AttachmentHandler) Upload(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "file is required")
+	}
+	if file.Size > services.MaxAttachmentSize {
+		return errorJSON(c, http.StatusRequestEntityTooLarge, "file must be at most 10 MB")
+	}
+	input := services.UploadInput{FileName: file.Filename, Size: file.Size}
+	if stepID := c.FormValue("step_id"); stepID != "" {
+		id, err := uuid.Parse(stepID)
+		if err != nil {
+			return errorJSON(c, http.StatusBadRequest, "invalid step_id")
+		}
+		input.StepID = &id
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "cannot read file")
+	}
+	defer src.Close()
+	input.Body = src
+
+	attachment, err := h.attachmentService.Upload(c.Request().Context(), userID, projectID, testCaseID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *AttachmentHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	attachments, err := h.attachmentService.ListAttachments(c.Request().Context(), userID, projectID, testCaseID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, attachments)
+}
+
+// Download sends the content of an attachment. Images are shown inline and
+// other files downloaded.
+func (h *AttachmentHandler) Download(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	attachmentID, err := uuidParam(c, "attachmentId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	attachment, content, err := h.attachmentService.OpenAttachment(c.Request().Context(), userID, projectID, testCaseID, attachmentID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, attachment.ContentType)
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set("Cache-Control", "private")
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The status has been sent, so failures can only be logged
+	if _, err := io.Copy(c.Response(), content); err != nil {
+		log.Printf("download of attachment %s failed: %v", attachmentID, err)
+	}
+	return nil
+}
+
+func (h *AttachmentHandler) Delete(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	attachmentID, err := uuidParam(c, "attachmentId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.attachmentService.DeleteAttachment(c.Request().Context(), userID, projectID, testCaseID, attachmentID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditAttachmentDelete,
+		TargetType: "attachment",
+		TargetID:   attachmentID.String(),
+		Metadata:   map[string]interface{}{"test_case_id": testCaseID},
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
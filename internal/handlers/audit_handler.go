@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// ListProject returns the audit log of a project. See auditFilter for the
+// query parameters.
+func (h *AuditHandler) ListProject(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	filter, err := auditFilter(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.auditService.ListProjectEvents(c.Request().Context(), userID, projectID, filter)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// List returns the audit log of the whole instance to admins.
+func (h *AuditHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	filter, err := auditFilter(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.auditService.ListEvents(c.Request().Context(), userID, filter)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// auditFilter parses the query parameters of the audit log endpoints:
+// action (comma-separated), actor, target_type, target_id, since and until
+// (RFC 3339), cursor and limit.
+func auditFilter(c echo.Context) (services.AuditFilter, error) {
+	filter := services.AuditFilter{
+		Actions:    splitList(c.QueryParam("action")),
+		TargetType: c.QueryParam("target_type"),
+		TargetID:   c.QueryParam("target_id"),
+		Cursor:     c.QueryParam("cursor"),
+	}
+	var err error
+	if actor := c.QueryParam("actor"); actor != "" {
+		if filter.ActorID, err = uuid.Parse(actor); err != nil {
+			return filter, errors.New("invalid actor")
+		}
+	}
+	if since := c.QueryParam("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, errors.New("invalid since")
+		}
+	}
+	if until := c.QueryParam("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, errors.New("invalid until")
+		}
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return filter, errors.New("invalid limit")
+		}
+	}
+	return filter, nil
+}
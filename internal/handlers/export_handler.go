@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"TestAlchemy/internal/export"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+	jobService    *services.JobService
+	auditService  *services.AuditService
+}
+
+func NewExportHandler(exportService *services.ExportService, jobService *services.JobService, auditService *services.AuditService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		jobService:    jobService,
+		auditService:  auditService,
+	}
+}
+
+// Export downloads every test case of the project. The format query
+// parameter selects "csv" (the default) or "xlsx". The steps query parameter
+// selects one row per step ("rows") or one row per test case with the steps
+// joined in a cell ("joined"); CSV defaults to rows and XLSX to joined.
+func (h *ExportHandler) Export(c echo.Context) error {
+	return h.export(c, c.QueryParam("format"))
+}
+
+// CSV streams every test case of the project as CSV.
+func (h *ExportHandler) CSV(c echo.Context) error {
+	return h.export(c, "csv")
+}
+
+func (h *ExportHandler) export(c echo.Context, format string) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	format, contentType, layout, err := exportFormat(format, c.QueryParam("steps"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	project, err := h.exportService.Authorize(ctx, userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	// Only exports that complete are audited
+	recordExport := func() {
+		h.auditService.Record(ctx, services.AuditEntry{
+			ActorID:    userID,
+			ProjectID:  projectID,
+			Action:     models.AuditProjectExport,
+			TargetType: "project",
+			TargetID:   projectID.String(),
+			Metadata:   map[string]interface{}{"format": format, "steps": layout},
+		})
+	}
+
+	// The download header is set only once the export is known to start,
+	// so that errors are not saved as a file
+	res := c.Response()
+	disposition := attachment(project.Name, format)
+
+	// Workbooks are built in memory anyway, so render them fully before
+	// responding and report failures properly
+	if format == "xlsx" {
+		var buf bytes.Buffer
+		exporter, err := export.NewXLSX(&buf, layout)
+		if err != nil {
+			return serviceError(c, err)
+		}
+		if err := h.exportService.Export(ctx, projectID, exporter); err != nil {
+			return serviceError(c, err)
+		}
+		recordExport()
+		res.Header().Set(echo.HeaderContentDisposition, disposition)
+		return c.Blob(http.StatusOK, contentType, buf.Bytes())
+	}
+
+	res.Header().Set(echo.HeaderContentDisposition, disposition)
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.WriteHeader(http.StatusOK)
+
+	// The status has been sent, so failures can only be logged
+	if err := h.exportService.Export(ctx, projectID, export.NewCSV(res, layout)); err != nil {
+		log.Printf("CSV export of project %s failed: %v", projectID, err)
+		return nil
+	}
+	recordExport()
+	return nil
+}
+
+// Enqueue exports the test cases of the project in the background, taking
+// the same query parameters as Export. It responds with the job; the file is
+// downloaded from the job once it has succeeded.
+func (h *ExportHandler) Enqueue(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	format, _, layout, err := exportFormat(c.QueryParam("format"), c.QueryParam("steps"))
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	job, err := h.jobService.EnqueueExport(c.Request().Context(), userID, projectID, format, layout)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditProjectExport,
+		TargetType: "project",
+		TargetID:   projectID.String(),
+		Metadata:   map[string]interface{}{"format": format, "steps": layout, "job_id": job.JobID},
+	})
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// exportFormat resolves the format and steps query parameters of an export
+// to a format, its content type and a layout.
+func exportFormat(format, steps string) (string, string, export.Layout, error) {
+	var contentType string
+	switch format {
+	case "", "csv":
+		format, contentType = "csv", "text/csv; charset=utf-8"
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		if steps == "" {
+			steps = string(export.StepsJoined)
+		}
+	default:
+		return "", "", "", errors.New("format must be csv or xlsx")
+	}
+	layout, err := export.ParseLayout(steps)
+	if err != nil {
+		return "", "", "", err
+	}
+	return format, contentType, layout, nil
+}
+
+// attachment returns a Content-Disposition header value for a download named
+// after the project.
+func attachment(name, extension string) string {
+	filename := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(name))
+	if filename == "" || len(filename) > 100 {
+		filename = "test-cases"
+	}
+	return fmt.Sprintf("attachment; filename=%q", filename+"."+extension)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"TestAlchemy/cmd/web"
+	"TestAlchemy/internal/services"
+	"github.com/a-h/templ"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// currentUserID returns the user ID stored in the context by middleware.RequireAuth.
+func currentUserID(c echo.Context) (uuid.UUID, error) {
+	userID, ok := c.Get("user_id").(uuid.UUID)
+	if !ok {
+		return uuid.Nil, errors.New("unauthorized")
+	}
+	return userID, nil
+}
+
+// currentSessionID returns the session ID stored in the context by
+// middleware.RequireAuth.
+func currentSessionID(c echo.Context) string {
+	sessionID, _ := c.Get("session_id").(string)
+	return sessionID
+}
+
+// uuidParam parses the named path parameter as a UUID.
+func uuidParam(c echo.Context, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid " + name)
+	}
+	return id, nil
+}
+
+// splitList splits a comma-separated query parameter, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func errorJSON(c echo.Context, status int, message string) error {
+	return c.JSON(status, map[string]string{"error": message})
+}
+
+// serviceError maps the sentinel errors returned by the services to a JSON
+// error response. Unexpected errors are logged and reported as a 500.
+func serviceError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrNotFound):
+		return errorJSON(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		return errorJSON(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrConflict):
+		return errorJSON(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrRateLimited):
+		return errorJSON(c, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrUnavailable):
+		log.Printf("%s %s: %v", c.Request().Method, c.Path(), err)
+		return errorJSON(c, http.StatusBadGateway, services.ErrUnavailable.Error())
+	default:
+		log.Printf("%s %s: %v", c.Request().Method, c.Path(), err)
+		return errorJSON(c, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// render writes an HTML page.
+func render(c echo.Context, status int, page templ.Component) error {
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(status)
+	return page.Render(c.Request().Context(), c.Response())
+}
+
+// pageError is serviceError for the forms of the pages opened from emails:
+// it reports errors as a page instead of JSON.
+func pageError(c echo.Context, title string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		return render(c, http.StatusBadRequest, web.Notice(title, pageMessage(err)))
+	case errors.Is(err, services.ErrRateLimited):
+		return render(c, http.StatusTooManyRequests, web.Notice(title, pageMessage(err)))
+	default:
+		log.Printf("%s %s: %v", c.Request().Method, c.Path(), err)
+		return render(c, http.StatusInternalServerError, web.Notice(title, "Something went wrong, try again later."))
+	}
+}
+
+// pageMessage returns the message of a service error without the sentinel
+// error it wraps, which users of a page do not need to see.
+func pageMessage(err error) string {
+	message := err.Error()
+	for _, sentinel := range []error{services.ErrInvalidInput, services.ErrRateLimited} {
+		message = strings.TrimPrefix(message, sentinel.Error()+": ")
+	}
+	return message
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"TestAlchemy/internal/importer"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// maxImportSize is the largest file accepted by Import, in bytes.
+const maxImportSize = 10 << 20
+
+type ImportHandler struct {
+	importService *services.ImportService
+	auditService  *services.AuditService
+}
+
+func NewImportHandler(importService *services.ImportService, auditService *services.AuditService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		auditService:  auditService,
+	}
+}
+
+// Import creates test cases from an uploaded CSV or XLSX file. The multipart
+// form holds the file, the column mapping as JSON, an optional format ("csv"
+// or "xlsx", taken from the file name by default) and dry_run. Imports are
+// dry runs unless dry_run is "false": the rows are only validated and the
+// per-row errors reported. A real import with invalid rows imports nothing
+// and responds with 422.
+func (h *ImportHandler) Import(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "file is required")
+	}
+	if file.Size > maxImportSize {
+		return errorJSON(c, http.StatusRequestEntityTooLarge, "file must be at most 10 MB")
+	}
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	}
+	if format != "csv" && format != "xlsx" {
+		return errorJSON(c, http.StatusBadRequest, "format must be csv or xlsx")
+	}
+
+	var mapping importer.Mapping
+	if err := json.Unmarshal([]byte(c.FormValue("mapping")), &mapping); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "invalid mapping")
+	}
+	dryRun := c.FormValue("dry_run") != "false"
+
+	src, err := file.Open()
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, "cannot read file")
+	}
+	defer src.Close()
+	table, err := importer.ReadTable(src, format, mapping.Sheet)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	report, err := h.importService.ImportTestCases(c.Request().Context(), userID, projectID, table, mapping, dryRun)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if report.Imported > 0 {
+		h.auditService.Record(c.Request().Context(), services.AuditEntry{
+			ActorID:    userID,
+			ProjectID:  projectID,
+			Action:     models.AuditProjectImport,
+			TargetType: "project",
+			TargetID:   projectID.String(),
+			Metadata:   map[string]interface{}{"file_name": file.Filename, "format": format, "imported": report.Imported},
+		})
+	}
+
+	switch {
+	case report.DryRun:
+		return c.JSON(http.StatusOK, report)
+	case len(report.Errors) > 0:
+		return c.JSON(http.StatusUnprocessableEntity, report)
+	default:
+		return c.JSON(http.StatusCreated, report)
+	}
+}
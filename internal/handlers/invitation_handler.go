@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type InvitationHandler struct {
+	invitationService *services.InvitationService
+	auditService      *services.AuditService
+}
+
+func NewInvitationHandler(invitationService *services.InvitationService, auditService *services.AuditService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		auditService:      auditService,
+	}
+}
+
+func (h *InvitationHandler) Invite(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.InviteInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	invitation, err := h.invitationService.Invite(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditInvitationCreate,
+		TargetType: "invitation",
+		TargetID:   invitation.InvitationID.String(),
+		Metadata:   map[string]interface{}{"email": invitation.Email, "role": invitation.Role},
+	})
+
+	return c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *InvitationHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	invitations, err := h.invitationService.ListInvitations(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+func (h *InvitationHandler) Revoke(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	invitationID, err := uuidParam(c, "invitationId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.invitationService.RevokeInvitation(c.Request().Context(), userID, projectID, invitationID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditInvitationRevoke,
+		TargetType: "invitation",
+		TargetID:   invitationID.String(),
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *InvitationHandler) Accept(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	member, err := h.invitationService.AcceptInvitation(c.Request().Context(), userID, c.Param("token"))
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  member.ProjectID,
+		Action:     models.AuditInvitationAccept,
+		TargetType: "user",
+		TargetID:   userID.String(),
+		Metadata:   map[string]interface{}{"role": member.Role},
+	})
+
+	return c.JSON(http.StatusOK, member)
+}
+
+func (h *InvitationHandler) Decline(c echo.Context) error {
+	if err := h.invitationService.DeclineInvitation(c.Request().Context(), c.Param("token")); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
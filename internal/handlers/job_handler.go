@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type JobHandler struct {
+	jobService *services.JobService
+}
+
+func NewJobHandler(jobService *services.JobService) *JobHandler {
+	return &JobHandler{jobService: jobService}
+}
+
+// Get returns the status of one of the user's background jobs.
+func (h *JobHandler) Get(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	jobID, err := uuidParam(c, "jobId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	job, err := h.jobService.GetJob(c.Request().Context(), userID, jobID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// Download sends the file of a finished export job.
+func (h *JobHandler) Download(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	jobID, err := uuidParam(c, "jobId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	result, file, err := h.jobService.OpenExport(c.Request().Context(), userID, jobID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	defer file.Close()
+	_, contentType, _, err := exportFormat(result.Format, "")
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(result.Size, 10))
+	header.Set(echo.HeaderContentDisposition, attachment(result.ProjectName, result.Format))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The status has been sent, so failures can only be logged
+	if _, err := io.Copy(c.Response(), file); err != nil {
+		log.Printf("download of export %s failed: %v", jobID, err)
+	}
+	return nil
+}
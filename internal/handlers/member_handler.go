@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type MemberHandler struct {
+	memberService *services.MemberService
+	auditService  *services.AuditService
+}
+
+func NewMemberHandler(memberService *services.MemberService, auditService *services.AuditService) *MemberHandler {
+	return &MemberHandler{
+		memberService: memberService,
+		auditService:  auditService,
+	}
+}
+
+type updateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type transferOwnershipRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (h *MemberHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	members, err := h.memberService.ListMembers(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, members)
+}
+
+func (h *MemberHandler) Add(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.AddMemberInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	member, err := h.memberService.AddMember(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditMemberAdd,
+		TargetType: "user",
+		TargetID:   member.UserID.String(),
+		Metadata:   map[string]interface{}{"email": member.Email, "role": member.Role},
+	})
+
+	return c.JSON(http.StatusCreated, member)
+}
+
+func (h *MemberHandler) UpdateRole(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	memberID, err := uuidParam(c, "userId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req updateMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.memberService.UpdateMemberRole(c.Request().Context(), userID, projectID, memberID, req.Role); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditMemberUpdateRole,
+		TargetType: "user",
+		TargetID:   memberID.String(),
+		Metadata:   map[string]interface{}{"role": req.Role},
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *MemberHandler) Remove(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	memberID, err := uuidParam(c, "userId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.memberService.RemoveMember(c.Request().Context(), userID, projectID, memberID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditMemberRemove,
+		TargetType: "user",
+		TargetID:   memberID.String(),
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *MemberHandler) TransferOwnership(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req transferOwnershipRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.memberService.TransferOwnership(c.Request().Context(), userID, projectID, req.UserID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditProjectTransfer,
+		TargetType: "user",
+		TargetID:   req.UserID.String(),
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
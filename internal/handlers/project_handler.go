@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type ProjectHandler struct {
+	projectService *services.ProjectService
+	auditService   *services.AuditService
+}
+
+func NewProjectHandler(projectService *services.ProjectService, auditService *services.AuditService) *ProjectHandler {
+	return &ProjectHandler{
+		projectService: projectService,
+		auditService:   auditService,
+	}
+}
+
+func (h *ProjectHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	projects, err := h.projectService.ListProjects(c.Request().Context(), userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, projects)
+}
+
+func (h *ProjectHandler) Create(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	var input services.ProjectInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	project, err := h.projectService.CreateProject(c.Request().Context(), userID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  project.ProjectID,
+		Action:     models.AuditProjectCreate,
+		TargetType: "project",
+		TargetID:   project.ProjectID.String(),
+		Metadata:   map[string]interface{}{"name": project.Name},
+	})
+
+	return c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) Get(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	project, err := h.projectService.GetProject(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) Update(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.ProjectInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	project, err := h.projectService.UpdateProject(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditProjectUpdate,
+		TargetType: "project",
+		TargetID:   projectID.String(),
+		Metadata:   map[string]interface{}{"name": project.Name},
+	})
+
+	return c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) Delete(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.projectService.DeleteProject(c.Request().Context(), userID, projectID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditProjectDelete,
+		TargetType: "project",
+		TargetID:   projectID.String(),
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
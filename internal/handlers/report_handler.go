@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/cmd/web"
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ReportHandler struct {
+	reportService  *services.ReportService
+	projectService *services.ProjectService
+}
+
+func NewReportHandler(reportService *services.ReportService, projectService *services.ProjectService) *ReportHandler {
+	return &ReportHandler{
+		reportService:  reportService,
+		projectService: projectService,
+	}
+}
+
+func (h *ReportHandler) Project(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	report, err := h.reportService.ProjectReport(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *ReportHandler) Runs(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	runs, err := h.reportService.RunSummaries(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+func (h *ReportHandler) Flaky(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	flaky, err := h.reportService.FlakyCases(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, flaky)
+}
+
+func (h *ReportHandler) Coverage(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	coverage, err := h.reportService.TagCoverage(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, coverage)
+}
+
+func (h *ReportHandler) NeverExecuted(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	cases, err := h.reportService.NeverExecuted(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, cases)
+}
+
+// Dashboard renders the reports of the project selected by the project
+// query parameter, or of the most recently updated project.
+func (h *ReportHandler) Dashboard(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	ctx := c.Request().Context()
+
+	projects, err := h.projectService.ListProjects(ctx, userID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	data := web.DashboardData{Projects: projects}
+	if len(projects) > 0 {
+		data.Project = &projects[0]
+		if value := c.QueryParam("project"); value != "" {
+			projectID, err := uuid.Parse(value)
+			if err != nil {
+				return errorJSON(c, http.StatusBadRequest, "invalid project")
+			}
+			if data.Project, err = h.projectService.GetProject(ctx, userID, projectID); err != nil {
+				return serviceError(c, err)
+			}
+		}
+		if data.Report, err = h.reportService.ProjectReport(ctx, userID, data.Project.ProjectID); err != nil {
+			return serviceError(c, err)
+		}
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return web.Dashboard(data).Render(ctx, c.Response())
+}
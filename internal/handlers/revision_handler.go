@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+type RevisionHandler struct {
+	revisionService *services.RevisionService
+	auditService    *services.AuditService
+}
+
+func NewRevisionHandler(revisionService *services.RevisionService, auditService *services.AuditService) *RevisionHandler {
+	return &RevisionHandler{
+		revisionService: revisionService,
+		auditService:    auditService,
+	}
+}
+
+func (h *RevisionHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	var limit int
+	if value := c.QueryParam("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "invalid limit")
+		}
+	}
+
+	page, err := h.revisionService.ListRevisions(c.Request().Context(), userID, projectID, testCaseID, c.QueryParam("cursor"), limit)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *RevisionHandler) Get(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	number, err := revisionNumber(c.Param("number"), "number")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	revision, err := h.revisionService.GetRevision(c.Request().Context(), userID, projectID, testCaseID, number)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, revision)
+}
+
+func (h *RevisionHandler) Diff(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	from, err := revisionNumber(c.QueryParam("from"), "from")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	to, err := revisionNumber(c.QueryParam("to"), "to")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	diff, err := h.revisionService.DiffRevisions(c.Request().Context(), userID, projectID, testCaseID, from, to)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+func (h *RevisionHandler) Restore(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	number, err := revisionNumber(c.Param("number"), "number")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	testCase, err := h.revisionService.RestoreRevision(c.Request().Context(), userID, projectID, testCaseID, number)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditTestCaseRestore,
+		TargetType: "test_case",
+		TargetID:   testCaseID.String(),
+		Metadata:   map[string]interface{}{"revision": number},
+	})
+
+	return c.JSON(http.StatusOK, testCase)
+}
+
+// revisionNumber parses a revision number from a path or query parameter.
+func revisionNumber(value, name string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return 0, errors.New("invalid " + name)
+	}
+	return number, nil
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type RunHandler struct {
+	runService *services.RunService
+}
+
+func NewRunHandler(runService *services.RunService) *RunHandler {
+	return &RunHandler{runService: runService}
+}
+
+func (h *RunHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	var limit int
+	if value := c.QueryParam("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "invalid limit")
+		}
+	}
+
+	page, err := h.runService.ListRuns(c.Request().Context(), userID, projectID, c.QueryParam("cursor"), limit)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *RunHandler) Start(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.StartRunInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	run, err := h.runService.StartRun(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, run)
+}
+
+func (h *RunHandler) Get(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, runID, err := runParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	run, err := h.runService.GetRun(c.Request().Context(), userID, projectID, runID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+func (h *RunHandler) RecordResult(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, runID, err := runParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	runCaseID, err := uuidParam(c, "runCaseId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.ResultInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := h.runService.RecordResult(c.Request().Context(), userID, projectID, runID, runCaseID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *RunHandler) Close(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, runID, err := runParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	run, err := h.runService.CloseRun(c.Request().Context(), userID, projectID, runID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
+
+// runParams parses the project and run IDs from the path.
+func runParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	runID, err := uuidParam(c, "runId")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return projectID, runID, nil
+}
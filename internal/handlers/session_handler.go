@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/internal/services"
+	"TestAlchemy/internal/session"
+	"github.com/labstack/echo/v4"
+)
+
+type SessionHandler struct {
+	userService  *services.UserService
+	sessionStore *session.Store
+}
+
+func NewSessionHandler(userService *services.UserService, sessionStore *session.Store) *SessionHandler {
+	return &SessionHandler{
+		userService:  userService,
+		sessionStore: sessionStore,
+	}
+}
+
+// Logout deletes the session of the request, if any, and clears its cookie.
+func (h *SessionHandler) Logout(c echo.Context) error {
+	if cookie, err := c.Cookie(session.CookieName); err == nil {
+		if err := h.userService.Logout(c.Request().Context(), cookie.Value); err != nil {
+			return serviceError(c, err)
+		}
+	}
+
+	c.SetCookie(h.sessionStore.ClearCookie())
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *SessionHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	sessions, err := h.userService.ListSessions(c.Request().Context(), userID, currentSessionID(c))
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+func (h *SessionHandler) Revoke(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	if err := h.userService.RevokeSession(c.Request().Context(), userID, c.Param("sessionId")); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeOthers logs the user out of every session but the current one.
+func (h *SessionHandler) RevokeOthers(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+
+	revoked, err := h.userService.RevokeOtherSessions(c.Request().Context(), userID, currentSessionID(c))
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"revoked": revoked})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+
+	"TestAlchemy/internal/services"
+	"TestAlchemy/internal/session"
+	"github.com/labstack/echo/v4"
+)
+
+const ssoStateCookie = "sso_state"
+
+type SSOHandler struct {
+	ssoService   *services.SSOService
+	sessionStore *session.Store
+}
+
+func NewSSOHandler(ssoService *services.SSOService, sessionStore *session.Store) *SSOHandler {
+	return &SSOHandler{
+		ssoService:   ssoService,
+		sessionStore: sessionStore,
+	}
+}
+
+// Login redirects to the identity provider. The state is also kept in a
+// cookie, so that the sign-in can only be completed in the same browser.
+func (h *SSOHandler) Login(c echo.Context) error {
+	start, err := h.ssoService.Start(c.Request().Context())
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	c.SetCookie(ssoCookie(start.State, 600))
+	return c.Redirect(http.StatusFound, start.URL)
+}
+
+// Callback completes the sign-in when the identity provider sends the
+// user back.
+func (h *SSOHandler) Callback(c echo.Context) error {
+	input := services.SSOCallbackInput{
+		Code:  c.QueryParam("code"),
+		State: c.QueryParam("state"),
+		Error: c.QueryParam("error"),
+	}
+	if cookie, err := c.Cookie(ssoStateCookie); err == nil {
+		input.CookieState = cookie.Value
+	}
+	if cookie, err := c.Cookie(session.CookieName); err == nil {
+		input.SessionID = cookie.Value
+	}
+	c.SetCookie(ssoCookie("", -1))
+
+	result, err := h.ssoService.Callback(c.Request().Context(), input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	if result.LinkRequired {
+		return c.HTML(http.StatusOK, linkPage(result.LinkToken))
+	}
+
+	// The session cookie is SameSite=Strict, so browsers would not send it
+	// on a redirect that started at the identity provider. Moving on from
+	// a page of our own makes the next request same-site.
+	c.SetCookie(h.sessionStore.Cookie(result.SessionID))
+	return c.HTML(http.StatusOK, `<!DOCTYPE html><meta http-equiv="refresh" content="0;url=/web"><a href="/web">Continue</a>`)
+}
+
+// Link links an account with two-factor authentication once the user
+// confirms with their password and a code, as asked by Callback. It takes
+// the form of the page shown by Callback, or JSON.
+func (h *SSOHandler) Link(c echo.Context) error {
+	var input services.SSOLinkInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+	if cookie, err := c.Cookie(session.CookieName); err == nil {
+		input.SessionID = cookie.Value
+	}
+
+	sessionID, err := h.ssoService.Link(c.Request().Context(), input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	c.SetCookie(h.sessionStore.Cookie(sessionID))
+	return c.Redirect(http.StatusSeeOther, "/web")
+}
+
+// linkPage asks the user to confirm the link of their account.
+func linkPage(token string) string {
+	return `<!DOCTYPE html><title>Link your account</title>
+<p>An account with this address already exists and uses two-factor authentication. Enter its password and a code to sign in with your identity provider from now on.</p>
+<form method="post" action="/api/sso/link">
+<input type="hidden" name="link_token" value="` + html.EscapeString(token) + `">
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<label>Code <input type="text" name="code" autocomplete="one-time-code" required></label>
+<button type="submit">Link account</button>
+</form>`
+}
+
+// ssoCookie returns the state cookie. It is SameSite=Lax, as it must come
+// back on the redirect from the identity provider.
+func ssoCookie(state string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    state,
+		Path:     "/api/sso",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TagHandler struct {
+	tagService   *services.TagService
+	auditService *services.AuditService
+}
+
+func NewTagHandler(tagService *services.TagService, auditService *services.AuditService) *TagHandler {
+	return &TagHandler{
+		tagService:   tagService,
+		auditService: auditService,
+	}
+}
+
+type mergeTagsRequest struct {
+	SourceIDs []uuid.UUID `json:"source_ids"`
+}
+
+func (h *TagHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	tags, err := h.tagService.ListTags(c.Request().Context(), userID, projectID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
+
+func (h *TagHandler) Create(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.TagInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	tag, err := h.tagService.CreateTag(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, tag)
+}
+
+func (h *TagHandler) Rename(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, tagID, err := tagParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.TagInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	tag, err := h.tagService.RenameTag(c.Request().Context(), userID, projectID, tagID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *TagHandler) Merge(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, tagID, err := tagParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req mergeTagsRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	tag, err := h.tagService.MergeTags(c.Request().Context(), userID, projectID, tagID, req.SourceIDs)
+	if err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditTagMerge,
+		TargetType: "tag",
+		TargetID:   tagID.String(),
+		Metadata:   map[string]interface{}{"name": tag.Name, "source_ids": req.SourceIDs},
+	})
+
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (h *TagHandler) Delete(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, tagID, err := tagParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.tagService.DeleteTag(c.Request().Context(), userID, projectID, tagID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditTagDelete,
+		TargetType: "tag",
+		TargetID:   tagID.String(),
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tagParams parses the project and tag IDs from the path.
+func tagParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	tagID, err := uuidParam(c, "tagId")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return projectID, tagID, nil
+}
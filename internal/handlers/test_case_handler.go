@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TestCaseHandler struct {
+	testCaseService *services.TestCaseService
+	auditService    *services.AuditService
+}
+
+func NewTestCaseHandler(testCaseService *services.TestCaseService, auditService *services.AuditService) *TestCaseHandler {
+	return &TestCaseHandler{
+		testCaseService: testCaseService,
+		auditService:    auditService,
+	}
+}
+
+type reorderStepsRequest struct {
+	StepIDs []uuid.UUID `json:"step_ids"`
+}
+
+func (h *TestCaseHandler) List(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	filter := services.TestCaseFilter{
+		Tags:     splitList(c.QueryParam("tags")),
+		MatchAll: c.QueryParam("match") == "all",
+	}
+
+	testCases, err := h.testCaseService.ListTestCases(c.Request().Context(), userID, projectID, filter)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, testCases)
+}
+
+func (h *TestCaseHandler) Search(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	input := services.SearchInput{
+		Query:     c.QueryParam("q"),
+		Tags:      splitList(c.QueryParam("tags")),
+		MatchAll:  c.QueryParam("match") == "all",
+		UserLevel: c.QueryParam("user_level"),
+		Status:    c.QueryParam("status"),
+		Sort:      c.QueryParam("sort"),
+		Desc:      c.QueryParam("order") == "desc",
+		Cursor:    c.QueryParam("cursor"),
+	}
+	if author := c.QueryParam("author"); author != "" {
+		if input.AuthorID, err = uuid.Parse(author); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "invalid author")
+		}
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		if input.Limit, err = strconv.Atoi(limit); err != nil {
+			return errorJSON(c, http.StatusBadRequest, "invalid limit")
+		}
+	}
+
+	page, err := h.testCaseService.SearchTestCases(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *TestCaseHandler) Create(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.TestCaseInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	testCase, err := h.testCaseService.CreateTestCase(c.Request().Context(), userID, projectID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, testCase)
+}
+
+func (h *TestCaseHandler) Get(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	testCase, err := h.testCaseService.GetTestCase(c.Request().Context(), userID, projectID, testCaseID)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, testCase)
+}
+
+func (h *TestCaseHandler) Update(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.TestCaseInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	testCase, err := h.testCaseService.UpdateTestCase(c.Request().Context(), userID, projectID, testCaseID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, testCase)
+}
+
+func (h *TestCaseHandler) Delete(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.testCaseService.DeleteTestCase(c.Request().Context(), userID, projectID, testCaseID); err != nil {
+		return serviceError(c, err)
+	}
+	h.auditService.Record(c.Request().Context(), services.AuditEntry{
+		ActorID:    userID,
+		ProjectID:  projectID,
+		Action:     models.AuditTestCaseDelete,
+		TargetType: "test_case",
+		TargetID:   testCaseID.String(),
+	})
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TestCaseHandler) AddStep(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.TestStepInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	step, err := h.testCaseService.AddStep(c.Request().Context(), userID, projectID, testCaseID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, step)
+}
+
+func (h *TestCaseHandler) UpdateStep(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	stepID, err := uuidParam(c, "stepId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var input services.TestStepInput
+	if err := c.Bind(&input); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	step, err := h.testCaseService.UpdateStep(c.Request().Context(), userID, projectID, testCaseID, stepID, input)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, step)
+}
+
+func (h *TestCaseHandler) DeleteStep(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+	stepID, err := uuidParam(c, "stepId")
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.testCaseService.DeleteStep(c.Request().Context(), userID, projectID, testCaseID, stepID); err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *TestCaseHandler) ReorderSteps(c echo.Context) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return errorJSON(c, http.StatusUnauthorized, err.Error())
+	}
+	projectID, testCaseID, err := testCaseParams(c)
+	if err != nil {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	var req reorderStepsRequest
+	if err := c.Bind(&req); err != nil {
+		return errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	}
+
+	testCase, err := h.testCaseService.ReorderSteps(c.Request().Context(), userID, projectID, testCaseID, req.StepIDs)
+	if err != nil {
+		return serviceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, testCase)
+}
+
+// testCaseParams parses the project and test case IDs from the path.
+func testCaseParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	projectID, err := uuidParam(c, "id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	testCaseID, err := uuidParam(c, "caseId")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return projectID, testCaseID, nil
+}
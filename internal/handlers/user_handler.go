@@ -2,18 +2,26 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
 
 	"TestAlchemy/internal/services"
+	"TestAlchemy/internal/session"
 )
 
 type UserHandler struct {
-	userService *services.UserService
+	userService  *services.UserService
+	sessionStore *session.Store
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *services.UserService, sessionStore *session.Store) *UserHandler {
+	return &UserHandler{
+		userService:  userService,
+		sessionStore: sessionStore,
+	}
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -47,24 +55,78 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if cookie, err := r.Cookie(session.CookieName); err == nil {
+		input.SessionID = cookie.Value
+	}
 
-	sessionID, err := h.userService.Login(r.Context(), input)
+	result, err := h.userService.Login(r.Context(), input)
+	if locked := new(services.LockedError); errors.As(err, &locked) {
+		tooManyAttempts(w, locked)
+		return
+	}
+	if errors.Is(err, services.ErrForbidden) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	// The session is only created once the second factor is verified
+	if result.MFARequired {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
 	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    sessionID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   24 * 60 * 60, // 24 hours in seconds
-		Expires:  time.Now().Add(24 * time.Hour),
-	})
+	http.SetCookie(w, h.sessionStore.Cookie(result.SessionID))
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// LoginMFA completes a login with the MFA token returned by Login and a
+// TOTP or recovery code.
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input services.MFALoginInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if cookie, err := r.Cookie(session.CookieName); err == nil {
+		input.SessionID = cookie.Value
+	}
+
+	sessionID, err := h.userService.CompleteMFALogin(r.Context(), input)
+	if locked := new(services.LockedError); errors.As(err, &locked) {
+		tooManyAttempts(w, locked)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidInput) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, h.sessionStore.Cookie(sessionID))
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// tooManyAttempts responds to a login attempt while locked out, telling
+// the client when to retry.
+func tooManyAttempts(w http.ResponseWriter, locked *services.LockedError) {
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, locked.Error(), http.StatusTooManyRequests)
+}
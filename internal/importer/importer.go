@@ -0,0 +1,237 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxRows is the largest number of data rows accepted in one import.
+const MaxRows = 10000
+
+// Mapping names the spreadsheet columns, by header, that hold each field of
+// a test case. Only Title is required. Header names are matched
+// case-insensitively.
+type Mapping struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	Preconditions string `json:"preconditions"`
+	UserLevel     string `json:"user_level"`
+	Priority      string `json:"priority"`
+	Status        string `json:"status"`
+	// Steps holds the step actions, one per line, optionally numbered
+	Steps string `json:"steps"`
+	// Expected holds the expected results, one per line matching Steps
+	Expected string `json:"expected"`
+	// Tags holds comma-separated tag names
+	Tags string `json:"tags"`
+	// Key, when set, groups consecutive rows with the same value into one
+	// test case with one step per row, as in a CSV export with steps as rows
+	Key string `json:"key"`
+	// Sheet is the XLSX worksheet to read, the first one when empty
+	Sheet string `json:"sheet"`
+}
+
+// Step is an imported action/expected-result pair.
+type Step struct {
+	Action         string
+	ExpectedResult string
+}
+
+// Draft is a test case read from a spreadsheet.
+type Draft struct {
+	// Row is the spreadsheet row the test case starts on, counting the header as row 1
+	Row           int
+	Title         string
+	Description   string
+	Preconditions string
+	UserLevel     string
+	Priority      string
+	Status        string
+	Steps         []Step
+	Tags          []string
+}
+
+// RowError is a validation error for one spreadsheet row.
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// ReadTable reads the rows of a "csv" or "xlsx" file. For workbooks, sheet
+// selects the worksheet, the first one when empty.
+func ReadTable(r io.Reader, format, sheet string) ([][]string, error) {
+	switch format {
+	case "csv":
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		table, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		if len(table) > 0 && len(table[0]) > 0 {
+			table[0][0] = strings.TrimPrefix(table[0][0], "\ufeff")
+		}
+		return table, nil
+	case "xlsx":
+		file, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %v", err)
+		}
+		defer file.Close()
+		if sheet == "" {
+			sheet = file.GetSheetName(0)
+		}
+		table, err := file.GetRows(sheet)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read worksheet %q: %v", sheet, err)
+		}
+		return table, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Parse maps the rows of a table, whose first row is the header, to test
+// case drafts. Mapping errors, such as a column missing from the header, are
+// returned as an error; problems with individual rows are left to the
+// caller's validation.
+func Parse(table [][]string, mapping Mapping) ([]Draft, error) {
+	if mapping.Title == "" {
+		return nil, errors.New("the title column must be mapped")
+	}
+	if len(table) == 0 {
+		return nil, errors.New("the file is empty")
+	}
+	if len(table)-1 > MaxRows {
+		return nil, fmt.Errorf("the file has more than %d rows", MaxRows)
+	}
+
+	columns := map[string]int{}
+	for i, name := range table[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	index := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("column %q not found in the header", name)
+		}
+		return i, nil
+	}
+
+	var fields [10]int
+	names := []string{
+		mapping.Title, mapping.Description, mapping.Preconditions, mapping.UserLevel, mapping.Priority,
+		mapping.Status, mapping.Steps, mapping.Expected, mapping.Tags, mapping.Key,
+	}
+	for i, name := range names {
+		var err error
+		if fields[i], err = index(name); err != nil {
+			return nil, err
+		}
+	}
+	title, description, preconditions, userLevel, priority := fields[0], fields[1], fields[2], fields[3], fields[4]
+	status, steps, expected, tags, key := fields[5], fields[6], fields[7], fields[8], fields[9]
+
+	var drafts []Draft
+	lastKey := ""
+	for i, row := range table[1:] {
+		if isBlank(row) {
+			continue
+		}
+		cell := func(column int) string {
+			if column < 0 || column >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[column])
+		}
+
+		// Rows sharing a key add a step to the current test case
+		if key >= 0 {
+			if k := cell(key); k != "" && k == lastKey && len(drafts) > 0 {
+				current := &drafts[len(drafts)-1]
+				current.Steps = append(current.Steps, rowSteps(cell(steps), cell(expected), false)...)
+				continue
+			}
+			lastKey = cell(key)
+		}
+
+		drafts = append(drafts, Draft{
+			Row:           i + 2,
+			Title:         cell(title),
+			Description:   cell(description),
+			Preconditions: cell(preconditions),
+			UserLevel:     cell(userLevel),
+			Priority:      strings.ToLower(cell(priority)),
+			Status:        strings.ToLower(cell(status)),
+			Steps:         rowSteps(cell(steps), cell(expected), key < 0),
+			Tags:          splitTags(cell(tags)),
+		})
+	}
+	return drafts, nil
+}
+
+var numbering = regexp.MustCompile(`^\s*\d+\s*[.)]\s*`)
+
+// rowSteps returns the steps held by a row. With split, the cells hold one
+// step per line; otherwise they hold a single step.
+func rowSteps(actions, expected string, split bool) []Step {
+	if !split {
+		if actions == "" && expected == "" {
+			return nil
+		}
+		return []Step{{Action: actions, ExpectedResult: expected}}
+	}
+
+	actionLines := splitLines(actions)
+	expectedLines := splitLines(expected)
+	count := max(len(actionLines), len(expectedLines))
+	steps := make([]Step, count)
+	for i := range steps {
+		if i < len(actionLines) {
+			steps[i].Action = actionLines[i]
+		}
+		if i < len(expectedLines) {
+			steps[i].ExpectedResult = expectedLines[i]
+		}
+	}
+	return steps
+}
+
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(numbering.ReplaceAllString(line, ""))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func isBlank(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseJoinedSteps(t *testing.T) {
+	table := [][]string{
+		{"Name", "Steps", "Expected", "Labels", "Priority"},
+		{"Login", "1. Open the login page\n2. Submit credentials", "Form shown\nDashboard shown", "smoke, auth", "High"},
+		{"", "", "", "", ""},
+		{"Logout", "Click logout", "", "", ""},
+	}
+	mapping := Mapping{Title: "name", Steps: "Steps", Expected: "expected", Tags: "Labels", Priority: "Priority"}
+
+	drafts, err := Parse(table, mapping)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(drafts) != 2 {
+		t.Fatalf("expected 2 drafts, got %d", len(drafts))
+	}
+
+	login := drafts[0]
+	wantSteps := []Step{
+		{Action: "Open the login page", ExpectedResult: "Form shown"},
+		{Action: "Submit credentials", ExpectedResult: "Dashboard shown"},
+	}
+	if login.Row != 2 || login.Title != "Login" || login.Priority != "high" {
+		t.Errorf("unexpected draft %+v", login)
+	}
+	if !reflect.DeepEqual(login.Steps, wantSteps) {
+		t.Errorf("expected steps %+v, got %+v", wantSteps, login.Steps)
+	}
+	if !reflect.DeepEqual(login.Tags, []string{"smoke", "auth"}) {
+		t.Errorf("unexpected tags %v", login.Tags)
+	}
+	if drafts[1].Row != 4 || len(drafts[1].Steps) != 1 {
+		t.Errorf("unexpected draft %+v", drafts[1])
+	}
+}
+
+func TestParseStepsAsRows(t *testing.T) {
+	table := [][]string{
+		{"Test Case ID", "Title", "Action", "Expected Result"},
+		{"a", "Login", "Open the login page", "Form shown"},
+		{"a", "Login", "Submit credentials", "Dashboard shown"},
+		{"b", "Logout", "Click logout", "Login page shown"},
+	}
+	mapping := Mapping{Title: "Title", Steps: "Action", Expected: "Expected Result", Key: "Test Case ID"}
+
+	drafts, err := Parse(table, mapping)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(drafts) != 2 {
+		t.Fatalf("expected 2 drafts, got %d", len(drafts))
+	}
+	if len(drafts[0].Steps) != 2 || drafts[0].Steps[1].Action != "Submit credentials" {
+		t.Errorf("expected rows with the same key to be merged, got %+v", drafts[0].Steps)
+	}
+	if drafts[1].Row != 4 {
+		t.Errorf("expected the second test case to start on row 4, got %d", drafts[1].Row)
+	}
+}
+
+func TestParseMappingErrors(t *testing.T) {
+	table := [][]string{{"Title"}, {"Login"}}
+
+	tests := []struct {
+		name    string
+		table   [][]string
+		mapping Mapping
+	}{
+		{name: "title not mapped", table: table, mapping: Mapping{}},
+		{name: "unknown column", table: table, mapping: Mapping{Title: "Title", Steps: "Steps"}},
+		{name: "empty table", table: nil, mapping: Mapping{Title: "Title"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.table, tt.mapping); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadTableCSV(t *testing.T) {
+	table, err := ReadTable(strings.NewReader("\ufeffTitle,Steps\nLogin\n"), "csv", "")
+	if err != nil {
+		t.Fatalf("ReadTable() error = %v", err)
+	}
+	want := [][]string{{"Title", "Steps"}, {"Login"}}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("expected %v, got %v", want, table)
+	}
+}
+
+func TestReadTableXLSX(t *testing.T) {
+	file := excelize.NewFile()
+	if _, err := file.NewSheet("Cases"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetSheetRow("Cases", "A1", &[]interface{}{"Title", "Priority"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetSheetRow("Cases", "A2", &[]interface{}{"Login", "high"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := ReadTable(bytes.NewReader(buf.Bytes()), "xlsx", "Cases")
+	if err != nil {
+		t.Fatalf("ReadTable() error = %v", err)
+	}
+	want := [][]string{{"Title", "Priority"}, {"Login", "high"}}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("expected %v, got %v", want, table)
+	}
+
+	if _, err := ReadTable(bytes.NewReader(buf.Bytes()), "xlsx", "Missing"); err == nil {
+		t.Error("expected an error for a missing worksheet")
+	}
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pollInterval bounds how long workers block waiting for a job, and so how
+// quickly they notice a drain, and how often due retries are promoted.
+const pollInterval = time.Second
+
+// Pool runs queued jobs on a fixed number of workers.
+type Pool struct {
+	queue *Queue
+	size  int
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	cancelJob context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewPool(queue *Queue, size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		queue: queue,
+		size:  size,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Start requeues jobs left unfinished by a previous run and starts the
+// workers. It assumes a single pool per queue is running when it starts.
+func (p *Pool) Start() {
+	if err := p.queue.requeueStale(context.Background()); err != nil {
+		log.Printf("failed to requeue unfinished jobs: %v", err)
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	p.cancelJob = cancel
+
+	for i := 0; i < p.size; i++ {
+		p.wg.Add(1)
+		go p.work(jobCtx)
+	}
+	p.wg.Add(1)
+	go p.promote()
+}
+
+// Drain stops taking new jobs and waits for the running ones to finish. When
+// ctx expires first, the running jobs are cancelled and put back on the
+// queue, and Drain returns the context's error once they have stopped.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancelJob()
+		return nil
+	case <-ctx.Done():
+		p.cancelJob()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work(jobCtx context.Context) {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		id, err := p.queue.client.BRPopLPush(context.Background(), readyKey, processingKey, pollInterval).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to fetch job: %v", err)
+			p.sleep()
+			continue
+		}
+
+		if err := p.queue.run(jobCtx, id); err != nil {
+			log.Printf("failed to run job %s: %v", id, err)
+		}
+	}
+}
+
+func (p *Pool) promote() {
+	defer p.wg.Done()
+	for {
+		if err := p.queue.promote(context.Background()); err != nil {
+			log.Printf("failed to promote retried jobs: %v", err)
+		}
+		if !p.sleep() {
+			return
+		}
+	}
+}
+
+// sleep waits for the poll interval and reports whether the pool is still
+// running.
+func (p *Pool) sleep() bool {
+	select {
+	case <-p.stop:
+		return false
+	case <-time.After(pollInterval):
+		return true
+	}
+}
@@ -0,0 +1,312 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	// jobTTL is how long job records and their results are kept
+	jobTTL = 7 * 24 * time.Hour
+
+	readyKey      = "jobs:ready"
+	processingKey = "jobs:processing"
+	delayedKey    = "jobs:delayed"
+)
+
+// ErrNotFound is returned for unknown or expired jobs.
+var ErrNotFound = errors.New("job not found")
+
+// Job is the status of a background job.
+type Job struct {
+	JobID       uuid.UUID `json:"job_id"`
+	Type        string    `json:"type"`
+	UserID      uuid.UUID `json:"user_id"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	// Error is the error of the last attempt
+	Error string `json:"error,omitempty"`
+	// Result is what the handler returned, once the job has succeeded
+	Result    json.RawMessage `json:"result,omitempty"`
+	NextRunAt *time.Time      `json:"next_run_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// record is a job as stored in KeyDB, with the payload kept out of the
+// status returned to users.
+type record struct {
+	Job
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Handler runs a job. The payload is the JSON the job was enqueued with,
+// and the returned result is stored as JSON on the job. Returning an error
+// retries the job with backoff unless it is wrapped with Permanent.
+type Handler func(ctx context.Context, job *Job, payload json.RawMessage) (interface{}, error)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying cannot fix, such as invalid input,
+// so the job fails immediately.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Queue stores jobs in KeyDB. Job IDs waiting to run are kept in a list,
+// moved to a processing list while they run, and retries wait in a sorted
+// set scored by the time they are due.
+type Queue struct {
+	client   *redis.Client
+	handlers map[string]Handler
+	now      func() time.Time
+
+	// MaxAttempts is the number of times a job is tried before it fails
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// further attempt, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func NewQueue(client *redis.Client) *Queue {
+	return &Queue{
+		client:      client,
+		handlers:    map[string]Handler{},
+		now:         time.Now,
+		MaxAttempts: 3,
+		BaseDelay:   5 * time.Second,
+		MaxDelay:    5 * time.Minute,
+	}
+}
+
+// Register sets the handler of a job type. Handlers must be registered
+// before the workers start.
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.handlers[jobType] = handler
+}
+
+// Enqueue stores a job for the user and queues it to run.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, userID uuid.UUID, payload interface{}) (*Job, error) {
+	if _, ok := q.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job payload: %v", err)
+	}
+
+	now := q.now()
+	rec := &record{
+		Job: Job{
+			JobID:       uuid.New(),
+			Type:        jobType,
+			UserID:      userID,
+			Status:      StatusQueued,
+			MaxAttempts: q.MaxAttempts,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+		Payload: data,
+	}
+	if err := q.save(ctx, rec); err != nil {
+		return nil, err
+	}
+	if err := q.client.LPush(ctx, readyKey, rec.JobID.String()).Err(); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %v", err)
+	}
+	return &rec.Job, nil
+}
+
+// Get returns the status of a job.
+func (q *Queue) Get(ctx context.Context, jobID uuid.UUID) (*Job, error) {
+	rec, err := q.load(ctx, jobID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Job, nil
+}
+
+// run processes a job taken from the ready list and records the outcome.
+func (q *Queue) run(ctx context.Context, id string) error {
+	rec, err := q.load(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return q.client.LRem(ctx, processingKey, 1, id).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	rec.Status = StatusRunning
+	rec.Attempts++
+	rec.NextRunAt = nil
+	rec.UpdatedAt = q.now()
+	if err := q.save(ctx, rec); err != nil {
+		return err
+	}
+
+	result, err := q.handle(ctx, rec)
+
+	// A job interrupted by a shutdown is put back without using up an attempt.
+	// Use a fresh context, since the job's context is cancelled.
+	store := context.WithoutCancel(ctx)
+	if err != nil && ctx.Err() != nil {
+		rec.Attempts--
+		return q.retry(store, rec, fmt.Sprintf("interrupted: %v", err), q.now())
+	}
+
+	switch {
+	case err == nil:
+		rec.Status = StatusSucceeded
+		rec.Error = ""
+		if rec.Result, err = json.Marshal(result); err != nil {
+			rec.Status, rec.Error = StatusFailed, fmt.Sprintf("failed to marshal result: %v", err)
+		}
+	case isPermanent(err) || rec.Attempts >= rec.MaxAttempts:
+		rec.Status = StatusFailed
+		rec.Error = err.Error()
+	default:
+		return q.retry(store, rec, err.Error(), q.now().Add(q.backoff(rec.Attempts)))
+	}
+
+	rec.UpdatedAt = q.now()
+	if err := q.save(store, rec); err != nil {
+		return err
+	}
+	return q.client.LRem(store, processingKey, 1, id).Err()
+}
+
+// handle calls the job's handler, turning panics into errors.
+func (q *Queue) handle(ctx context.Context, rec *record) (result interface{}, err error) {
+	handler, ok := q.handlers[rec.Type]
+	if !ok {
+		return nil, Permanent(fmt.Errorf("unknown job type %q", rec.Type))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, &rec.Job, rec.Payload)
+}
+
+// retry schedules another attempt of a job at the given time.
+func (q *Queue) retry(ctx context.Context, rec *record, message string, at time.Time) error {
+	rec.Status = StatusQueued
+	rec.Error = message
+	rec.NextRunAt = &at
+	rec.UpdatedAt = q.now()
+	if err := q.save(ctx, rec); err != nil {
+		return err
+	}
+
+	id := rec.JobID.String()
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, delayedKey, redis.Z{Score: float64(at.UnixMilli()), Member: id})
+		pipe.LRem(ctx, processingKey, 1, id)
+		return nil
+	})
+	return err
+}
+
+// promote moves the retries that are due to the ready list.
+func (q *Queue) promote(ctx context.Context) error {
+	due := fmt.Sprint(q.now().UnixMilli())
+	ids, err := q.client.ZRangeByScore(ctx, delayedKey, &redis.ZRangeBy{Min: "-inf", Max: due}).Result()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		// Only the caller that removes the entry queues the job, so several
+		// pools can promote concurrently
+		removed, err := q.client.ZRem(ctx, delayedKey, id).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 1 {
+			if err := q.client.LPush(ctx, readyKey, id).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requeueStale puts jobs left in the processing list by a worker that stopped
+// without finishing them back on the ready list.
+func (q *Queue) requeueStale(ctx context.Context) error {
+	for {
+		_, err := q.client.RPopLPush(ctx, processingKey, readyKey).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before the attempt after the given one.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.BaseDelay
+	for i := 1; i < attempts && delay < q.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, q.MaxDelay)
+}
+
+func (q *Queue) save(ctx context.Context, rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+	if err := q.client.Set(ctx, jobKey(rec.JobID.String()), data, jobTTL).Err(); err != nil {
+		return fmt.Errorf("failed to store job: %v", err)
+	}
+	return nil
+}
+
+func (q *Queue) load(ctx context.Context, id string) (*record, error) {
+	data, err := q.client.Get(ctx, jobKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %v", err)
+	}
+
+	var rec record
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %v", err)
+	}
+	return &rec, nil
+}
+
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+func jobKey(id string) string {
+	return fmt.Sprintf("job:%s", id)
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("bad payload")
+	err := fmt.Errorf("job failed: %w", Permanent(base))
+
+	if !isPermanent(err) {
+		t.Error("expected a wrapped permanent error to be permanent")
+	}
+	if !errors.Is(err, base) {
+		t.Error("expected Permanent to keep the original error")
+	}
+	if isPermanent(base) {
+		t.Error("expected a plain error not to be permanent")
+	}
+}
+
+// skipWithoutDocker skips tests that need containers when Docker is not
+// available. testcontainers panics when it cannot find a Docker host.
+func skipWithoutDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+func startKeyDB(t *testing.T) *redis.Client {
+	t.Helper()
+	skipWithoutDocker(t)
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "eqalpha/keydb:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to start container: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: host + ":" + port.Port()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func waitForStatus(t *testing.T, q *Queue, jobID uuid.UUID, status string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(context.Background(), jobID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not reach status %s", jobID, status)
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	client := startKeyDB(t)
+	ctx := context.Background()
+
+	q := NewQueue(client)
+	q.BaseDelay = 10 * time.Millisecond
+	var flakyCalls atomic.Int32
+	q.Register("echo", func(ctx context.Context, job *Job, payload json.RawMessage) (interface{}, error) {
+		var text string
+		if err := json.Unmarshal(payload, &text); err != nil {
+			return nil, Permanent(err)
+		}
+		return text, nil
+	})
+	q.Register("flaky", func(ctx context.Context, job *Job, payload json.RawMessage) (interface{}, error) {
+		if flakyCalls.Add(1) < 2 {
+			return nil, errors.New("try again")
+		}
+		return "ok", nil
+	})
+	q.Register("broken", func(ctx context.Context, job *Job, payload json.RawMessage) (interface{}, error) {
+		return nil, errors.New("always fails")
+	})
+
+	pool := NewPool(q, 2)
+	pool.Start()
+
+	userID := uuid.New()
+	echo, err := q.Enqueue(ctx, "echo", userID, "hello")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	flaky, err := q.Enqueue(ctx, "flaky", userID, nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	broken, err := q.Enqueue(ctx, "broken", userID, nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if _, err := q.Enqueue(ctx, "unknown", userID, nil); err == nil {
+		t.Error("expected an error for an unknown job type")
+	}
+
+	if job := waitForStatus(t, q, echo.JobID, StatusSucceeded); string(job.Result) != `"hello"` {
+		t.Errorf("unexpected result %s", job.Result)
+	}
+	if job := waitForStatus(t, q, flaky.JobID, StatusSucceeded); job.Attempts != 2 {
+		t.Errorf("expected the flaky job to succeed on the second attempt, got %d attempts", job.Attempts)
+	}
+	if job := waitForStatus(t, q, broken.JobID, StatusFailed); job.Attempts != q.MaxAttempts || job.Error != "always fails" {
+		t.Errorf("unexpected failed job %+v", job)
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := pool.Drain(drainCtx); err != nil {
+		t.Errorf("Drain() error = %v", err)
+	}
+	if n := client.LLen(ctx, processingKey).Val(); n != 0 {
+		t.Errorf("expected no job left processing, got %d", n)
+	}
+}
+
+func TestDrainRequeuesInterruptedJobs(t *testing.T) {
+	client := startKeyDB(t)
+	ctx := context.Background()
+
+	q := NewQueue(client)
+	started := make(chan struct{})
+	q.Register("slow", func(ctx context.Context, job *Job, payload json.RawMessage) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	pool := NewPool(q, 1)
+	pool.Start()
+	job, err := q.Enqueue(ctx, "slow", uuid.New(), nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	<-started
+
+	drainCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := pool.Drain(drainCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Drain to time out, got %v", err)
+	}
+
+	interrupted, err := q.Get(ctx, job.JobID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if interrupted.Status != StatusQueued || interrupted.Attempts != 0 {
+		t.Errorf("expected the interrupted job to be queued again, got %+v", interrupted)
+	}
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	// Send delivers the message to all of its recipients
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns an SMTP mailer when SMTP_HOST is set, and otherwise an outbox
+// that keeps messages in memory and writes them to MAIL_OUTBOX_DIR if set.
+func New() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Test Alchemy <no-reply@localhost>"
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	}
+
+	dir := os.Getenv("MAIL_OUTBOX_DIR")
+	log.Printf("SMTP_HOST is not set, emails are kept in the local outbox %s", dir)
+	return NewOutbox(dir)
+}
+
+// format renders the message as an RFC 5322 email.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.SentAt.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outbox is a Mailer that keeps sent messages in memory instead of
+// delivering them. When created with a directory it also writes every
+// message there as an .eml file, which is handy for local development.
+type Outbox struct {
+	mu       sync.Mutex
+	dir      string
+	messages []Message
+}
+
+func NewOutbox(dir string) *Outbox {
+	return &Outbox{dir: dir}
+}
+
+// Send implements Mailer
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	msg.SentAt = time.Now()
+	o.messages = append(o.messages, msg)
+
+	if o.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(o.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %v", err)
+	}
+	name := fmt.Sprintf("%s-%03d.eml", msg.SentAt.Format("20060102T150405"), len(o.messages))
+	if err := os.WriteFile(filepath.Join(o.dir, name), format("outbox@localhost", msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write message to outbox: %v", err)
+	}
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// Last returns the most recently sent message to the recipient.
+func (o *Outbox) Last(to string) (Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.messages) - 1; i >= 0; i-- {
+		for _, recipient := range o.messages[i].To {
+			if recipient == to {
+				return o.messages[i], true
+			}
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutboxSend(t *testing.T) {
+	dir := t.TempDir()
+	outbox := NewOutbox(dir)
+	ctx := context.Background()
+
+	if err := outbox.Send(ctx, Message{To: []string{"a@example.com"}, Subject: "First", Body: "one"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := outbox.Send(ctx, Message{To: []string{"b@example.com"}, Subject: "Second", Body: "two"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := len(outbox.Messages()); got != 2 {
+		t.Fatalf("expected 2 messages, got %d", got)
+	}
+
+	msg, ok := outbox.Last("a@example.com")
+	if !ok {
+		t.Fatal("expected a message for a@example.com")
+	}
+	if msg.Subject != "First" {
+		t.Fatalf("expected subject 'First', got %q", msg.Subject)
+	}
+	if _, ok := outbox.Last("c@example.com"); ok {
+		t.Fatal("expected no message for c@example.com")
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files in the outbox directory, got %d", len(files))
+	}
+	data, err := os.ReadFile(dir + "/" + files[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Subject: First") {
+		t.Fatalf("expected written message to contain the subject, got %q", data)
+	}
+}
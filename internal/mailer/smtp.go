@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends emails through an SMTP server.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send implements Mailer
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %v", err)
+	}
+
+	msg.SentAt = time.Now()
+	if err := smtp.SendMail(m.addr, m.auth, sender.Address, msg.To, format(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
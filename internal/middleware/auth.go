@@ -1,15 +1,34 @@
 package middleware
 
 import (
+	"log"
+	"net/http"
+	"strings"
+
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/services"
 	"TestAlchemy/internal/session"
 	"github.com/labstack/echo/v4"
-	"net/http"
 )
 
-func RequireAuth(sessionStore *session.Store) echo.MiddlewareFunc {
+// RequireAuth authenticates requests with either a session cookie or a
+// personal access token sent as "Authorization: Bearer <token>". Access
+// tokens need the read scope for safe methods and the write scope for the
+// others; RequireScope asks for more on specific routes.
+func RequireAuth(sessionStore *session.Store, accessTokens *services.AccessTokenService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			cookie, err := c.Cookie("session_id")
+			if token, ok := bearerToken(c); ok {
+				accessToken, err := accessTokens.Authenticate(c.Request().Context(), token)
+				if err != nil {
+					return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid access token"})
+				}
+				c.Set("user_id", accessToken.UserID)
+				c.Set("access_token", accessToken)
+				return requireScope(c, methodScope(c.Request().Method), next)
+			}
+
+			cookie, err := c.Cookie(session.CookieName)
 			if err != nil {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 			}
@@ -22,9 +41,65 @@ func RequireAuth(sessionStore *session.Store) echo.MiddlewareFunc {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "session expired"})
 			}
 
-			// Store user ID in context for later use
+			// Slide the idle expiry. The request is authenticated either way,
+			// so a failed refresh is only logged.
+			if err := sessionStore.Touch(c.Request().Context(), cookie.Value, sess); err != nil {
+				log.Printf("failed to refresh session of user %s: %v", sess.UserID, err)
+			}
+
+			// Store user and session IDs in context for later use
 			c.Set("user_id", sess.UserID)
+			c.Set("session_id", cookie.Value)
+			return next(c)
+		}
+	}
+}
+
+// RequireScope requires requests authenticated with an access token to
+// have the given scope. Sessions have every scope.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return requireScope(c, scope, next)
+		}
+	}
+}
+
+// RequireSession refuses requests authenticated with an access token, for
+// routes that manage the credentials of the account.
+func RequireSession() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := c.Get("access_token").(*models.AccessToken); ok {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "access tokens cannot be used here, sign in instead"})
+			}
 			return next(c)
 		}
 	}
 }
+
+func requireScope(c echo.Context, scope string, next echo.HandlerFunc) error {
+	accessToken, ok := c.Get("access_token").(*models.AccessToken)
+	if ok && !accessToken.HasScope(scope) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "access token lacks the " + scope + " scope"})
+	}
+	return next(c)
+}
+
+func methodScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return models.ScopeRead
+	default:
+		return models.ScopeWrite
+	}
+}
+
+func bearerToken(c echo.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken *models.AccessToken
+		scope       string
+		want        int
+	}{
+		{name: "session", scope: models.ScopeAdmin, want: http.StatusOK},
+		{name: "enough scope", accessToken: &models.AccessToken{Scopes: []string{models.ScopeWrite}}, scope: models.ScopeRead, want: http.StatusOK},
+		{name: "missing scope", accessToken: &models.AccessToken{Scopes: []string{models.ScopeWrite}}, scope: models.ScopeAdmin, want: http.StatusForbidden},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			if tt.accessToken != nil {
+				c.Set("access_token", tt.accessToken)
+			}
+
+			handler := RequireScope(tt.scope)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			if err := handler(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireSession(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	c.Set("access_token", &models.AccessToken{Scopes: []string{models.ScopeAdmin}})
+
+	handler := RequireSession()(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	if err := handler(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer ta_abc", "ta_abc", true},
+		{"bearer  ta_abc ", "ta_abc", true},
+		{"Basic dXNlcg==", "", false},
+		{"Bearer ", "", false},
+		{"", "", false},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(echo.HeaderAuthorization, tt.header)
+		got, ok := bearerToken(e.NewContext(req, httptest.NewRecorder()))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("bearerToken(%q) = %q, %v, want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
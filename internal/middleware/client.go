@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"TestAlchemy/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// ClientInfo stores the IP address and user agent of the request in its
+// context, for the audit events recorded while handling it. The address
+// comes from the IP extractor of the server, which decides whether
+// forwarding headers are trusted.
+func ClientInfo() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := services.WithClient(req.Context(), services.Client{
+				IP:        c.RealIP(),
+				UserAgent: req.UserAgent(),
+			})
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
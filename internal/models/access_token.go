@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes of personal access tokens. Each scope includes the ones below it.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+var scopeRanks = map[string]int{
+	ScopeRead:  1,
+	ScopeWrite: 2,
+	ScopeAdmin: 3,
+}
+
+// AccessToken is a personal access token, used by scripts and CI jobs in
+// place of a session. Only the hash of the token is stored.
+type AccessToken struct {
+	AccessTokenID uuid.UUID  `gorm:"type:char(36);primary_key" json:"access_token_id"`
+	UserID        uuid.UUID  `gorm:"type:char(36);not null;index" json:"user_id"`
+	User          *User      `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Name          string     `gorm:"size:100;not null" json:"name"`
+	TokenHash     string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes        []string   `gorm:"type:json;serializer:json;not null" json:"scopes"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	CreatedAt     time.Time  `gorm:"not null;autoCreateTime" json:"created_at"`
+}
+
+// HasScope reports whether one of the token's scopes is at least as
+// privileged as scope.
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if scopeRanks[s] >= scopeRanks[scope] && scopeRanks[scope] > 0 {
+			return true
+		}
+	}
+	return false
+}
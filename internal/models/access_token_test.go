@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestAccessTokenHasScope(t *testing.T) {
+	tests := []struct {
+		scopes   []string
+		required string
+		want     bool
+	}{
+		{[]string{ScopeRead}, ScopeRead, true},
+		{[]string{ScopeRead}, ScopeWrite, false},
+		{[]string{ScopeWrite}, ScopeRead, true},
+		{[]string{ScopeRead, ScopeAdmin}, ScopeWrite, true},
+		{[]string{ScopeWrite}, ScopeAdmin, false},
+		{nil, ScopeRead, false},
+		{[]string{ScopeAdmin}, "owner", false},
+	}
+
+	for _, tt := range tests {
+		token := &AccessToken{Scopes: tt.scopes}
+		if got := token.HasScope(tt.required); got != tt.want {
+			t.Errorf("HasScope(%q) for scopes %v = %v, want %v", tt.required, tt.scopes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file uploaded to a test case, such as a screenshot kept as
+// evidence. It may belong to one step of the test case; when that step is
+// deleted the attachment stays on the test case. The file itself lives in
+// the blob store under StorageKey.
+type Attachment struct {
+	AttachmentID uuid.UUID  `gorm:"type:char(36);primary_key" json:"attachment_id"`
+	ProjectID    uuid.UUID  `gorm:"type:char(36);not null;index" json:"project_id"`
+	TestCaseID   uuid.UUID  `gorm:"type:char(36);not null;index" json:"test_case_id"`
+	TestCase     *TestCase  `gorm:"foreignKey:TestCaseID;references:TestCaseID;constraint:OnDelete:CASCADE" json:"-"`
+	TestStepID   *uuid.UUID `gorm:"type:char(36);index" json:"test_step_id"`
+	TestStep     *TestStep  `gorm:"foreignKey:TestStepID;references:TestStepID;constraint:OnDelete:SET NULL" json:"-"`
+	UploaderID   uuid.UUID  `gorm:"type:char(36);not null" json:"uploader_id"`
+	FileName     string     `gorm:"size:255;not null" json:"file_name"`
+	ContentType  string     `gorm:"size:127;not null" json:"content_type"`
+	Size         int64      `gorm:"not null" json:"size"`
+	StorageKey   string     `gorm:"size:255;not null" json:"-"`
+	CreatedAt    time.Time  `gorm:"not null;autoCreateTime" json:"created_at"`
+}
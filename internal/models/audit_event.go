@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditUserRegister         = "user.register"
+	AuditUserVerifyEmail      = "user.verify_email"
+	AuditUserLogin            = "user.login"
+	AuditUserLoginFailed      = "user.login_failed"
+	AuditUserLockout          = "user.lockout"
+	AuditUserLogout           = "user.logout"
+	AuditSessionRevoke        = "session.revoke"
+	AuditAccessTokenCreate    = "access_token.create"
+	AuditAccessTokenRevoke    = "access_token.revoke"
+	AuditMFAEnable            = "mfa.enable"
+	AuditMFADisable           = "mfa.disable"
+	AuditMFARecoveryCodes     = "mfa.recovery_codes"
+	AuditMFARecoveryCodeUse   = "mfa.recovery_code_use"
+	AuditUserPasswordChange   = "user.password_change"
+	AuditUserSSOLink          = "user.sso_link"
+	AuditUserAdminChange      = "user.admin_change"
+	AuditPasswordResetRequest = "user.password_reset_request"
+	AuditPasswordReset        = "user.password_reset"
+	AuditProjectCreate        = "project.create"
+	AuditProjectUpdate        = "project.update"
+	AuditProjectDelete        = "project.delete"
+	AuditProjectTransfer      = "project.transfer"
+	AuditProjectExport        = "project.export"
+	AuditProjectImport        = "project.import"
+	AuditMemberAdd            = "member.add"
+	AuditMemberUpdateRole     = "member.update_role"
+	AuditMemberRemove         = "member.remove"
+	AuditInvitationCreate     = "invitation.create"
+	AuditInvitationRevoke     = "invitation.revoke"
+	AuditInvitationAccept     = "invitation.accept"
+	AuditTestCaseDelete       = "test_case.delete"
+	AuditTestCaseRestore      = "test_case.restore"
+	AuditAttachmentDelete     = "attachment.delete"
+	AuditTagMerge             = "tag.merge"
+	AuditTagDelete            = "tag.delete"
+)
+
+// AuditEvent records who did what to which target, and from where. Events
+// are only ever inserted.
+type AuditEvent struct {
+	AuditEventID uuid.UUID `gorm:"type:char(36);primary_key" json:"audit_event_id"`
+	// ProjectID is nil for events outside of a project, such as logins
+	ProjectID *uuid.UUID `gorm:"type:char(36);index" json:"project_id"`
+	// ActorID is nil when the actor is unknown, such as a failed login for
+	// an email that is not registered
+	ActorID    *uuid.UUID      `gorm:"type:char(36);index" json:"actor_id"`
+	IP         string          `gorm:"size:64" json:"ip"`
+	UserAgent  string          `gorm:"size:512" json:"user_agent"`
+	Action     string          `gorm:"size:64;not null;index" json:"action"`
+	TargetType string          `gorm:"size:32;index:idx_audit_events_target" json:"target_type"`
+	TargetID   string          `gorm:"size:255;index:idx_audit_events_target" json:"target_id"`
+	Metadata   json.RawMessage `gorm:"type:json" json:"metadata"`
+	CreatedAt  time.Time       `gorm:"not null;autoCreateTime;index" json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Project struct {
+	ProjectID   uuid.UUID      `gorm:"type:char(36);primary_key" json:"project_id"`
+	UserID      uuid.UUID      `gorm:"type:char(36);not null;index" json:"user_id"`
+	Name        string         `gorm:"size:255;not null" json:"name"`
+	Description string         `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var Roles = []string{RoleOwner, RoleEditor, RoleViewer}
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// ProjectMember grants a user a role on a project.
+type ProjectMember struct {
+	ProjectID uuid.UUID `gorm:"type:char(36);primary_key" json:"project_id"`
+	UserID    uuid.UUID `gorm:"type:char(36);primary_key;index" json:"user_id"`
+	Role      string    `gorm:"size:16;not null" json:"role"`
+	CreatedAt time.Time `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
+
+// HasRole reports whether the member's role is at least as privileged as role.
+func (m *ProjectMember) HasRole(role string) bool {
+	return roleRanks[m.Role] >= roleRanks[role] && roleRanks[role] > 0
+}
@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestProjectMemberHasRole(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{RoleOwner, RoleOwner, true},
+		{RoleOwner, RoleViewer, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleEditor, RoleOwner, false},
+		{RoleViewer, RoleEditor, false},
+		{"", RoleViewer, false},
+		{RoleOwner, "admin", false},
+	}
+
+	for _, tt := range tests {
+		m := &ProjectMember{Role: tt.role}
+		if got := m.HasRole(tt.required); got != tt.want {
+			t.Errorf("HasRole(%q) for role %q = %v, want %v", tt.required, tt.role, got, tt.want)
+		}
+	}
+}
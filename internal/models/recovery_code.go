@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the user
+// has lost their authenticator. Only the hash of the code is stored.
+type RecoveryCode struct {
+	RecoveryCodeID uuid.UUID `gorm:"type:char(36);primary_key"`
+	UserID         uuid.UUID `gorm:"type:char(36);not null;index"`
+	User           *User     `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE"`
+	CodeHash       string    `gorm:"size:64;not null;index"`
+	UsedAt         *time.Time
+	CreatedAt      time.Time `gorm:"not null"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag labels test cases within a project. Tag names are unique per project.
+type Tag struct {
+	TagID     uuid.UUID `gorm:"type:char(36);primary_key" json:"tag_id"`
+	ProjectID uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_tags_project_name" json:"project_id"`
+	Name      string    `gorm:"size:64;not null;uniqueIndex:idx_tags_project_name" json:"name"`
+	CreatedAt time.Time `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
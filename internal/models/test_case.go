@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PriorityLow      = "low"
+	PriorityMedium   = "medium"
+	PriorityHigh     = "high"
+	PriorityCritical = "critical"
+)
+
+const (
+	StatusDraft      = "draft"
+	StatusReady      = "ready"
+	StatusDeprecated = "deprecated"
+)
+
+var (
+	Priorities = []string{PriorityLow, PriorityMedium, PriorityHigh, PriorityCritical}
+	Statuses   = []string{StatusDraft, StatusReady, StatusDeprecated}
+)
+
+type TestCase struct {
+	TestCaseID    uuid.UUID      `gorm:"type:char(36);primary_key" json:"test_case_id"`
+	ProjectID     uuid.UUID      `gorm:"type:char(36);not null;index" json:"project_id"`
+	Project       *Project       `gorm:"foreignKey:ProjectID;references:ProjectID" json:"-"`
+	AuthorID      uuid.UUID      `gorm:"type:char(36);not null;index" json:"author_id"`
+	Title         string         `gorm:"size:255;not null;index:idx_test_cases_fulltext,class:FULLTEXT" json:"title"`
+	Description   string         `gorm:"type:text;index:idx_test_cases_fulltext,class:FULLTEXT" json:"description"`
+	Preconditions string         `gorm:"type:text" json:"preconditions"`
+	UserLevel     string         `gorm:"size:64" json:"user_level"`
+	Priority      string         `gorm:"size:16;not null;default:medium" json:"priority"`
+	Status        string         `gorm:"size:16;not null;default:draft" json:"status"`
+	Steps         []TestStep     `gorm:"foreignKey:TestCaseID;references:TestCaseID;constraint:OnDelete:CASCADE" json:"steps"`
+	Tags          []Tag          `gorm:"many2many:test_case_tags;foreignKey:TestCaseID;joinForeignKey:TestCaseID;references:TagID;joinReferences:TagID" json:"tags"`
+	CreatedAt     time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TestStep is a single action/expected-result pair of a test case. Steps
+// are ordered by Position, starting at 1.
+type TestStep struct {
+	TestStepID     uuid.UUID `gorm:"type:char(36);primary_key" json:"test_step_id"`
+	TestCaseID     uuid.UUID `gorm:"type:char(36);not null;index:idx_test_steps_case_position" json:"test_case_id"`
+	Position       int       `gorm:"not null;index:idx_test_steps_case_position" json:"position"`
+	Action         string    `gorm:"type:text;not null;index:idx_test_steps_fulltext,class:FULLTEXT" json:"action"`
+	ExpectedResult string    `gorm:"type:text;index:idx_test_steps_fulltext,class:FULLTEXT" json:"expected_result"`
+	CreatedAt      time.Time `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestCaseRevision is an immutable snapshot of a test case and its steps,
+// recorded every time the case changes. Revisions of a case are numbered
+// from 1 and never updated or deleted while the case exists.
+type TestCaseRevision struct {
+	TestCaseRevisionID uuid.UUID `gorm:"type:char(36);primary_key" json:"test_case_revision_id"`
+	TestCaseID         uuid.UUID `gorm:"type:char(36);not null;uniqueIndex:idx_test_case_revisions_case_number" json:"test_case_id"`
+	TestCase           *TestCase `gorm:"foreignKey:TestCaseID;references:TestCaseID;constraint:OnDelete:CASCADE" json:"-"`
+	Number             int       `gorm:"not null;uniqueIndex:idx_test_case_revisions_case_number" json:"number"`
+	// AuthorID is the user who made the change the revision records
+	AuthorID uuid.UUID `gorm:"type:char(36);not null;index" json:"author_id"`
+	// RestoredFrom is the number of the revision this one restored, if any
+	RestoredFrom  *int   `json:"restored_from"`
+	Title         string `gorm:"size:255;not null" json:"title"`
+	Description   string `gorm:"type:text" json:"description"`
+	Preconditions string `gorm:"type:text" json:"preconditions"`
+	UserLevel     string `gorm:"size:64" json:"user_level"`
+	Priority      string `gorm:"size:16;not null" json:"priority"`
+	Status        string `gorm:"size:16;not null" json:"status"`
+	// Tags holds the comma-separated tag names of the test case
+	Tags      string                 `gorm:"type:text" json:"tags"`
+	Steps     []TestCaseRevisionStep `gorm:"foreignKey:TestCaseRevisionID;references:TestCaseRevisionID;constraint:OnDelete:CASCADE" json:"steps,omitempty"`
+	CreatedAt time.Time              `gorm:"not null" json:"created_at"`
+}
+
+// TestCaseRevisionStep is the snapshot of a test step. TestStepID identifies
+// the step across revisions.
+type TestCaseRevisionStep struct {
+	TestCaseRevisionStepID uuid.UUID `gorm:"type:char(36);primary_key" json:"-"`
+	TestCaseRevisionID     uuid.UUID `gorm:"type:char(36);not null;index" json:"-"`
+	TestStepID             uuid.UUID `gorm:"type:char(36);not null" json:"test_step_id"`
+	Position               int       `gorm:"not null" json:"position"`
+	Action                 string    `gorm:"type:text;not null" json:"action"`
+	ExpectedResult         string    `gorm:"type:text" json:"expected_result"`
+}
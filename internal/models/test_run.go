@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RunOpen   = "open"
+	RunClosed = "closed"
+)
+
+// Result statuses recorded for a step, or for a test case without steps.
+const (
+	ResultPassed  = "passed"
+	ResultFailed  = "failed"
+	ResultBlocked = "blocked"
+	ResultSkipped = "skipped"
+)
+
+// Statuses of a test case in a run that are derived from its results.
+const (
+	ResultUntested   = "untested"
+	ResultInProgress = "in_progress"
+)
+
+var ResultStatuses = []string{ResultPassed, ResultFailed, ResultBlocked, ResultSkipped}
+
+// TestRun is an execution of a set of test cases. The cases are copied when
+// the run starts, so later edits do not change what was executed.
+type TestRun struct {
+	TestRunID uuid.UUID     `gorm:"type:char(36);primary_key" json:"test_run_id"`
+	ProjectID uuid.UUID     `gorm:"type:char(36);not null;index" json:"project_id"`
+	Project   *Project      `gorm:"foreignKey:ProjectID;references:ProjectID" json:"-"`
+	Name      string        `gorm:"size:255;not null" json:"name"`
+	Status    string        `gorm:"size:16;not null;default:open" json:"status"`
+	CreatedBy uuid.UUID     `gorm:"type:char(36);not null" json:"created_by"`
+	Cases     []TestRunCase `gorm:"foreignKey:TestRunID;references:TestRunID;constraint:OnDelete:CASCADE" json:"cases,omitempty"`
+	ClosedAt  *time.Time    `json:"closed_at"`
+	CreatedAt time.Time     `gorm:"not null;autoCreateTime;index" json:"created_at"`
+	UpdatedAt time.Time     `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
+
+// TestRunCase is the snapshot of a test case taken when a run starts. Its
+// status sums up the results recorded for it.
+type TestRunCase struct {
+	TestRunCaseID uuid.UUID `gorm:"type:char(36);primary_key" json:"test_run_case_id"`
+	TestRunID     uuid.UUID `gorm:"type:char(36);not null;index:idx_test_run_cases_run_position" json:"test_run_id"`
+	TestCaseID    uuid.UUID `gorm:"type:char(36);not null;index" json:"test_case_id"`
+	Position      int       `gorm:"not null;index:idx_test_run_cases_run_position" json:"position"`
+	Title         string    `gorm:"size:255;not null" json:"title"`
+	Description   string    `gorm:"type:text" json:"description"`
+	Preconditions string    `gorm:"type:text" json:"preconditions"`
+	Priority      string    `gorm:"size:16;not null" json:"priority"`
+	// Tags holds the comma-separated tag names of the test case
+	Tags      string        `gorm:"type:text" json:"tags"`
+	Status    string        `gorm:"size:16;not null;default:untested;index" json:"status"`
+	Steps     []TestRunStep `gorm:"foreignKey:TestRunCaseID;references:TestRunCaseID;constraint:OnDelete:CASCADE" json:"steps"`
+	Results   []TestResult  `gorm:"foreignKey:TestRunCaseID;references:TestRunCaseID;constraint:OnDelete:CASCADE" json:"results"`
+	CreatedAt time.Time     `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time     `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
+
+// TestRunStep is the snapshot of a test step.
+type TestRunStep struct {
+	TestRunStepID  uuid.UUID `gorm:"type:char(36);primary_key" json:"test_run_step_id"`
+	TestRunCaseID  uuid.UUID `gorm:"type:char(36);not null;index" json:"test_run_case_id"`
+	Position       int       `gorm:"not null" json:"position"`
+	Action         string    `gorm:"type:text;not null" json:"action"`
+	ExpectedResult string    `gorm:"type:text" json:"expected_result"`
+}
+
+// TestResult is the outcome of executing one step of a test case in a run,
+// or the whole test case when it has no steps. Recording a step again
+// replaces its result.
+type TestResult struct {
+	TestResultID  uuid.UUID  `gorm:"type:char(36);primary_key" json:"test_result_id"`
+	TestRunID     uuid.UUID  `gorm:"type:char(36);not null;index" json:"test_run_id"`
+	TestRunCaseID uuid.UUID  `gorm:"type:char(36);not null;index" json:"test_run_case_id"`
+	TestRunStepID *uuid.UUID `gorm:"type:char(36);index" json:"test_run_step_id"`
+	Status        string     `gorm:"size:16;not null" json:"status"`
+	Notes         string     `gorm:"type:text" json:"notes"`
+	ExecutorID    uuid.UUID  `gorm:"type:char(36);not null" json:"executor_id"`
+	DurationMs    int64      `gorm:"not null;default:0" json:"duration_ms"`
+	CreatedAt     time.Time  `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null;autoUpdateTime" json:"updated_at"`
+}
+
+// CaseStatus sums up the results of a test case with the given number of
+// steps: any failure fails the case, then any blocked step blocks it. A case
+// passes once every step has a result and at least one passed, and is
+// skipped when every step was skipped.
+func CaseStatus(steps int, results []TestResult) string {
+	if len(results) == 0 {
+		return ResultUntested
+	}
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	switch {
+	case counts[ResultFailed] > 0:
+		return ResultFailed
+	case counts[ResultBlocked] > 0:
+		return ResultBlocked
+	case len(results) < max(steps, 1):
+		return ResultInProgress
+	case counts[ResultPassed] > 0:
+		return ResultPassed
+	default:
+		return ResultSkipped
+	}
+}
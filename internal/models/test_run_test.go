@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestCaseStatus(t *testing.T) {
+	results := func(statuses ...string) []TestResult {
+		var rs []TestResult
+		for _, status := range statuses {
+			rs = append(rs, TestResult{Status: status})
+		}
+		return rs
+	}
+
+	tests := []struct {
+		name    string
+		steps   int
+		results []TestResult
+		want    string
+	}{
+		{"no results", 3, nil, ResultUntested},
+		{"partly passed", 3, results(ResultPassed), ResultInProgress},
+		{"all passed", 2, results(ResultPassed, ResultPassed), ResultPassed},
+		{"passed and skipped", 2, results(ResultPassed, ResultSkipped), ResultPassed},
+		{"all skipped", 2, results(ResultSkipped, ResultSkipped), ResultSkipped},
+		{"failed early", 3, results(ResultFailed), ResultFailed},
+		{"failure beats blocked", 3, results(ResultBlocked, ResultFailed), ResultFailed},
+		{"blocked", 2, results(ResultPassed, ResultBlocked), ResultBlocked},
+		{"case without steps", 0, results(ResultPassed), ResultPassed},
+	}
+
+	for _, tt := range tests {
+		if got := CaseStatus(tt.steps, tt.results); got != tt.want {
+			t.Errorf("%s: CaseStatus() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
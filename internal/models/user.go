@@ -10,8 +10,24 @@ type User struct {
 	UserID       uuid.UUID `gorm:"type:char(36);primary_key"`
 	Email        string    `gorm:"unique;not null"`
 	PasswordHash string    `gorm:"not null"`
-	CreatedAt    time.Time `gorm:"not null;autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"not null;autoUpdateTime"`
+	// EmailVerified is set once the user has followed the link sent to
+	// their address. Unverified users cannot log in.
+	EmailVerified bool `gorm:"not null;default:false"`
+	// MFAEnabled requires a TOTP or recovery code on login, once the user
+	// has confirmed enrolment of TOTPSecret
+	MFAEnabled bool   `gorm:"not null;default:false"`
+	TOTPSecret string `gorm:"size:64"`
+	// TOTPCounter is the time step of the last accepted TOTP code, which
+	// cannot be used again
+	TOTPCounter int64 `gorm:"not null;default:0"`
+	// OIDCSubject is the subject identifier of the user at the OpenID
+	// provider, once they have signed in with single sign-on
+	OIDCSubject *string `gorm:"size:255;uniqueIndex"`
+	// IsAdmin grants access to instance-wide administration, such as the
+	// audit log of every project
+	IsAdmin   bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time `gorm:"not null;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime"`
 }
 
 func (u *User) HashPassword(password string) error {
@@ -0,0 +1,221 @@
+// Package oidc is a minimal OpenID Connect relying party: it runs the
+// authorization code flow with PKCE and verifies the ID tokens it gets
+// back. Signing keys are fetched from the issuer's JWKS endpoint.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leeway is the clock skew tolerated when checking token times.
+const leeway = time.Minute
+
+// ErrInvalidToken is returned for ID tokens that fail verification.
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Config describes a client registered with an OpenID provider.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested in addition to "openid"
+	Scopes []string
+}
+
+// New returns the provider configured by the OIDC_* environment variables,
+// or nil when OIDC_ISSUER_URL is not set and single sign-on is disabled.
+// OIDC_SCOPES replaces the default "email profile" scopes, for instance
+// to add one that releases a group claim.
+func New() *Provider {
+	issuer := os.Getenv("OIDC_ISSUER_URL")
+	if issuer == "" {
+		log.Println("OIDC_ISSUER_URL is not set, single sign-on is disabled")
+		return nil
+	}
+
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		baseURL := os.Getenv("APP_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8080"
+		}
+		redirectURL = strings.TrimRight(baseURL, "/") + "/api/sso/callback"
+	}
+	scopes := []string{"email", "profile"}
+	if extra := os.Getenv("OIDC_SCOPES"); extra != "" {
+		scopes = strings.Fields(strings.ReplaceAll(extra, ",", " "))
+	}
+	return NewProvider(Config{
+		IssuerURL:    issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+	})
+}
+
+// Provider is an OpenID provider. Its discovery document and keys are
+// fetched on first use, so that the application starts while the
+// provider is unreachable.
+type Provider struct {
+	config Config
+	client *http.Client
+	// Now returns the current time. Tests replace it with a fake clock.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]interface{} // public keys by key ID
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func NewProvider(config Config) *Provider {
+	config.IssuerURL = strings.TrimRight(config.IssuerURL, "/")
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+		Now:    time.Now,
+	}
+}
+
+// AuthCodeURL returns the URL to send the user to for signing in. The
+// state and nonce are checked on the way back; verifier is the PKCE code
+// verifier to pass to Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(append([]string{"openid"}, p.config.Scopes...), " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Exchange trades an authorization code for tokens and returns the
+// verified claims of the ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.config.ClientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var response struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &response)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", status, response.Error, response.ErrorDescription)
+	}
+	if response.IDToken == "" {
+		return nil, errors.New("token response has no ID token")
+	}
+	return p.Verify(ctx, response.IDToken, nonce)
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.IssuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var d discovery
+	status, err := p.do(req, &d)
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery failed with status %d", status)
+	}
+	if strings.TrimRight(d.Issuer, "/") != p.config.IssuerURL {
+		return nil, fmt.Errorf("discovery returned issuer %q, expected %q", d.Issuer, p.config.IssuerURL)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// do sends a request and decodes the JSON response into v, whatever its
+// status, which is returned.
+func (p *Provider) do(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,180 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/oidc"
+	"TestAlchemy/internal/oidc/oidctest"
+)
+
+const (
+	clientID    = "test-alchemy"
+	redirectURL = "http://localhost:8080/api/sso/callback"
+)
+
+func startIssuer(t *testing.T) (*oidctest.Issuer, *oidc.Provider) {
+	t.Helper()
+	issuer, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatalf("failed to start issuer: %v", err)
+	}
+	t.Cleanup(issuer.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    issuer.URL,
+		ClientID:     clientID,
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"email", "profile"},
+	})
+	return issuer, provider
+}
+
+// signIn runs the flow up to the callback and returns its code and state.
+func signIn(t *testing.T, issuer *oidctest.Issuer, provider *oidc.Provider, verifier string, claims map[string]interface{}) (string, string) {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	callback, err := issuer.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(callback, redirectURL) {
+		t.Fatalf("unexpected callback %s", callback)
+	}
+	return u.Query().Get("code"), u.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer, provider := startIssuer(t)
+	ctx := context.Background()
+
+	code, state := signIn(t, issuer, provider, "verifier-1", map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"groups":         []string{"qa", "test-alchemy-admins"},
+	})
+	if state != "state-1" {
+		t.Errorf("expected the state to come back, got %q", state)
+	}
+
+	claims, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Error("expected the email to be verified")
+	}
+	if got := claims.Strings("groups"); !reflect.DeepEqual(got, []string{"qa", "test-alchemy-admins"}) {
+		t.Errorf("unexpected groups %v", got)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("expected a used code to be rejected")
+	}
+}
+
+func TestExchangeChecks(t *testing.T) {
+	issuer, provider := startIssuer(t)
+	ctx := context.Background()
+	claims := map[string]interface{}{"sub": "user-1"}
+
+	code, _ := signIn(t, issuer, provider, "verifier-1", claims)
+	if _, err := provider.Exchange(ctx, code, "another-verifier", "nonce-1"); err == nil {
+		t.Error("expected a wrong code verifier to be rejected")
+	}
+
+	code, _ = signIn(t, issuer, provider, "verifier-1", claims)
+	if _, err := provider.Exchange(ctx, code, "verifier-1", "another-nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("expected a wrong nonce to be rejected, got %v", err)
+	}
+
+	provider.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	code, _ = signIn(t, issuer, provider, "verifier-1", claims)
+	if _, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	issuer, provider := startIssuer(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   issuer.URL,
+			"sub":   "user-1",
+			"aud":   []string{"other", clientID},
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "nonce-1",
+		}
+	}
+	token, err := issuer.Sign(valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Verify(ctx, token, "nonce-1"); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// A token signed by someone else
+	other, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	forged, err := other.Sign(valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Verify(ctx, forged, "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("expected a forged token to be rejected, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(claims map[string]interface{})
+	}{
+		{"wrong issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }},
+		{"wrong audience", func(c map[string]interface{}) { c["aud"] = "other" }},
+		{"expired", func(c map[string]interface{}) { c["exp"] = now.Add(-time.Hour).Unix() }},
+		{"issued in the future", func(c map[string]interface{}) { c["iat"] = now.Add(time.Hour).Unix() }},
+		{"no subject", func(c map[string]interface{}) { delete(c, "sub") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.change(claims)
+			token, err := issuer.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := provider.Verify(ctx, token, "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+
+	if _, err := provider.Verify(ctx, "not.a-token", "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("expected a malformed token to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+// Package oidctest runs a mock OpenID provider for tests. It signs ID
+// tokens with a generated RSA key and enforces PKCE, but has no login
+// page: tests call Authorize to sign a user in.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const keyID = "test-key"
+
+// Issuer is a mock OpenID provider listening on a local port.
+type Issuer struct {
+	URL string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant is an authorization code waiting to be exchanged.
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	claims      map[string]interface{}
+}
+
+func NewIssuer() (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	issuer := &Issuer{key: key, codes: map[string]grant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	issuer.URL = issuer.server.URL
+	return issuer, nil
+}
+
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// Authorize plays the part of the user signing in at the provider. It
+// takes the authorization URL built by the client and returns the URL the
+// provider redirects back to, carrying the code and state. The ID token
+// gets the given claims on top of the standard ones; "sub" is required.
+func (i *Issuer) Authorize(authURL string, claims map[string]interface{}) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	if query.Get("response_type") != "code" {
+		return "", errors.New("response_type must be code")
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", errors.New("an S256 code challenge is required")
+	}
+	if _, ok := claims["sub"]; !ok {
+		return "", errors.New("the sub claim is required")
+	}
+
+	tokenClaims := map[string]interface{}{
+		"iss":   i.URL,
+		"aud":   query.Get("client_id"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		tokenClaims[name] = value
+	}
+
+	code := randomString()
+	i.mu.Lock()
+	i.codes[code] = grant{
+		clientID:    query.Get("client_id"),
+		redirectURI: query.Get("redirect_uri"),
+		challenge:   query.Get("code_challenge"),
+		claims:      tokenClaims,
+	}
+	i.mu.Unlock()
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		return "", err
+	}
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	return redirect.String(), nil
+}
+
+// Sign returns an ID token with the given claims, signed with the key of
+// the issuer.
+func (i *Issuer) Sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := i.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Codes are single use, whether the exchange succeeds or not
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	g, ok := i.codes[code]
+	delete(i.codes, code)
+	i.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown code"})
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(user)
+	}
+	switch {
+	case clientID != g.clientID:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client"})
+		return
+	case r.PostForm.Get("redirect_uri") != g.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri does not match"})
+		return
+	case challenge(r.PostForm.Get("code_verifier")) != g.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier does not match"})
+		return
+	}
+
+	idToken, err := i.Sign(g.claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
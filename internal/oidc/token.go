@@ -0,0 +1,262 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Claims are the claims of a verified ID token.
+type Claims struct {
+	Issuer   string   `json:"iss"`
+	Subject  string   `json:"sub"`
+	Audience audience `json:"aud"`
+	Expiry   float64  `json:"exp"`
+	IssuedAt float64  `json:"iat"`
+	Nonce    string   `json:"nonce"`
+	Email    string   `json:"email"`
+	// EmailVerified is nil when the provider does not send the claim
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+
+	raw map[string]json.RawMessage
+}
+
+// Strings returns a claim holding a string or a list of strings, such as
+// a group claim. It returns nil when the claim is missing.
+func (c *Claims) Strings(name string) []string {
+	raw, ok := c.raw[name]
+	if !ok {
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(raw, &values); err == nil {
+		return values
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil && value != "" {
+		return []string{value}
+	}
+	return nil
+}
+
+// audience is a single audience or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verify checks the signature, issuer, audience, times and nonce of an ID
+// token and returns its claims.
+func (p *Provider) Verify(ctx context.Context, idToken, nonce string) (*Claims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	key, err := p.key(ctx, h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(h.Algorithm, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := decodeSegment(parts[1], &claims.raw); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	if strings.TrimRight(claims.Issuer, "/") != p.config.IssuerURL {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, p.config.ClientID) {
+		return nil, fmt.Errorf("%w: token is not meant for this client", ErrInvalidToken)
+	}
+	now := p.Now()
+	if claims.Expiry == 0 || now.After(unixTime(claims.Expiry).Add(leeway)) {
+		return nil, fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if claims.IssuedAt != 0 && unixTime(claims.IssuedAt).After(now.Add(leeway)) {
+		return nil, fmt.Errorf("%w: token is issued in the future", ErrInvalidToken)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	return &claims, nil
+}
+
+func verifySignature(algorithm string, key interface{}, input string, signature []byte) error {
+	digest := sha256.Sum256([]byte(input))
+	switch algorithm {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: key does not match algorithm", ErrInvalidToken)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("%w: key does not match algorithm", ErrInvalidToken)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, algorithm)
+	}
+	return nil
+}
+
+// key returns the signing key with the given ID, fetching the keys of the
+// provider again when it is unknown, as happens after a key rotation.
+func (p *Provider) key(ctx context.Context, keyID string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.keys[keyID]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := p.do(req, &set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch keys: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch keys: status %d", status)
+	}
+
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.KeyID] = pub
+		}
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, keyID)
+}
+
+// jwk is a JSON web key, RFC 7517.
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
@@ -1,23 +1,38 @@
 package server
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 
 	"TestAlchemy/cmd/web"
+	"TestAlchemy/internal/ai"
+	"TestAlchemy/internal/blobstore"
 	"TestAlchemy/internal/database"
 	"TestAlchemy/internal/handlers"
+	"TestAlchemy/internal/jobs"
+	"TestAlchemy/internal/mailer"
 	"TestAlchemy/internal/middleware"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/oidc"
 	"TestAlchemy/internal/services"
 	"TestAlchemy/internal/session"
-	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
 	e := echo.New()
+	extractor, err := ipExtractor(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		panic(err)
+	}
+	e.IPExtractor = extractor
 	e.Use(echomiddleware.Logger())
 	e.Use(echomiddleware.Recover())
+	e.Use(middleware.ClientInfo())
 
 	e.Use(echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
 		AllowOrigins:     []string{"https://*", "http://*"},
@@ -33,24 +48,183 @@ func (s *Server) RegisterRoutes() http.Handler {
 	if err != nil {
 		panic(err)
 	}
-	userService := services.NewUserService(db, sessionStore)
-	userHandler := handlers.NewUserHandler(userService)
+	keydb := database.NewKeyDB()
+	mail := mailer.New()
+	projectService := services.NewProjectService(db)
+	auditService := services.NewAuditService(db, projectService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	invitationService := services.NewInvitationService(db, keydb, projectService, mail)
+	invitationHandler := handlers.NewInvitationHandler(invitationService, auditService)
+	mfaService := services.NewMFAService(db, keydb, auditService)
+	userService := services.NewUserService(db, keydb, sessionStore, mail, invitationService, mfaService, auditService)
+	userHandler := handlers.NewUserHandler(userService, sessionStore)
+	ssoService := services.NewSSOService(db, keydb, oidc.New(), userService, invitationService, auditService)
+	ssoHandler := handlers.NewSSOHandler(ssoService, sessionStore)
+	accessTokenService := services.NewAccessTokenService(db, auditService)
+	accessTokenHandler := handlers.NewAccessTokenHandler(accessTokenService)
+	sessionHandler := handlers.NewSessionHandler(userService, sessionStore)
+	passwordResetService := services.NewPasswordResetService(db, keydb, sessionStore, mail, auditService)
+	accountHandler := handlers.NewAccountHandler(userService, passwordResetService, mfaService, sessionStore)
+	projectHandler := handlers.NewProjectHandler(projectService, auditService)
+	testCaseService := services.NewTestCaseService(db, projectService)
+	testCaseHandler := handlers.NewTestCaseHandler(testCaseService, auditService)
+	revisionService := services.NewRevisionService(db, testCaseService)
+	revisionHandler := handlers.NewRevisionHandler(revisionService, auditService)
+	memberService := services.NewMemberService(db, projectService)
+	memberHandler := handlers.NewMemberHandler(memberService, auditService)
+	tagService := services.NewTagService(db, projectService)
+	tagHandler := handlers.NewTagHandler(tagService, auditService)
+	blobs := blobstore.New()
+	s.queue = jobs.NewQueue(keydb.Client())
+	exportService := services.NewExportService(db, projectService)
+	aiService := services.NewAIService(keydb, projectService, testCaseService, ai.New())
+	jobService := services.NewJobService(s.queue, aiService, exportService, blobs)
+	jobHandler := handlers.NewJobHandler(jobService)
+	exportHandler := handlers.NewExportHandler(exportService, jobService, auditService)
+	importService := services.NewImportService(db, projectService, testCaseService)
+	importHandler := handlers.NewImportHandler(importService, auditService)
+	aiHandler := handlers.NewAIHandler(aiService, jobService)
+	attachmentService := services.NewAttachmentService(db, testCaseService, blobs)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, auditService)
+	runService := services.NewRunService(db, projectService)
+	runHandler := handlers.NewRunHandler(runService)
+	reportService := services.NewReportService(db, projectService)
+	reportHandler := handlers.NewReportHandler(reportService, projectService)
 
 	fileServer := http.FileServer(http.FS(web.Files))
 	e.GET("/assets/*", echo.WrapHandler(fileServer))
 
 	// Public routes
-	e.GET("/web", echo.WrapHandler(templ.Handler(web.HelloForm())))
-	e.POST("/hello", echo.WrapHandler(http.HandlerFunc(web.HelloWebHandler)))
 	e.POST("/api/register", echo.WrapHandler(http.HandlerFunc(userHandler.Register)))
 	e.POST("/api/login", echo.WrapHandler(http.HandlerFunc(userHandler.Login)))
+	e.POST("/api/login/mfa", echo.WrapHandler(http.HandlerFunc(userHandler.LoginMFA)))
+	e.POST("/api/logout", sessionHandler.Logout)
+	e.GET("/api/sso/login", ssoHandler.Login)
+	e.GET("/api/sso/callback", ssoHandler.Callback)
+	e.POST("/api/sso/link", ssoHandler.Link)
+	e.POST("/api/password/forgot", accountHandler.ForgotPassword)
+	e.POST("/api/password/reset", accountHandler.ResetPassword)
+	e.POST("/api/email/verify", accountHandler.VerifyEmail)
+	e.POST("/api/email/resend", accountHandler.ResendVerification)
+	e.GET("/verify", accountHandler.VerifyEmailPage)
+	e.POST("/verify", accountHandler.VerifyEmailForm)
+	e.GET("/reset-password", accountHandler.ResetPasswordPage)
+	e.POST("/reset-password", accountHandler.ResetPasswordForm)
+	e.POST("/api/invitations/:token/decline", invitationHandler.Decline)
 	e.GET("/health", s.healthHandler)
 
-	// Protected routes - require authentication
+	// Protected routes - require authentication. Access tokens need the
+	// admin scope for the routes below marked admin, and cannot manage the
+	// account at all.
 	protected := e.Group("")
-	protected.Use(middleware.RequireAuth(sessionStore))
+	protected.Use(middleware.RequireAuth(sessionStore, accessTokenService))
+	admin := middleware.RequireScope(models.ScopeAdmin)
+	account := protected.Group("", middleware.RequireSession())
 	protected.GET("/", s.HelloWorldHandler)
-	// Add other protected routes here
+	protected.GET("/web", reportHandler.Dashboard)
+
+	// Account
+	account.PUT("/api/account/password", accountHandler.ChangePassword)
+	account.GET("/api/account/mfa", accountHandler.MFAStatus)
+	account.DELETE("/api/account/mfa", accountHandler.DisableMFA)
+	account.POST("/api/account/mfa/totp", accountHandler.StartTOTP)
+	account.POST("/api/account/mfa/totp/confirm", accountHandler.ConfirmTOTP)
+	account.POST("/api/account/mfa/recovery-codes", accountHandler.RegenerateRecoveryCodes)
+
+	// Access tokens
+	account.GET("/api/account/tokens", accessTokenHandler.List)
+	account.POST("/api/account/tokens", accessTokenHandler.Create)
+	account.DELETE("/api/account/tokens/:tokenId", accessTokenHandler.Revoke)
+
+	// Sessions
+	account.GET("/api/sessions", sessionHandler.List)
+	account.DELETE("/api/sessions", sessionHandler.RevokeOthers)
+	account.DELETE("/api/sessions/:sessionId", sessionHandler.Revoke)
+
+	// Projects
+	protected.GET("/api/projects", projectHandler.List)
+	protected.POST("/api/projects", projectHandler.Create)
+	protected.GET("/api/projects/:id", projectHandler.Get)
+	protected.PUT("/api/projects/:id", projectHandler.Update, admin)
+	protected.DELETE("/api/projects/:id", projectHandler.Delete, admin)
+	protected.POST("/api/projects/:id/transfer", memberHandler.TransferOwnership, admin)
+
+	// Project members
+	protected.GET("/api/projects/:id/members", memberHandler.List)
+	protected.POST("/api/projects/:id/members", memberHandler.Add, admin)
+	protected.PUT("/api/projects/:id/members/:userId", memberHandler.UpdateRole, admin)
+	protected.DELETE("/api/projects/:id/members/:userId", memberHandler.Remove, admin)
+
+	// Invitations
+	protected.GET("/api/projects/:id/invitations", invitationHandler.List)
+	protected.POST("/api/projects/:id/invitations", invitationHandler.Invite, admin)
+	protected.DELETE("/api/projects/:id/invitations/:invitationId", invitationHandler.Revoke, admin)
+	protected.POST("/api/invitations/:token/accept", invitationHandler.Accept, admin)
+
+	// Test cases
+	protected.GET("/api/projects/:id/testcases", testCaseHandler.List)
+	protected.POST("/api/projects/:id/testcases", testCaseHandler.Create)
+	protected.GET("/api/projects/:id/testcases/search", testCaseHandler.Search)
+	protected.POST("/api/projects/:id/testcases/generate", aiHandler.GenerateTestCase)
+	protected.POST("/api/projects/:id/testcases/generate/jobs", aiHandler.EnqueueGeneration)
+	protected.GET("/api/projects/:id/testcases/:caseId", testCaseHandler.Get)
+	protected.PUT("/api/projects/:id/testcases/:caseId", testCaseHandler.Update)
+	protected.DELETE("/api/projects/:id/testcases/:caseId", testCaseHandler.Delete)
+	protected.POST("/api/projects/:id/testcases/:caseId/steps", testCaseHandler.AddStep)
+	protected.PUT("/api/projects/:id/testcases/:caseId/steps/order", testCaseHandler.ReorderSteps)
+	protected.PUT("/api/projects/:id/testcases/:caseId/steps/:stepId", testCaseHandler.UpdateStep)
+	protected.DELETE("/api/projects/:id/testcases/:caseId/steps/:stepId", testCaseHandler.DeleteStep)
+	protected.POST("/api/projects/:id/testcases/:caseId/suggestions", aiHandler.SuggestField)
+	protected.POST("/api/projects/:id/testcases/:caseId/suggestions/:suggestionId/accept", aiHandler.AcceptSuggestion)
+
+	// Revisions
+	protected.GET("/api/projects/:id/testcases/:caseId/revisions", revisionHandler.List)
+	protected.GET("/api/projects/:id/testcases/:caseId/revisions/diff", revisionHandler.Diff)
+	protected.GET("/api/projects/:id/testcases/:caseId/revisions/:number", revisionHandler.Get)
+	protected.POST("/api/projects/:id/testcases/:caseId/revisions/:number/restore", revisionHandler.Restore)
+
+	// Attachments
+	protected.GET("/api/projects/:id/testcases/:caseId/attachments", attachmentHandler.List)
+	protected.POST("/api/projects/:id/testcases/:caseId/attachments", attachmentHandler.Upload)
+	protected.GET("/api/projects/:id/testcases/:caseId/attachments/:attachmentId", attachmentHandler.Download)
+	protected.DELETE("/api/projects/:id/testcases/:caseId/attachments/:attachmentId", attachmentHandler.Delete)
+
+	// Test runs
+	protected.GET("/api/projects/:id/runs", runHandler.List)
+	protected.POST("/api/projects/:id/runs", runHandler.Start)
+	protected.GET("/api/projects/:id/runs/:runId", runHandler.Get)
+	protected.PUT("/api/projects/:id/runs/:runId/cases/:runCaseId/results", runHandler.RecordResult)
+	protected.POST("/api/projects/:id/runs/:runId/close", runHandler.Close)
+
+	// Reports
+	protected.GET("/api/projects/:id/reports", reportHandler.Project)
+	protected.GET("/api/projects/:id/reports/runs", reportHandler.Runs)
+	protected.GET("/api/projects/:id/reports/flaky", reportHandler.Flaky)
+	protected.GET("/api/projects/:id/reports/coverage", reportHandler.Coverage)
+	protected.GET("/api/projects/:id/reports/never-executed", reportHandler.NeverExecuted)
+
+	// Tags
+	protected.GET("/api/projects/:id/tags", tagHandler.List)
+	protected.POST("/api/projects/:id/tags", tagHandler.Create)
+	protected.PUT("/api/projects/:id/tags/:tagId", tagHandler.Rename)
+	protected.POST("/api/projects/:id/tags/:tagId/merge", tagHandler.Merge)
+	protected.DELETE("/api/projects/:id/tags/:tagId", tagHandler.Delete)
+
+	// Exports
+	protected.GET("/api/projects/:id/export", exportHandler.Export)
+	protected.GET("/api/projects/:id/export.csv", exportHandler.CSV)
+	protected.POST("/api/projects/:id/export/jobs", exportHandler.Enqueue)
+
+	// Imports
+	protected.POST("/api/projects/:id/import", importHandler.Import)
+
+	// Audit log
+	protected.GET("/api/projects/:id/audit", auditHandler.ListProject, admin)
+	protected.GET("/api/audit", auditHandler.List, admin)
+
+	// Background jobs
+	protected.GET("/api/jobs/:jobId", jobHandler.Get)
+	protected.GET("/api/jobs/:jobId/download", jobHandler.Download)
 
 	return e
 }
@@ -66,3 +240,41 @@ func (s *Server) HelloWorldHandler(c echo.Context) error {
 func (s *Server) healthHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, s.db.Health())
 }
+
+// ipExtractor returns how the client address of a request is found, from
+// TRUSTED_PROXIES: a comma-separated list of the addresses or CIDR ranges of
+// the reverse proxies in front of the application. Without proxies the
+// address of the connection is used; forwarding headers sent by clients are
+// never trusted, since the address limits logins and is audited.
+func ipExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	var options []echo.TrustOption
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	if len(options) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// Only the listed proxies are trusted, not every private address
+	options = append(options, echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false))
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
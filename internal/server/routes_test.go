@@ -37,3 +37,51 @@ func TestHandler(t *testing.T) {
 		return
 	}
 }
+
+func TestIPExtractor(t *testing.T) {
+	request := func(remoteAddr, forwardedFor string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		}
+		req.Header.Set(echo.HeaderXRealIP, "198.51.100.99")
+		return req
+	}
+
+	direct, err := ipExtractor("")
+	if err != nil {
+		t.Fatalf("ipExtractor() error = %v", err)
+	}
+	if ip := direct(request("203.0.113.7:4000", "198.51.100.1")); ip != "203.0.113.7" {
+		t.Errorf("expected forwarding headers to be ignored without proxies, got %s", ip)
+	}
+
+	proxied, err := ipExtractor("10.0.0.0/8, 192.0.2.10")
+	if err != nil {
+		t.Fatalf("ipExtractor() error = %v", err)
+	}
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{"through a trusted proxy", "10.1.2.3:4000", "203.0.113.7", "203.0.113.7"},
+		{"through a trusted single address", "192.0.2.10:4000", "203.0.113.7", "203.0.113.7"},
+		{"spoofed header behind the proxy", "10.1.2.3:4000", "198.51.100.1, 203.0.113.7", "203.0.113.7"},
+		{"not through a proxy", "203.0.113.8:4000", "198.51.100.1", "203.0.113.8"},
+		{"untrusted private address", "172.16.0.1:4000", "198.51.100.1", "172.16.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ip := proxied(request(tt.remoteAddr, tt.forwardedFor)); ip != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, ip)
+			}
+		})
+	}
+
+	if _, err := ipExtractor("not-an-address"); err == nil {
+		t.Error("expected an invalid proxy to be rejected")
+	}
+}
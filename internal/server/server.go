@@ -10,15 +10,19 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 
 	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/jobs"
 )
 
 type Server struct {
 	port int
 
-	db database.Service
+	db    database.Service
+	queue *jobs.Queue
 }
 
-func NewServer() *http.Server {
+// NewServer returns the HTTP server and the pool of JOB_WORKERS workers, 4 by
+// default, that runs its background jobs. The caller starts both.
+func NewServer() (*http.Server, *jobs.Pool) {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
 	NewServer := &Server{
 		port: port,
@@ -35,5 +39,10 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
-	return server
+	workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if err != nil || workers < 1 {
+		workers = 4
+	}
+
+	return server, jobs.NewPool(NewServer.queue, workers)
 }
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/tokens"
+	"github.com/google/uuid"
+)
+
+const (
+	// accessTokenPrefix makes tokens easy to recognise, for instance by
+	// secret scanners
+	accessTokenPrefix        = "ta_"
+	defaultAccessTokenDays   = 30
+	maxAccessTokenDays       = 365
+	maxAccessTokenNameLength = 100
+	// accessTokenUseInterval limits how often LastUsedAt is written
+	accessTokenUseInterval = time.Minute
+)
+
+type AccessTokenService struct {
+	db    database.Service
+	audit *AuditService
+}
+
+func NewAccessTokenService(db database.Service, audit *AuditService) *AccessTokenService {
+	return &AccessTokenService{
+		db:    db,
+		audit: audit,
+	}
+}
+
+type CreateAccessTokenInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays defaults to 30 and is at most 365
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// NewAccessToken is a token that has just been created. Token is shown
+// once and cannot be retrieved afterwards.
+type NewAccessToken struct {
+	models.AccessToken
+	Token string `json:"token"`
+}
+
+func (s *AccessTokenService) ValidateAccessToken(input *CreateAccessTokenInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len([]rune(input.Name)) > maxAccessTokenNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters long", ErrInvalidInput, maxAccessTokenNameLength)
+	}
+
+	if len(input.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	var scopes []string
+	for _, scope := range input.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return fmt.Errorf("%w: scope must be one of %s", ErrInvalidInput, strings.Join(models.Scopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	input.Scopes = scopes
+
+	if input.ExpiresInDays == 0 {
+		input.ExpiresInDays = defaultAccessTokenDays
+	}
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxAccessTokenDays {
+		return fmt.Errorf("%w: expires_in_days must be between 1 and %d", ErrInvalidInput, maxAccessTokenDays)
+	}
+	return nil
+}
+
+func (s *AccessTokenService) CreateAccessToken(ctx context.Context, userID uuid.UUID, input CreateAccessTokenInput) (*NewAccessToken, error) {
+	if err := s.ValidateAccessToken(&input); err != nil {
+		return nil, err
+	}
+
+	token := accessTokenPrefix + tokens.Random(32)
+	accessToken := &NewAccessToken{
+		AccessToken: models.AccessToken{
+			AccessTokenID: uuid.New(),
+			UserID:        userID,
+			Name:          input.Name,
+			TokenHash:     tokens.Hash(token),
+			Scopes:        input.Scopes,
+			ExpiresAt:     time.Now().AddDate(0, 0, input.ExpiresInDays),
+		},
+		Token: token,
+	}
+	if err := s.db.Create(ctx, &accessToken.AccessToken); err != nil {
+		return nil, fmt.Errorf("failed to create access token: %v", err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditAccessTokenCreate,
+		TargetType: "access_token",
+		TargetID:   accessToken.AccessTokenID.String(),
+		Metadata:   map[string]interface{}{"name": input.Name, "scopes": input.Scopes},
+	})
+
+	return accessToken, nil
+}
+
+// ListAccessTokens returns the tokens of a user, newest first, including
+// expired ones.
+func (s *AccessTokenService) ListAccessTokens(ctx context.Context, userID uuid.UUID) ([]models.AccessToken, error) {
+	var accessTokens []models.AccessToken
+	err := s.db.DB().WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&accessTokens).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access tokens: %v", err)
+	}
+	return accessTokens, nil
+}
+
+func (s *AccessTokenService) RevokeAccessToken(ctx context.Context, userID, accessTokenID uuid.UUID) error {
+	result := s.db.DB().WithContext(ctx).
+		Where("access_token_id = ? AND user_id = ?", accessTokenID, userID).
+		Delete(&models.AccessToken{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke access token: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: access token", ErrNotFound)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditAccessTokenRevoke,
+		TargetType: "access_token",
+		TargetID:   accessTokenID.String(),
+	})
+	return nil
+}
+
+// Authenticate returns the unexpired access token matching token. It fails
+// with ErrNotFound for unknown, revoked and expired tokens.
+func (s *AccessTokenService) Authenticate(ctx context.Context, token string) (*models.AccessToken, error) {
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return nil, fmt.Errorf("%w: access token", ErrNotFound)
+	}
+
+	var accessToken models.AccessToken
+	if err := s.db.Read(ctx, &accessToken, "token_hash = ?", tokens.Hash(token)); err != nil {
+		return nil, fmt.Errorf("%w: access token", ErrNotFound)
+	}
+	now := time.Now()
+	if !now.Before(accessToken.ExpiresAt) {
+		return nil, fmt.Errorf("%w: access token", ErrNotFound)
+	}
+
+	if accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) > accessTokenUseInterval {
+		err := s.db.DB().WithContext(ctx).Model(&models.AccessToken{}).
+			Where("access_token_id = ?", accessToken.AccessTokenID).
+			Update("last_used_at", now).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to update access token: %v", err)
+		}
+		accessToken.LastUsedAt = &now
+	}
+	return &accessToken, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"TestAlchemy/internal/models"
+)
+
+func TestValidateAccessToken(t *testing.T) {
+	s := &AccessTokenService{}
+
+	tests := []struct {
+		name    string
+		input   CreateAccessTokenInput
+		wantErr bool
+	}{
+		{name: "valid", input: CreateAccessTokenInput{Name: "CI", Scopes: []string{models.ScopeRead}}},
+		{name: "missing name", input: CreateAccessTokenInput{Name: "  ", Scopes: []string{models.ScopeRead}}, wantErr: true},
+		{name: "no scopes", input: CreateAccessTokenInput{Name: "CI"}, wantErr: true},
+		{name: "unknown scope", input: CreateAccessTokenInput{Name: "CI", Scopes: []string{"owner"}}, wantErr: true},
+		{name: "too long", input: CreateAccessTokenInput{Name: "CI", Scopes: []string{models.ScopeRead}, ExpiresInDays: 400}, wantErr: true},
+		{name: "negative expiry", input: CreateAccessTokenInput{Name: "CI", Scopes: []string{models.ScopeRead}, ExpiresInDays: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ValidateAccessToken(&tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("expected ErrInvalidInput, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAccessTokenDefaults(t *testing.T) {
+	s := &AccessTokenService{}
+	input := CreateAccessTokenInput{Name: " CI ", Scopes: []string{models.ScopeRead, models.ScopeWrite, models.ScopeRead}}
+
+	if err := s.ValidateAccessToken(&input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if input.Name != "CI" {
+		t.Errorf("expected name to be trimmed, got %q", input.Name)
+	}
+	if want := []string{models.ScopeRead, models.ScopeWrite}; !reflect.DeepEqual(input.Scopes, want) {
+		t.Errorf("expected scopes %v, got %v", want, input.Scopes)
+	}
+	if input.ExpiresInDays != defaultAccessTokenDays {
+		t.Errorf("expected default expiry of %d days, got %d", defaultAccessTokenDays, input.ExpiresInDays)
+	}
+}
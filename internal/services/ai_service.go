@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"TestAlchemy/internal/ai"
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/diff"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	suggestionTTL        = time.Hour
+	maxDescriptionLength = 4000
+	// MaxImageSize is the largest image accepted for generation, in bytes
+	MaxImageSize = 5 << 20
+)
+
+// ImageTypes are the accepted image media types.
+var ImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// Fields that can be enhanced. Step fields also need the step ID.
+const (
+	FieldTitle          = "title"
+	FieldDescription    = "description"
+	FieldPreconditions  = "preconditions"
+	FieldAction         = "action"
+	FieldExpectedResult = "expected_result"
+)
+
+var enhanceableFields = []string{FieldTitle, FieldDescription, FieldPreconditions, FieldAction, FieldExpectedResult}
+
+type AIService struct {
+	keydb     database.KeyDBService
+	projects  *ProjectService
+	testCases *TestCaseService
+	provider  ai.Provider
+}
+
+func NewAIService(keydb database.KeyDBService, projects *ProjectService, testCases *TestCaseService, provider ai.Provider) *AIService {
+	return &AIService{
+		keydb:     keydb,
+		projects:  projects,
+		testCases: testCases,
+		provider:  provider,
+	}
+}
+
+// Suggestion is an AI rewrite of one field of a test case, waiting to be
+// accepted. Suggestions live in KeyDB and expire after an hour.
+type Suggestion struct {
+	SuggestionID uuid.UUID  `json:"suggestion_id"`
+	ProjectID    uuid.UUID  `json:"project_id"`
+	TestCaseID   uuid.UUID  `json:"test_case_id"`
+	StepID       *uuid.UUID `json:"step_id,omitempty"`
+	Field        string     `json:"field"`
+	Current      string     `json:"current"`
+	Suggested    string     `json:"suggested"`
+	Diff         []diff.Op  `json:"diff"`
+	UserID       uuid.UUID  `json:"user_id"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+}
+
+type SuggestionInput struct {
+	Field  string     `json:"field"`
+	StepID *uuid.UUID `json:"step_id"`
+}
+
+// GenerateTestCase drafts a test case for the project from an optional
+// image and a description. The draft is not saved.
+func (s *AIService) GenerateTestCase(ctx context.Context, userID, projectID uuid.UUID, image ai.Image, description string) (*ai.TestCaseDraft, error) {
+	description, err := s.checkGeneration(ctx, userID, projectID, image, description)
+	if err != nil {
+		return nil, err
+	}
+
+	draft, err := s.provider.GenerateTestCase(ctx, image, description)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to generate test case: %v", ErrUnavailable, err)
+	}
+	normalizeDraft(&draft)
+	return &draft, nil
+}
+
+// checkGeneration validates a generation request and checks that the user
+// can edit the project. It returns the trimmed description.
+func (s *AIService) checkGeneration(ctx context.Context, userID, projectID uuid.UUID, image ai.Image, description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if description == "" && len(image.Data) == 0 {
+		return "", fmt.Errorf("%w: a description or an image is required", ErrInvalidInput)
+	}
+	if len(description) > maxDescriptionLength {
+		return "", fmt.Errorf("%w: description must be at most %d characters long", ErrInvalidInput, maxDescriptionLength)
+	}
+	if len(image.Data) > 0 {
+		if len(image.Data) > MaxImageSize {
+			return "", fmt.Errorf("%w: image must be at most %d MB", ErrInvalidInput, MaxImageSize>>20)
+		}
+		if !slices.Contains(ImageTypes, image.MediaType) {
+			return "", fmt.Errorf("%w: image must be one of %s", ErrInvalidInput, strings.Join(ImageTypes, ", "))
+		}
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return "", err
+	}
+	return description, nil
+}
+
+// normalizeDraft makes a generated test case pass ValidateTestCase: values
+// out of range are reset and incomplete steps and invalid tags dropped, so a
+// sloppy model answer still gives a usable draft.
+func normalizeDraft(draft *ai.TestCaseDraft) {
+	draft.Title = truncateBytes(strings.TrimSpace(draft.Title), 255)
+	draft.UserLevel = truncateBytes(strings.TrimSpace(draft.UserLevel), 64)
+	draft.Priority = strings.ToLower(strings.TrimSpace(draft.Priority))
+	if !slices.Contains(models.Priorities, draft.Priority) {
+		draft.Priority = models.PriorityMedium
+	}
+
+	steps := []ai.StepDraft{}
+	for _, step := range draft.Steps {
+		step.Action = strings.TrimSpace(step.Action)
+		step.ExpectedResult = strings.TrimSpace(step.ExpectedResult)
+		if step.Action != "" {
+			steps = append(steps, step)
+		}
+	}
+	draft.Steps = steps
+
+	tags := []string{}
+	for _, tag := range uniqueFold(draft.Tags) {
+		if name, err := normalizeTagName(tag); err == nil {
+			tags = append(tags, name)
+		}
+	}
+	draft.Tags = tags
+}
+
+// truncateBytes shortens value to at most length bytes without splitting a
+// character.
+func truncateBytes(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	for length > 0 && !utf8.RuneStart(value[length]) {
+		length--
+	}
+	return value[:length]
+}
+
+// SuggestField asks the AI provider for a rewrite of one field of a test
+// case. The suggestion is returned with a word diff against the current
+// value and is only applied by AcceptSuggestion.
+func (s *AIService) SuggestField(ctx context.Context, userID, projectID, testCaseID uuid.UUID, input SuggestionInput) (*Suggestion, error) {
+	if !slices.Contains(enhanceableFields, input.Field) {
+		return nil, fmt.Errorf("%w: field must be one of %s", ErrInvalidInput, strings.Join(enhanceableFields, ", "))
+	}
+	isStepField := input.Field == FieldAction || input.Field == FieldExpectedResult
+	if isStepField != (input.StepID != nil) {
+		return nil, fmt.Errorf("%w: step_id is required for step fields only", ErrInvalidInput)
+	}
+
+	testCase, err := s.testCases.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := fieldValue(testCase, input.Field, input.StepID)
+	if err != nil {
+		return nil, err
+	}
+
+	label := strings.ReplaceAll(input.Field, "_", " ")
+	if isStepField {
+		label = "step " + label
+	}
+	suggested, err := s.provider.EnhanceField(ctx, ai.FieldRequest{
+		Field:    label,
+		Value:    current,
+		TestCase: testCaseDraft(testCase),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to enhance %s: %v", ErrUnavailable, input.Field, err)
+	}
+	suggested = strings.TrimSpace(suggested)
+	if input.Field == FieldTitle {
+		suggested = truncateBytes(suggested, 255)
+	}
+
+	suggestion := &Suggestion{
+		SuggestionID: uuid.New(),
+		ProjectID:    projectID,
+		TestCaseID:   testCaseID,
+		StepID:       input.StepID,
+		Field:        input.Field,
+		Current:      current,
+		Suggested:    suggested,
+		Diff:         diff.Words(current, suggested),
+		UserID:       userID,
+		ExpiresAt:    time.Now().Add(suggestionTTL),
+	}
+	data, err := json.Marshal(suggestion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal suggestion: %v", err)
+	}
+	if err := s.keydb.Set(ctx, suggestionKey(suggestion.SuggestionID), data, suggestionTTL); err != nil {
+		return nil, fmt.Errorf("failed to store suggestion: %v", err)
+	}
+
+	return suggestion, nil
+}
+
+// AcceptSuggestion applies a suggestion made to the same user and returns
+// the updated test case. It fails with ErrConflict when the field has
+// changed since the suggestion was made. The comparison is made with the
+// test case locked, so a concurrent edit cannot slip in before the update.
+func (s *AIService) AcceptSuggestion(ctx context.Context, userID, projectID, testCaseID, suggestionID uuid.UUID) (*models.TestCase, error) {
+	data, err := s.keydb.Get(ctx, suggestionKey(suggestionID))
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: suggestion", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggestion: %v", err)
+	}
+	var suggestion Suggestion
+	if err := json.Unmarshal([]byte(data), &suggestion); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal suggestion: %v", err)
+	}
+	if suggestion.UserID != userID || suggestion.ProjectID != projectID || suggestion.TestCaseID != testCaseID {
+		return nil, fmt.Errorf("%w: suggestion", ErrNotFound)
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	err = s.testCases.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var testCase models.TestCase
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Steps", orderSteps).
+			Where("test_case_id = ? AND project_id = ?", testCaseID, projectID).
+			First(&testCase).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: test case", ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		current, err := fieldValue(&testCase, suggestion.Field, suggestion.StepID)
+		if err != nil {
+			return err
+		}
+		if current != suggestion.Current {
+			return fmt.Errorf("%w: the %s has changed since the suggestion was made", ErrConflict, suggestion.Field)
+		}
+		return reviseTestCase(tx, testCaseID, userID, nil, func() error {
+			return s.applySuggestion(tx, &testCase, &suggestion)
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to accept suggestion: %v", err)
+	}
+
+	if err := s.keydb.Delete(ctx, suggestionKey(suggestionID)); err != nil {
+		log.Printf("failed to delete accepted suggestion %s: %v", suggestionID, err)
+	}
+	return s.testCases.getTestCase(ctx, projectID, testCaseID)
+}
+
+// applySuggestion writes the suggested value to its field, after checking
+// that the field stays valid. The field names match the column names.
+func (s *AIService) applySuggestion(tx *gorm.DB, testCase *models.TestCase, suggestion *Suggestion) error {
+	if suggestion.StepID != nil {
+		step, err := findStep(testCase, *suggestion.StepID)
+		if err != nil {
+			return err
+		}
+		input := TestStepInput{Action: step.Action, ExpectedResult: step.ExpectedResult}
+		if suggestion.Field == FieldAction {
+			input.Action = suggestion.Suggested
+		}
+		if err := s.testCases.ValidateStep(input); err != nil {
+			return err
+		}
+		err = tx.Model(&models.TestStep{}).
+			Where("test_step_id = ?", step.TestStepID).
+			Update(suggestion.Field, suggestion.Suggested).Error
+		if err != nil {
+			return err
+		}
+		return touchTestCase(tx, testCase.TestCaseID)
+	}
+
+	if suggestion.Field == FieldTitle {
+		input := TestCaseInput{
+			Title:     suggestion.Suggested,
+			UserLevel: testCase.UserLevel,
+			Priority:  testCase.Priority,
+			Status:    testCase.Status,
+		}
+		if err := s.testCases.ValidateTestCase(&input); err != nil {
+			return err
+		}
+	}
+	return tx.Model(testCase).Update(suggestion.Field, suggestion.Suggested).Error
+}
+
+// fieldValue returns the current value of an enhanceable field.
+func fieldValue(testCase *models.TestCase, field string, stepID *uuid.UUID) (string, error) {
+	switch field {
+	case FieldTitle:
+		return testCase.Title, nil
+	case FieldDescription:
+		return testCase.Description, nil
+	case FieldPreconditions:
+		return testCase.Preconditions, nil
+	}
+
+	step, err := findStep(testCase, *stepID)
+	if err != nil {
+		return "", err
+	}
+	if field == FieldAction {
+		return step.Action, nil
+	}
+	return step.ExpectedResult, nil
+}
+
+// testCaseDraft describes a test case to the AI provider.
+func testCaseDraft(testCase *models.TestCase) ai.TestCaseDraft {
+	draft := ai.TestCaseDraft{
+		Title:         testCase.Title,
+		Description:   testCase.Description,
+		Preconditions: testCase.Preconditions,
+		UserLevel:     testCase.UserLevel,
+		Priority:      testCase.Priority,
+		Steps:         []ai.StepDraft{},
+		Tags:          []string{},
+	}
+	for _, step := range testCase.Steps {
+		draft.Steps = append(draft.Steps, ai.StepDraft{Action: step.Action, ExpectedResult: step.ExpectedResult})
+	}
+	for _, tag := range testCase.Tags {
+		draft.Tags = append(draft.Tags, tag.Name)
+	}
+	return draft
+}
+
+func suggestionKey(suggestionID uuid.UUID) string {
+	return fmt.Sprintf("suggestion:%s", suggestionID)
+}
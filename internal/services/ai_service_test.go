@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"TestAlchemy/internal/ai"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestNormalizeDraft(t *testing.T) {
+	draft := ai.TestCaseDraft{
+		Title:    "  " + strings.Repeat("é", 200) + "  ",
+		Priority: "Urgent",
+		Steps: []ai.StepDraft{
+			{Action: " Open the page ", ExpectedResult: " Shown "},
+			{Action: "  ", ExpectedResult: "Nothing to do"},
+		},
+		Tags: []string{"smoke", "Smoke", "a,b", ""},
+	}
+
+	normalizeDraft(&draft)
+
+	if len(draft.Title) > 255 || !strings.HasPrefix(draft.Title, "é") {
+		t.Errorf("expected the title to be trimmed to 255 bytes, got %d bytes", len(draft.Title))
+	}
+	if draft.Priority != models.PriorityMedium {
+		t.Errorf("expected an unknown priority to be reset, got %q", draft.Priority)
+	}
+	wantSteps := []ai.StepDraft{{Action: "Open the page", ExpectedResult: "Shown"}}
+	if !reflect.DeepEqual(draft.Steps, wantSteps) {
+		t.Errorf("expected steps %+v, got %+v", wantSteps, draft.Steps)
+	}
+	if !reflect.DeepEqual(draft.Tags, []string{"smoke"}) {
+		t.Errorf("unexpected tags %v", draft.Tags)
+	}
+
+	input := TestCaseInput{Title: draft.Title, Priority: draft.Priority}
+	if err := (&TestCaseService{}).ValidateTestCase(&input); err != nil {
+		t.Errorf("expected the normalized draft to be valid, got %v", err)
+	}
+}
+
+func TestAcceptSuggestion(t *testing.T) {
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	testCases := NewTestCaseService(db, projects)
+	revisions := NewRevisionService(db, testCases)
+	s := NewAIService(keydb, projects, testCases, ai.NewFake())
+	owner := createUser(t, db)
+	project := createProject(t, db, owner, nil)
+	testCase, err := testCases.CreateTestCase(ctx, owner.UserID, project.ProjectID, TestCaseInput{
+		Title:       "log in",
+		Description: "with a  password",
+		Steps:       []TestStepInput{{Action: "open the login page"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTestCase() error = %v", err)
+	}
+
+	suggest := func(field string, stepID *uuid.UUID) uuid.UUID {
+		t.Helper()
+		suggestion, err := s.SuggestField(ctx, owner.UserID, project.ProjectID, testCase.TestCaseID, SuggestionInput{Field: field, StepID: stepID})
+		if err != nil {
+			t.Fatalf("SuggestField() error = %v", err)
+		}
+		return suggestion.SuggestionID
+	}
+	accept := func(suggestionID uuid.UUID) (*models.TestCase, error) {
+		return s.AcceptSuggestion(ctx, owner.UserID, project.ProjectID, testCase.TestCaseID, suggestionID)
+	}
+
+	updated, err := accept(suggest(FieldTitle, nil))
+	if err != nil {
+		t.Fatalf("AcceptSuggestion() error = %v", err)
+	}
+	if updated.Title != "Log in" || updated.Description != "with a  password" {
+		t.Errorf("expected only the title to change, got %+v", updated)
+	}
+	stepID := testCase.Steps[0].TestStepID
+	updated, err = accept(suggest(FieldAction, &stepID))
+	if err != nil {
+		t.Fatalf("AcceptSuggestion() error = %v", err)
+	}
+	if updated.Steps[0].Action != "Open the login page." {
+		t.Errorf("expected the step to change, got %q", updated.Steps[0].Action)
+	}
+	page, err := revisions.ListRevisions(ctx, owner.UserID, project.ProjectID, testCase.TestCaseID, "", 10)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(page.Items) != 3 {
+		t.Errorf("expected a revision per accepted suggestion, got %d revisions", len(page.Items))
+	}
+
+	// A suggestion for a value edited since is refused
+	stale := suggest(FieldDescription, nil)
+	input := TestCaseInput{Title: updated.Title, Description: "edited meanwhile"}
+	if _, err := testCases.UpdateTestCase(ctx, owner.UserID, project.ProjectID, testCase.TestCaseID, input); err != nil {
+		t.Fatalf("UpdateTestCase() error = %v", err)
+	}
+	if _, err := accept(stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a stale suggestion to be refused, got %v", err)
+	}
+
+	// Of two suggestions for the same value accepted at once, one wins
+	first, second := suggest(FieldDescription, nil), suggest(FieldDescription, nil)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, suggestionID := range []uuid.UUID{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = accept(suggestionID)
+		}()
+	}
+	wg.Wait()
+	if (errs[0] == nil) == (errs[1] == nil) || !errors.Is(errors.Join(errs...), ErrConflict) {
+		t.Errorf("expected one suggestion to be applied and the other refused, got %v", errs)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"TestAlchemy/internal/blobstore"
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxAttachmentSize is the largest accepted upload, in bytes.
+const MaxAttachmentSize = 10 << 20
+
+// AttachmentTypes are the accepted attachment media types. The type is
+// detected from the content, not taken from the client.
+var AttachmentTypes = append(slices.Clone(ImageTypes), "application/pdf", "text/plain")
+
+type AttachmentService struct {
+	db        database.Service
+	testCases *TestCaseService
+	store     blobstore.BlobStore
+}
+
+func NewAttachmentService(db database.Service, testCases *TestCaseService, store blobstore.BlobStore) *AttachmentService {
+	return &AttachmentService{
+		db:        db,
+		testCases: testCases,
+		store:     store,
+	}
+}
+
+type UploadInput struct {
+	FileName string
+	Size     int64
+	Body     io.Reader
+	// StepID attaches the file to a step of the test case when set
+	StepID *uuid.UUID
+}
+
+// Upload stores a file and attaches it to a test case or one of its steps.
+// Editors and owners can upload.
+func (s *AttachmentService) Upload(ctx context.Context, userID, projectID, testCaseID uuid.UUID, input UploadInput) (*models.Attachment, error) {
+	if input.Size <= 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidInput)
+	}
+	if input.Size > MaxAttachmentSize {
+		return nil, fmt.Errorf("%w: the file must be at most %d MB", ErrInvalidInput, MaxAttachmentSize>>20)
+	}
+	fileName := cleanFileName(input.FileName)
+
+	testCase, err := s.testCases.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+	if input.StepID != nil {
+		if _, err := findStep(testCase, *input.StepID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sniff the type from the start of the file
+	head := make([]byte, 512)
+	n, err := io.ReadFull(input.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read upload: %v", err)
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !slices.Contains(AttachmentTypes, contentType) {
+		return nil, fmt.Errorf("%w: file type %s is not allowed, use one of %s", ErrInvalidInput, contentType, strings.Join(AttachmentTypes, ", "))
+	}
+
+	attachment := &models.Attachment{
+		AttachmentID: uuid.New(),
+		ProjectID:    projectID,
+		TestCaseID:   testCaseID,
+		TestStepID:   input.StepID,
+		UploaderID:   userID,
+		FileName:     fileName,
+		ContentType:  contentType,
+		Size:         input.Size,
+	}
+	attachment.StorageKey = fmt.Sprintf("projects/%s/attachments/%s", projectID, attachment.AttachmentID)
+
+	body := io.MultiReader(bytes.NewReader(head), input.Body)
+	if err := s.store.Put(ctx, attachment.StorageKey, body, input.Size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %v", err)
+	}
+	if err := s.db.Create(ctx, attachment); err != nil {
+		s.deleteBlob(ctx, attachment.StorageKey)
+		return nil, fmt.Errorf("failed to create attachment: %v", err)
+	}
+
+	return attachment, nil
+}
+
+// ListAttachments returns the attachments of a test case, oldest first.
+func (s *AttachmentService) ListAttachments(ctx context.Context, userID, projectID, testCaseID uuid.UUID) ([]models.Attachment, error) {
+	if _, err := s.testCases.GetTestCase(ctx, userID, projectID, testCaseID); err != nil {
+		return nil, err
+	}
+
+	attachments := []models.Attachment{}
+	err := s.db.DB().WithContext(ctx).
+		Where("test_case_id = ?", testCaseID).
+		Order("created_at").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %v", err)
+	}
+	return attachments, nil
+}
+
+// OpenAttachment returns an attachment and its content, which the caller
+// must close. Any project member can download attachments.
+func (s *AttachmentService) OpenAttachment(ctx context.Context, userID, projectID, testCaseID, attachmentID uuid.UUID) (*models.Attachment, io.ReadCloser, error) {
+	if _, err := s.testCases.GetTestCase(ctx, userID, projectID, testCaseID); err != nil {
+		return nil, nil, err
+	}
+	attachment, err := s.getAttachment(ctx, testCaseID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: attachment content", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment: %v", err)
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment removes an attachment and its content. Editors and
+// owners can delete attachments.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, userID, projectID, testCaseID, attachmentID uuid.UUID) error {
+	if _, err := s.testCases.editTestCase(ctx, userID, projectID, testCaseID); err != nil {
+		return err
+	}
+	attachment, err := s.getAttachment(ctx, testCaseID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.DB().WithContext(ctx).Delete(attachment).Error; err != nil {
+		return fmt.Errorf("failed to delete attachment: %v", err)
+	}
+	s.deleteBlob(ctx, attachment.StorageKey)
+	return nil
+}
+
+func (s *AttachmentService) getAttachment(ctx context.Context, testCaseID, attachmentID uuid.UUID) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := s.db.DB().WithContext(ctx).
+		Where("attachment_id = ? AND test_case_id = ?", attachmentID, testCaseID).
+		First(&attachment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: attachment", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get attachment: %v", err)
+	}
+	return &attachment, nil
+}
+
+// deleteBlob removes stored content that is no longer referenced. Failures
+// only leave an orphaned blob, so they are logged.
+func (s *AttachmentService) deleteBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete blob %s: %v", key, err)
+	}
+}
+
+// cleanFileName keeps the base name of an uploaded file, without control
+// characters and at most 255 bytes long.
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	return truncateBytes(name, 255)
+}
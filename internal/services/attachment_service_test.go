@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestCleanFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"screenshot.png", "screenshot.png"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\qa\login page.png`, "login page.png"},
+		{"bad\x00name\n.txt", "badname.txt"},
+		{"", "attachment"},
+		{"/", "attachment"},
+	}
+
+	for _, tt := range tests {
+		if got := cleanFileName(tt.name); got != tt.want {
+			t.Errorf("cleanFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewAuditService(db database.Service, projects *ProjectService) *AuditService {
+	return &AuditService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+// Client describes where a request comes from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+type clientKey struct{}
+
+// WithClient returns a context carrying the client of the request, which
+// audit events recorded with the context are attributed to.
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFromContext returns the client stored by WithClient, or an empty
+// client.
+func ClientFromContext(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
+
+// AuditEntry describes an event to record. Nil IDs are left empty.
+type AuditEntry struct {
+	ActorID    uuid.UUID
+	ProjectID  uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Metadata   map[string]interface{}
+}
+
+// AuditFilter selects audit events. Zero fields match every event.
+type AuditFilter struct {
+	Actions    []string
+	ActorID    uuid.UUID
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	Cursor     string
+	Limit      int
+}
+
+type AuditPage struct {
+	Items []models.AuditEvent `json:"items"`
+	database.Page
+}
+
+// Record stores an audit event, attributed to the client of the context.
+// The action it records has already happened, so failures are logged
+// rather than returned.
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	client := ClientFromContext(ctx)
+	event := &models.AuditEvent{
+		AuditEventID: uuid.New(),
+		ProjectID:    optionalID(entry.ProjectID),
+		ActorID:      optionalID(entry.ActorID),
+		IP:           client.IP,
+		UserAgent:    truncateBytes(client.UserAgent, 512),
+		Action:       entry.Action,
+		TargetType:   entry.TargetType,
+		TargetID:     entry.TargetID,
+	}
+	if len(entry.Metadata) > 0 {
+		metadata, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			log.Printf("failed to encode metadata of audit event %s: %v", entry.Action, err)
+		} else {
+			event.Metadata = metadata
+		}
+	}
+
+	if err := s.db.Create(ctx, event); err != nil {
+		log.Printf("failed to record audit event %s: %v", entry.Action, err)
+	}
+}
+
+// ListProjectEvents returns the audit events of a project, newest first.
+// Only owners may read the audit log of a project.
+func (s *AuditService) ListProjectEvents(ctx context.Context, userID, projectID uuid.UUID, filter AuditFilter) (*AuditPage, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.listEvents(ctx, filter, func(db *gorm.DB) *gorm.DB {
+		return db.Where("project_id = ?", projectID)
+	})
+}
+
+// ListEvents returns the audit events of the whole instance, newest first.
+// Only admins may read it.
+func (s *AuditService) ListEvents(ctx context.Context, userID uuid.UUID, filter AuditFilter) (*AuditPage, error) {
+	var user models.User
+	err := s.db.Read(ctx, &user, "user_id = ?", userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if err != nil || !user.IsAdmin {
+		return nil, fmt.Errorf("%w: admin access is required", ErrForbidden)
+	}
+	return s.listEvents(ctx, filter)
+}
+
+func (s *AuditService) listEvents(ctx context.Context, filter AuditFilter, scopes ...func(*gorm.DB) *gorm.DB) (*AuditPage, error) {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return nil, fmt.Errorf("%w: until must not be before since", ErrInvalidInput)
+	}
+
+	result := &AuditPage{Items: []models.AuditEvent{}}
+	page, err := s.db.List(ctx, &result.Items, database.ListQuery{
+		Scopes:    append(scopes, auditFilter(filter)),
+		SortField: "created_at",
+		SortDesc:  true,
+		Cursor:    filter.Cursor,
+		Limit:     filter.Limit,
+	})
+	if errors.Is(err, database.ErrInvalidQuery) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %v", err)
+	}
+	result.Page = *page
+	return result, nil
+}
+
+func auditFilter(filter AuditFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filter.Actions) > 0 {
+			db = db.Where("action IN ?", filter.Actions)
+		}
+		if filter.ActorID != uuid.Nil {
+			db = db.Where("actor_id = ?", filter.ActorID)
+		}
+		if filter.TargetType != "" {
+			db = db.Where("target_type = ?", filter.TargetType)
+		}
+		if filter.TargetID != "" {
+			db = db.Where("target_id = ?", filter.TargetID)
+		}
+		if !filter.Since.IsZero() {
+			db = db.Where("created_at >= ?", filter.Since)
+		}
+		if !filter.Until.IsZero() {
+			db = db.Where("created_at < ?", filter.Until)
+		}
+		return db
+	}
+}
+
+func optionalID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
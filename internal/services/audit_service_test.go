@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestClientFromContext(t *testing.T) {
+	if client := ClientFromContext(context.Background()); client != (Client{}) {
+		t.Errorf("expected an empty client, got %+v", client)
+	}
+
+	want := Client{IP: "203.0.113.7", UserAgent: "curl/8.0"}
+	if client := ClientFromContext(WithClient(context.Background(), want)); client != want {
+		t.Errorf("expected %+v, got %+v", want, client)
+	}
+}
+
+func TestOptionalID(t *testing.T) {
+	if id := optionalID(uuid.Nil); id != nil {
+		t.Errorf("expected nil for the nil UUID, got %v", *id)
+	}
+	id := uuid.New()
+	if got := optionalID(id); got == nil || *got != id {
+		t.Errorf("expected %v, got %v", id, got)
+	}
+}
+
+func TestAuditFilter(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:password@tcp(127.0.0.1:1)/database", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := func(filter AuditFilter) *gorm.Statement {
+		var events []models.AuditEvent
+		return db.Scopes(auditFilter(filter)).Find(&events).Statement
+	}
+
+	if stmt := query(AuditFilter{}); strings.Contains(stmt.SQL.String(), "WHERE") {
+		t.Errorf("expected an empty filter to match every event, got %s", stmt.SQL.String())
+	}
+
+	actorID := uuid.New()
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	stmt := query(AuditFilter{
+		Actions:    []string{models.AuditUserLogin, models.AuditUserLoginFailed},
+		ActorID:    actorID,
+		TargetType: "user",
+		TargetID:   "target",
+		Since:      since,
+		Until:      until,
+	})
+	sql := stmt.SQL.String()
+	for _, condition := range []string{"action IN (?,?)", "actor_id = ?", "target_type = ?", "target_id = ?", "created_at >= ?", "created_at < ?"} {
+		if !strings.Contains(sql, condition) {
+			t.Errorf("expected %q in %s", condition, sql)
+		}
+	}
+	want := []interface{}{models.AuditUserLogin, models.AuditUserLoginFailed, actorID, "user", "target", since, until}
+	if !reflect.DeepEqual(stmt.Vars, want) {
+		t.Errorf("expected vars %v, got %v", want, stmt.Vars)
+	}
+}
+
+func TestListAuditEventsAccess(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	members := NewMemberService(db, projects)
+	audit := NewAuditService(db, projects)
+
+	owner := createUser(t, db)
+	editor := createUser(t, db)
+	outsider := createUser(t, db)
+	admin := createUser(t, db)
+	admin.IsAdmin = true
+	if err := db.Update(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := projects.CreateProject(ctx, owner.UserID, ProjectInput{Name: "Audited"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	if _, err := members.AddMember(ctx, owner.UserID, project.ProjectID, AddMemberInput{Email: editor.Email, Role: models.RoleEditor}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+	audit.Record(ctx, AuditEntry{ActorID: owner.UserID, ProjectID: project.ProjectID, Action: models.AuditProjectUpdate, TargetType: "project", TargetID: project.ProjectID.String()})
+	audit.Record(ctx, AuditEntry{ActorID: editor.UserID, ProjectID: project.ProjectID, Action: models.AuditTestCaseDelete, TargetType: "test_case", TargetID: uuid.NewString()})
+	audit.Record(ctx, AuditEntry{ActorID: outsider.UserID, Action: models.AuditUserLogin, TargetType: "user", TargetID: outsider.UserID.String()})
+
+	page, err := audit.ListProjectEvents(ctx, owner.UserID, project.ProjectID, AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListProjectEvents() error = %v", err)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("expected the 2 events of the project, got %d", len(page.Items))
+	}
+	for _, event := range page.Items {
+		if event.ProjectID == nil || *event.ProjectID != project.ProjectID {
+			t.Errorf("expected only events of the project, got %+v", event)
+		}
+	}
+	page, err = audit.ListProjectEvents(ctx, owner.UserID, project.ProjectID, AuditFilter{ActorID: editor.UserID})
+	if err != nil {
+		t.Fatalf("ListProjectEvents() error = %v", err)
+	}
+	if len(page.Items) != 1 || *page.Items[0].ActorID != editor.UserID {
+		t.Errorf("expected only the event of the editor, got %+v", page.Items)
+	}
+
+	if _, err := audit.ListProjectEvents(ctx, editor.UserID, project.ProjectID, AuditFilter{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected editors to be refused, got %v", err)
+	}
+	if _, err := audit.ListProjectEvents(ctx, outsider.UserID, project.ProjectID, AuditFilter{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the project to be hidden from non-members, got %v", err)
+	}
+
+	page, err = audit.ListEvents(ctx, admin.UserID, AuditFilter{ActorID: outsider.UserID})
+	if err != nil {
+		t.Fatalf("ListEvents() error = %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Action != models.AuditUserLogin {
+		t.Errorf("expected the login of the outsider, got %+v", page.Items)
+	}
+	if _, err := audit.ListEvents(ctx, owner.UserID, AuditFilter{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected project owners who are not admins to be refused, got %v", err)
+	}
+	if _, err := audit.ListEvents(ctx, uuid.New(), AuditFilter{}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected unknown users to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"log"
+	"os"
+
+	"TestAlchemy/internal/tokens"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+var (
+	appBaseURL    = getEnvOrDefault("APP_BASE_URL", "http://localhost:8080")
+	signingSecret = []byte(getEnvOrDefault("TOKEN_SIGNING_SECRET", ""))
+	// Members of oidcAdminGroup, as listed in the oidcGroupsClaim claim of
+	// the ID token, are instance admins. Admin rights are left alone when
+	// no group is set.
+	oidcAdminGroup  = getEnvOrDefault("OIDC_ADMIN_GROUP", "")
+	oidcGroupsClaim = getEnvOrDefault("OIDC_GROUPS_CLAIM", "groups")
+)
+
+func init() {
+	if len(signingSecret) == 0 {
+		log.Println("TOKEN_SIGNING_SECRET is not set, using a random secret; signed links will not survive a restart")
+		signingSecret = []byte(tokens.Random(32))
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// Sentinel errors returned by the services. Handlers map them to HTTP
+// status codes, so wrap them with fmt.Errorf("%w: ...") to add detail.
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrNotFound     = errors.New("not found")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+	// ErrRateLimited reports that a request was refused for being repeated too often
+	ErrRateLimited = errors.New("too many requests")
+	// ErrUnavailable reports a failure of an external service, such as the AI provider
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// LockedError is returned while an account or client address is locked out
+// after too many failed login attempts. It matches ErrRateLimited.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
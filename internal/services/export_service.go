@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/export"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const exportBatchSize = 200
+
+type ExportService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewExportService(db database.Service, projects *ProjectService) *ExportService {
+	return &ExportService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+// Authorize checks that the user may export the project and returns it.
+func (s *ExportService) Authorize(ctx context.Context, userID, projectID uuid.UUID) (*models.Project, error) {
+	return s.projects.Authorize(ctx, userID, projectID, models.RoleViewer)
+}
+
+// Export writes every test case of the project to the exporter, oldest
+// first, loading them in batches. Callers must check access with Authorize
+// first. The exporter is closed when all test cases have been written.
+func (s *ExportService) Export(ctx context.Context, projectID uuid.UUID, exporter export.Exporter) error {
+	authors := map[uuid.UUID]string{}
+	cursor := ""
+	for {
+		var batch []models.TestCase
+		page, err := s.db.List(ctx, &batch, database.ListQuery{
+			Scopes: []func(*gorm.DB) *gorm.DB{func(db *gorm.DB) *gorm.DB {
+				return db.Preload("Steps", orderSteps).Preload("Tags", orderTags).
+					Where("project_id = ?", projectID)
+			}},
+			SortField: "created_at",
+			Cursor:    cursor,
+			Limit:     exportBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to load test cases: %v", err)
+		}
+
+		if err := s.loadAuthors(ctx, batch, authors); err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := exporter.Add(&batch[i], authors[batch[i].AuthorID]); err != nil {
+				return fmt.Errorf("failed to export test case: %v", err)
+			}
+		}
+
+		if !page.HasMore {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if err := exporter.Close(); err != nil {
+		return fmt.Errorf("failed to finish export: %v", err)
+	}
+	return nil
+}
+
+// loadAuthors adds the emails of the batch's authors that are not in the
+// authors map yet.
+func (s *ExportService) loadAuthors(ctx context.Context, batch []models.TestCase, authors map[uuid.UUID]string) error {
+	var missing []uuid.UUID
+	for _, testCase := range batch {
+		if _, ok := authors[testCase.AuthorID]; !ok {
+			authors[testCase.AuthorID] = ""
+			missing = append(missing, testCase.AuthorID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	if err := s.db.DB().WithContext(ctx).Where("user_id IN ?", missing).Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to load authors: %v", err)
+	}
+	for _, user := range users {
+		authors[user.UserID] = user.Email
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/session"
+	"TestAlchemy/internal/testutil"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// testPassword is the password of the users made by createUser.
+const testPassword = "Secret-123"
+
+var testDBOnce struct {
+	sync.Once
+	db  database.Service
+	err error
+}
+
+// startDB connects to the MySQL database shared by the tests of the
+// package. Tests must use their own users and projects.
+func startDB(t *testing.T) database.Service {
+	t.Helper()
+	dsn := testutil.MySQL(t)
+	testDBOnce.Do(func() {
+		testDBOnce.db, testDBOnce.err = database.Open(dsn)
+	})
+	if testDBOnce.err != nil {
+		t.Fatalf("failed to connect to MySQL: %v", testDBOnce.err)
+	}
+	return testDBOnce.db
+}
+
+// startKeyDB starts an empty KeyDB for the test.
+func startKeyDB(t *testing.T) database.KeyDBService {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: testutil.KeyDB(t)})
+	t.Cleanup(func() { client.Close() })
+	return database.NewKeyDBClient(client)
+}
+
+// newSessionStore returns a session store kept in the given KeyDB.
+func newSessionStore(keydb database.KeyDBService) *session.Store {
+	return session.NewStoreWithClient(keydb.Client(), time.Hour, 24*time.Hour)
+}
+
+// createUser creates a verified user with a unique address and the
+// password testPassword.
+func createUser(t *testing.T, db database.Service) *models.User {
+	t.Helper()
+	user := &models.User{
+		UserID:        uuid.New(),
+		Email:         uuid.NewString() + "@example.com",
+		EmailVerified: true,
+	}
+	if err := user.HashPassword(testPassword); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// createProject creates a project owned by owner, with the given members
+// added by role.
+func createProject(t *testing.T, db database.Service, owner *models.User, members map[*models.User]string) *models.Project {
+	t.Helper()
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	project, err := projects.CreateProject(ctx, owner.UserID, ProjectInput{Name: "Project " + uuid.NewString()[:8]})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	for user, role := range members {
+		input := AddMemberInput{Email: user.Email, Role: role}
+		if _, err := NewMemberService(db, projects).AddMember(ctx, owner.UserID, project.ProjectID, input); err != nil {
+			t.Fatalf("AddMember() error = %v", err)
+		}
+	}
+	return project
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/importer"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ImportService struct {
+	db        database.Service
+	projects  *ProjectService
+	testCases *TestCaseService
+}
+
+func NewImportService(db database.Service, projects *ProjectService, testCases *TestCaseService) *ImportService {
+	return &ImportService{
+		db:        db,
+		projects:  projects,
+		testCases: testCases,
+	}
+}
+
+// ImportReport describes the outcome of an import. Nothing is imported when
+// it is a dry run or when any row has errors.
+type ImportReport struct {
+	DryRun   bool                `json:"dry_run"`
+	Total    int                 `json:"total"`
+	Valid    int                 `json:"valid"`
+	Imported int                 `json:"imported"`
+	Errors   []importer.RowError `json:"errors"`
+}
+
+// ImportTestCases maps the rows of a table to test cases and validates them.
+// Unless it is a dry run, and provided every row is valid, all test cases
+// are then created in a single transaction, so an import is all or nothing.
+func (s *ImportService) ImportTestCases(ctx context.Context, userID, projectID uuid.UUID, table [][]string, mapping importer.Mapping, dryRun bool) (*ImportReport, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	drafts, err := importer.Parse(table, mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if len(drafts) == 0 {
+		return nil, fmt.Errorf("%w: the file has no test cases", ErrInvalidInput)
+	}
+
+	report := &ImportReport{DryRun: dryRun, Total: len(drafts), Errors: []importer.RowError{}}
+	inputs := make([]TestCaseInput, 0, len(drafts))
+	for _, draft := range drafts {
+		input := draftInput(draft)
+		if err := s.testCases.ValidateTestCase(&input); err != nil {
+			report.Errors = append(report.Errors, importer.RowError{Row: draft.Row, Message: err.Error()})
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+	report.Valid = len(inputs)
+	if dryRun || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, input := range inputs {
+			if err := createTestCase(tx, newTestCase(userID, projectID, input), input.Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import test cases: %w", err)
+	}
+
+	report.Imported = len(inputs)
+	return report, nil
+}
+
+func draftInput(draft importer.Draft) TestCaseInput {
+	input := TestCaseInput{
+		Title:         draft.Title,
+		Description:   draft.Description,
+		Preconditions: draft.Preconditions,
+		UserLevel:     draft.UserLevel,
+		Priority:      draft.Priority,
+		Status:        draft.Status,
+		Tags:          draft.Tags,
+	}
+	for _, step := range draft.Steps {
+		input.Steps = append(input.Steps, TestStepInput{
+			Action:         step.Action,
+			ExpectedResult: step.ExpectedResult,
+		})
+	}
+	return input
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/mailer"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/tokens"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm/clause"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+type InvitationService struct {
+	db       database.Service
+	keydb    database.KeyDBService
+	projects *ProjectService
+	mailer   mailer.Mailer
+}
+
+func NewInvitationService(db database.Service, keydb database.KeyDBService, projects *ProjectService, m mailer.Mailer) *InvitationService {
+	return &InvitationService{
+		db:       db,
+		keydb:    keydb,
+		projects: projects,
+		mailer:   m,
+	}
+}
+
+// Invitation is a pending invitation to join a project. Invitations live in
+// KeyDB and disappear once accepted, declined, revoked or expired.
+type Invitation struct {
+	InvitationID uuid.UUID `json:"invitation_id"`
+	ProjectID    uuid.UUID `json:"project_id"`
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	InvitedBy    uuid.UUID `json:"invited_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type InviteInput struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// Invite stores an invitation and emails a signed link to the invitee. Only
+// owners can invite collaborators.
+func (s *InvitationService) Invite(ctx context.Context, userID, projectID uuid.UUID, input InviteInput) (*Invitation, error) {
+	if err := validateRole(input.Role); err != nil {
+		return nil, err
+	}
+	address, err := mail.ParseAddress(input.Email)
+	if err != nil || address.Name != "" {
+		return nil, fmt.Errorf("%w: invalid email format", ErrInvalidInput)
+	}
+	email := strings.ToLower(address.Address)
+
+	project, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	var members int64
+	err = s.db.DB().WithContext(ctx).Model(&models.ProjectMember{}).
+		Joins("JOIN users ON users.user_id = project_members.user_id").
+		Where("project_members.project_id = ? AND users.email = ?", projectID, email).
+		Count(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership: %v", err)
+	}
+	if members > 0 {
+		return nil, fmt.Errorf("%w: user is already a member", ErrConflict)
+	}
+
+	now := time.Now()
+	invitation := &Invitation{
+		InvitationID: uuid.New(),
+		ProjectID:    projectID,
+		Email:        email,
+		Role:         input.Role,
+		InvitedBy:    userID,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(invitationTTL),
+	}
+	if err := s.store(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	token := tokens.Sign(signingSecret, invitation.InvitationID.String())
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{email},
+		Subject: fmt.Sprintf("You have been invited to %s on Test Alchemy", project.Name),
+		Body: fmt.Sprintf("You have been invited to join the project %q as %s.\n\n"+
+			"Sign in with this address and accept the invitation at:\n%s/api/invitations/%s/accept\n\n"+
+			"If you do not have an account yet, register with this address and you will be added automatically.\n\n"+
+			"To decline the invitation, use:\n%s/api/invitations/%s/decline\n\n"+
+			"The invitation expires on %s.\n",
+			project.Name, input.Role, appBaseURL, token, appBaseURL, token, invitation.ExpiresAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		s.remove(ctx, invitation)
+		return nil, fmt.Errorf("failed to send invitation: %v", err)
+	}
+
+	return invitation, nil
+}
+
+// ListInvitations returns the pending invitations of a project.
+func (s *InvitationService) ListInvitations(ctx context.Context, userID, projectID uuid.UUID) ([]Invitation, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.loadSet(ctx, projectInvitationsKey(projectID))
+}
+
+func (s *InvitationService) RevokeInvitation(ctx context.Context, userID, projectID, invitationID uuid.UUID) error {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	invitation, err := s.load(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation.ProjectID != projectID {
+		return fmt.Errorf("%w: invitation", ErrNotFound)
+	}
+	return s.remove(ctx, invitation)
+}
+
+// AcceptInvitation adds the user to the project of the invitation. The user's
+// email must match the address the invitation was sent to.
+func (s *InvitationService) AcceptInvitation(ctx context.Context, userID uuid.UUID, token string) (*models.ProjectMember, error) {
+	invitation, err := s.lookup(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, fmt.Errorf("%w: the invitation was sent to another address", ErrForbidden)
+	}
+
+	return s.accept(ctx, userID, invitation)
+}
+
+// DeclineInvitation discards the invitation. Anyone holding the link can decline it.
+func (s *InvitationService) DeclineInvitation(ctx context.Context, token string) error {
+	invitation, err := s.lookup(ctx, token)
+	if err != nil {
+		return err
+	}
+	return s.remove(ctx, invitation)
+}
+
+// LinkPendingInvitations accepts every pending invitation sent to email on
+// behalf of the user. It is called when an invitee registers.
+func (s *InvitationService) LinkPendingInvitations(ctx context.Context, userID uuid.UUID, email string) error {
+	invitations, err := s.loadSet(ctx, emailInvitationsKey(email))
+	if err != nil {
+		return err
+	}
+
+	for i := range invitations {
+		if _, err := s.accept(ctx, userID, &invitations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *InvitationService) accept(ctx context.Context, userID uuid.UUID, invitation *Invitation) (*models.ProjectMember, error) {
+	member := &models.ProjectMember{
+		ProjectID: invitation.ProjectID,
+		UserID:    userID,
+		Role:      invitation.Role,
+	}
+	// Existing members keep their current role
+	err := s.db.DB().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(member).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to add member: %v", err)
+	}
+	if err := s.remove(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Read(ctx, member, "project_id = ? AND user_id = ?", invitation.ProjectID, userID); err != nil {
+		return nil, fmt.Errorf("failed to get member: %v", err)
+	}
+	return member, nil
+}
+
+// lookup verifies the signature of an invitation token and loads the invitation.
+func (s *InvitationService) lookup(ctx context.Context, token string) (*Invitation, error) {
+	value, ok := tokens.Verify(signingSecret, token)
+	if !ok {
+		return nil, fmt.Errorf("%w: invitation", ErrNotFound)
+	}
+	invitationID, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invitation", ErrNotFound)
+	}
+	return s.load(ctx, invitationID)
+}
+
+func (s *InvitationService) load(ctx context.Context, invitationID uuid.UUID) (*Invitation, error) {
+	data, err := s.keydb.Get(ctx, invitationKey(invitationID))
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: invitation", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %v", err)
+	}
+
+	var invitation Invitation
+	if err := json.Unmarshal([]byte(data), &invitation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal invitation: %v", err)
+	}
+	return &invitation, nil
+}
+
+// loadSet loads the invitations listed in an index set, dropping the ones
+// that have expired in the meantime.
+func (s *InvitationService) loadSet(ctx context.Context, key string) ([]Invitation, error) {
+	ids, err := s.keydb.Client().SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %v", err)
+	}
+
+	invitations := []Invitation{}
+	for _, id := range ids {
+		invitationID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		invitation, err := s.load(ctx, invitationID)
+		if errors.Is(err, ErrNotFound) {
+			s.keydb.Client().SRem(ctx, key, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, nil
+}
+
+func (s *InvitationService) store(ctx context.Context, invitation *Invitation) error {
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invitation: %v", err)
+	}
+
+	if err := s.keydb.Set(ctx, invitationKey(invitation.InvitationID), data, invitationTTL); err != nil {
+		return fmt.Errorf("failed to store invitation: %v", err)
+	}
+
+	// Index the invitation by invitee and by project
+	id := invitation.InvitationID.String()
+	_, err = s.keydb.Client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range []string{emailInvitationsKey(invitation.Email), projectInvitationsKey(invitation.ProjectID)} {
+			pipe.SAdd(ctx, key, id)
+			pipe.Expire(ctx, key, invitationTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store invitation: %v", err)
+	}
+	return nil
+}
+
+func (s *InvitationService) remove(ctx context.Context, invitation *Invitation) error {
+	id := invitation.InvitationID.String()
+	_, err := s.keydb.Client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, invitationKey(invitation.InvitationID))
+		pipe.SRem(ctx, emailInvitationsKey(invitation.Email), id)
+		pipe.SRem(ctx, projectInvitationsKey(invitation.ProjectID), id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove invitation: %v", err)
+	}
+	return nil
+}
+
+func invitationKey(invitationID uuid.UUID) string {
+	return fmt.Sprintf("invitation:%s", invitationID)
+}
+
+func emailInvitationsKey(email string) string {
+	return fmt.Sprintf("invitations:email:%s", strings.ToLower(email))
+}
+
+func projectInvitationsKey(projectID uuid.UUID) string {
+	return fmt.Sprintf("invitations:project:%s", projectID)
+}
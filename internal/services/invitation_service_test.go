@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"TestAlchemy/internal/mailer"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+var invitationTokenPattern = regexp.MustCompile(`/api/invitations/([^/\s]+)/accept`)
+
+// invitationTest is a project with its owner, and the services to invite
+// people to it.
+type invitationTest struct {
+	invitations *InvitationService
+	projects    *ProjectService
+	outbox      *mailer.Outbox
+	owner       *models.User
+	project     *models.Project
+}
+
+func startInvitationTest(t *testing.T) *invitationTest {
+	t.Helper()
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	outbox := mailer.NewOutbox("")
+	projects := NewProjectService(db)
+	owner := createUser(t, db)
+	project, err := projects.CreateProject(context.Background(), owner.UserID, ProjectInput{Name: "Invitations"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	return &invitationTest{
+		invitations: NewInvitationService(db, keydb, projects, outbox),
+		projects:    projects,
+		outbox:      outbox,
+		owner:       owner,
+		project:     project,
+	}
+}
+
+// invite invites the address to the project and returns the token emailed
+// to it.
+func (it *invitationTest) invite(t *testing.T, email, role string) (*Invitation, string) {
+	t.Helper()
+	invitation, err := it.invitations.Invite(context.Background(), it.owner.UserID, it.project.ProjectID, InviteInput{Email: email, Role: role})
+	if err != nil {
+		t.Fatalf("Invite() error = %v", err)
+	}
+	message, ok := it.outbox.Last(strings.ToLower(email))
+	if !ok {
+		t.Fatal("expected an invitation email")
+	}
+	match := invitationTokenPattern.FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no token in the email %q", message.Body)
+	}
+	return invitation, match[1]
+}
+
+// pending returns the IDs of the pending invitations of the project.
+func (it *invitationTest) pending(t *testing.T) []uuid.UUID {
+	t.Helper()
+	invitations, err := it.invitations.ListInvitations(context.Background(), it.owner.UserID, it.project.ProjectID)
+	if err != nil {
+		t.Fatalf("ListInvitations() error = %v", err)
+	}
+	ids := []uuid.UUID{}
+	for _, invitation := range invitations {
+		ids = append(ids, invitation.InvitationID)
+	}
+	return ids
+}
+
+func (it *invitationTest) role(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	var member models.ProjectMember
+	if err := it.invitations.db.Read(context.Background(), &member, "project_id = ? AND user_id = ?", it.project.ProjectID, userID); err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+func TestInvite(t *testing.T) {
+	it := startInvitationTest(t)
+	ctx := context.Background()
+	outsider := createUser(t, it.invitations.db)
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		input  InviteInput
+		want   error
+	}{
+		{"unknown role", it.owner.UserID, InviteInput{Email: "new@example.com", Role: "admin"}, ErrInvalidInput},
+		{"invalid address", it.owner.UserID, InviteInput{Email: "Someone <new@example.com>", Role: models.RoleEditor}, ErrInvalidInput},
+		{"not a member", outsider.UserID, InviteInput{Email: "new@example.com", Role: models.RoleEditor}, ErrNotFound},
+		{"already a member", it.owner.UserID, InviteInput{Email: strings.ToUpper(it.owner.Email), Role: models.RoleEditor}, ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := it.invitations.Invite(ctx, tt.userID, it.project.ProjectID, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	if len(it.outbox.Messages()) != 0 {
+		t.Errorf("expected no email for refused invitations, got %d", len(it.outbox.Messages()))
+	}
+
+	invitation, _ := it.invite(t, "New.Member@Example.com", models.RoleViewer)
+	if invitation.Email != "new.member@example.com" {
+		t.Errorf("expected the address to be lower-cased, got %q", invitation.Email)
+	}
+	if got := it.pending(t); len(got) != 1 || got[0] != invitation.InvitationID {
+		t.Errorf("expected the invitation to be pending, got %v", got)
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	it := startInvitationTest(t)
+	ctx := context.Background()
+	invitee := createUser(t, it.invitations.db)
+	other := createUser(t, it.invitations.db)
+	_, token := it.invite(t, invitee.Email, models.RoleEditor)
+
+	if _, err := it.invitations.AcceptInvitation(ctx, other.UserID, token); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected an invitation sent to another address to be refused, got %v", err)
+	}
+	if _, err := it.invitations.AcceptInvitation(ctx, invitee.UserID, token+"x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a tampered token to be refused, got %v", err)
+	}
+
+	member, err := it.invitations.AcceptInvitation(ctx, invitee.UserID, token)
+	if err != nil {
+		t.Fatalf("AcceptInvitation() error = %v", err)
+	}
+	if member.Role != models.RoleEditor || it.role(t, invitee.UserID) != models.RoleEditor {
+		t.Errorf("expected the invitee to join as editor, got %+v", member)
+	}
+	if got := it.pending(t); len(got) != 0 {
+		t.Errorf("expected no pending invitation, got %v", got)
+	}
+	if _, err := it.invitations.AcceptInvitation(ctx, invitee.UserID, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an accepted invitation to be used once, got %v", err)
+	}
+	if it.role(t, other.UserID) != "" {
+		t.Error("expected the other user not to join")
+	}
+}
+
+func TestDeclineAndRevokeInvitation(t *testing.T) {
+	it := startInvitationTest(t)
+	ctx := context.Background()
+	invitee := createUser(t, it.invitations.db)
+
+	_, token := it.invite(t, invitee.Email, models.RoleViewer)
+	if err := it.invitations.DeclineInvitation(ctx, token); err != nil {
+		t.Fatalf("DeclineInvitation() error = %v", err)
+	}
+	if _, err := it.invitations.AcceptInvitation(ctx, invitee.UserID, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a declined invitation to be gone, got %v", err)
+	}
+
+	invitation, token := it.invite(t, invitee.Email, models.RoleViewer)
+	if err := it.invitations.RevokeInvitation(ctx, it.owner.UserID, uuid.New(), invitation.InvitationID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected revoking through another project to be refused, got %v", err)
+	}
+	if err := it.invitations.RevokeInvitation(ctx, it.owner.UserID, it.project.ProjectID, invitation.InvitationID); err != nil {
+		t.Fatalf("RevokeInvitation() error = %v", err)
+	}
+	if _, err := it.invitations.AcceptInvitation(ctx, invitee.UserID, token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a revoked invitation to be gone, got %v", err)
+	}
+	if got := it.pending(t); len(got) != 0 {
+		t.Errorf("expected no pending invitation, got %v", got)
+	}
+	if it.role(t, invitee.UserID) != "" {
+		t.Error("expected the invitee not to join")
+	}
+}
+
+func TestLinkPendingInvitations(t *testing.T) {
+	it := startInvitationTest(t)
+	ctx := context.Background()
+	second, err := it.projects.CreateProject(ctx, it.owner.UserID, ProjectInput{Name: "Second"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	invitee := createUser(t, it.invitations.db)
+
+	it.invite(t, invitee.Email, models.RoleEditor)
+	if _, err := it.invitations.Invite(ctx, it.owner.UserID, second.ProjectID, InviteInput{Email: invitee.Email, Role: models.RoleViewer}); err != nil {
+		t.Fatalf("Invite() error = %v", err)
+	}
+	// An invitation that expired before the invitee registered
+	expired, _ := it.invite(t, "expired-"+invitee.Email, models.RoleEditor)
+	if err := it.invitations.keydb.Delete(ctx, invitationKey(expired.InvitationID)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := it.invitations.LinkPendingInvitations(ctx, invitee.UserID, strings.ToUpper(invitee.Email)); err != nil {
+		t.Fatalf("LinkPendingInvitations() error = %v", err)
+	}
+	if role := it.role(t, invitee.UserID); role != models.RoleEditor {
+		t.Errorf("expected the invitee to join as editor, got %q", role)
+	}
+	var member models.ProjectMember
+	if err := it.invitations.db.Read(ctx, &member, "project_id = ? AND user_id = ?", second.ProjectID, invitee.UserID); err != nil || member.Role != models.RoleViewer {
+		t.Errorf("expected the invitee to join the second project as viewer, got %+v, %v", member, err)
+	}
+
+	// Listing drops the expired invitation from the indexes
+	if got := it.pending(t); len(got) != 0 {
+		t.Errorf("expected no pending invitation, got %v", got)
+	}
+	if n := it.invitations.keydb.Client().SCard(ctx, projectInvitationsKey(it.project.ProjectID)).Val(); n != 0 {
+		t.Errorf("expected the expired invitation to be pruned, got %d left", n)
+	}
+}
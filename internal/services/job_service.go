@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"TestAlchemy/internal/ai"
+	"TestAlchemy/internal/blobstore"
+	"TestAlchemy/internal/export"
+	"TestAlchemy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+// Job types
+const (
+	JobGenerateTestCase = "generate_test_case"
+	JobExport           = "export"
+)
+
+type JobService struct {
+	queue   *jobs.Queue
+	ai      *AIService
+	exports *ExportService
+	store   blobstore.BlobStore
+}
+
+// NewJobService returns a service that runs AI generation and exports in
+// the background. It registers their handlers on the queue.
+func NewJobService(queue *jobs.Queue, aiService *AIService, exports *ExportService, store blobstore.BlobStore) *JobService {
+	s := &JobService{
+		queue:   queue,
+		ai:      aiService,
+		exports: exports,
+		store:   store,
+	}
+	queue.Register(JobGenerateTestCase, s.generateTestCase)
+	queue.Register(JobExport, s.export)
+	return s
+}
+
+type generatePayload struct {
+	ProjectID   uuid.UUID `json:"project_id"`
+	Description string    `json:"description"`
+	Image       []byte    `json:"image,omitempty"`
+	MediaType   string    `json:"media_type,omitempty"`
+}
+
+type exportPayload struct {
+	ProjectID uuid.UUID     `json:"project_id"`
+	Format    string        `json:"format"`
+	Layout    export.Layout `json:"layout"`
+}
+
+// ExportResult is the result of an export job. The file is downloaded
+// separately.
+type ExportResult struct {
+	ProjectID   uuid.UUID `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	Format      string    `json:"format"`
+	Size        int64     `json:"size"`
+}
+
+// EnqueueGeneration queues the generation of a test case draft. The request
+// is validated up front; the draft becomes the result of the job.
+func (s *JobService) EnqueueGeneration(ctx context.Context, userID, projectID uuid.UUID, image ai.Image, description string) (*jobs.Job, error) {
+	description, err := s.ai.checkGeneration(ctx, userID, projectID, image, description)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.queue.Enqueue(ctx, JobGenerateTestCase, userID, generatePayload{
+		ProjectID:   projectID,
+		Description: description,
+		Image:       image.Data,
+		MediaType:   image.MediaType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue generation: %v", err)
+	}
+	return job, nil
+}
+
+// EnqueueExport queues an export of every test case of the project in the
+// "csv" or "xlsx" format.
+func (s *JobService) EnqueueExport(ctx context.Context, userID, projectID uuid.UUID, format string, layout export.Layout) (*jobs.Job, error) {
+	if format != "csv" && format != "xlsx" {
+		return nil, fmt.Errorf("%w: format must be csv or xlsx", ErrInvalidInput)
+	}
+	if _, err := s.exports.Authorize(ctx, userID, projectID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.queue.Enqueue(ctx, JobExport, userID, exportPayload{
+		ProjectID: projectID,
+		Format:    format,
+		Layout:    layout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue export: %v", err)
+	}
+	return job, nil
+}
+
+// GetJob returns the status of one of the user's jobs.
+func (s *JobService) GetJob(ctx context.Context, userID, jobID uuid.UUID) (*jobs.Job, error) {
+	job, err := s.queue.Get(ctx, jobID)
+	if errors.Is(err, jobs.ErrNotFound) {
+		return nil, fmt.Errorf("%w: job", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, fmt.Errorf("%w: job", ErrNotFound)
+	}
+	return job, nil
+}
+
+// OpenExport returns the result and the file of one of the user's finished
+// export jobs. Access to the project is checked again, as the user may have
+// lost it since the export ran. The caller must close the file.
+func (s *JobService) OpenExport(ctx context.Context, userID, jobID uuid.UUID) (*ExportResult, io.ReadCloser, error) {
+	job, err := s.GetJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Type != JobExport {
+		return nil, nil, fmt.Errorf("%w: the job is not an export", ErrInvalidInput)
+	}
+	if job.Status != jobs.StatusSucceeded {
+		return nil, nil, fmt.Errorf("%w: the export is %s", ErrConflict, job.Status)
+	}
+
+	var result ExportResult
+	if err := json.Unmarshal(job.Result, &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal export result: %v", err)
+	}
+	if _, err := s.exports.Authorize(ctx, userID, result.ProjectID); err != nil {
+		return nil, nil, err
+	}
+	file, err := s.store.Get(ctx, exportKey(jobID, result.Format))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: export file", ErrNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %v", err)
+	}
+	return &result, file, nil
+}
+
+func (s *JobService) generateTestCase(ctx context.Context, job *jobs.Job, data json.RawMessage) (interface{}, error) {
+	var payload generatePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	image := ai.Image{Data: payload.Image, MediaType: payload.MediaType}
+	draft, err := s.ai.GenerateTestCase(ctx, job.UserID, payload.ProjectID, image, payload.Description)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return draft, nil
+}
+
+// export writes the file of an export job to the blob store. Access is
+// checked again, in case the user lost it while the job was queued.
+func (s *JobService) export(ctx context.Context, job *jobs.Job, data json.RawMessage) (interface{}, error) {
+	var payload exportPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	project, err := s.exports.Authorize(ctx, job.UserID, payload.ProjectID)
+	if err != nil {
+		return nil, jobError(err)
+	}
+
+	var buf bytes.Buffer
+	var exporter export.Exporter = export.NewCSV(&buf, payload.Layout)
+	if payload.Format == "xlsx" {
+		if exporter, err = export.NewXLSX(&buf, payload.Layout); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.exports.Export(ctx, payload.ProjectID, exporter); err != nil {
+		return nil, err
+	}
+
+	size := int64(buf.Len())
+	if err := s.store.Put(ctx, exportKey(job.JobID, payload.Format), &buf, size, ""); err != nil {
+		return nil, fmt.Errorf("failed to store export: %v", err)
+	}
+	return ExportResult{ProjectID: project.ProjectID, ProjectName: project.Name, Format: payload.Format, Size: size}, nil
+}
+
+// jobError marks the service errors that retrying cannot fix as permanent.
+func jobError(err error) error {
+	if errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrForbidden) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// exportKey is where the file of an export job is stored. Export files are
+// not deleted when their job expires; a lifecycle rule on the "exports/"
+// prefix can remove them.
+func exportKey(jobID uuid.UUID, format string) string {
+	return fmt.Sprintf("exports/%s.%s", jobID, format)
+}
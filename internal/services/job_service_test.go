@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/blobstore"
+	"TestAlchemy/internal/export"
+	"TestAlchemy/internal/jobs"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestOpenExportChecksAccess(t *testing.T) {
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	members := NewMemberService(db, projects)
+	queue := jobs.NewQueue(keydb.Client())
+	s := NewJobService(queue, nil, NewExportService(db, projects), blobstore.NewLocal(t.TempDir()))
+	pool := jobs.NewPool(queue, 1)
+	pool.Start()
+	t.Cleanup(func() {
+		drainCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		pool.Drain(drainCtx)
+	})
+
+	owner := createUser(t, db)
+	viewer := createUser(t, db)
+	project, err := projects.CreateProject(ctx, owner.UserID, ProjectInput{Name: "Exported"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	if _, err := members.AddMember(ctx, owner.UserID, project.ProjectID, AddMemberInput{Email: viewer.Email, Role: models.RoleViewer}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+
+	// exportAs runs an export of the project for the user until it succeeds.
+	exportAs := func(userID uuid.UUID) uuid.UUID {
+		t.Helper()
+		job, err := s.EnqueueExport(ctx, userID, project.ProjectID, "csv", export.StepsJoined)
+		if err != nil {
+			t.Fatalf("EnqueueExport() error = %v", err)
+		}
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			job, err = s.GetJob(ctx, userID, job.JobID)
+			if err != nil {
+				t.Fatalf("GetJob() error = %v", err)
+			}
+			if job.Status == jobs.StatusSucceeded {
+				return job.JobID
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("export did not succeed, got %+v", job)
+		return uuid.Nil
+	}
+
+	viewerJob := exportAs(viewer.UserID)
+	result, file, err := s.OpenExport(ctx, viewer.UserID, viewerJob)
+	if err != nil {
+		t.Fatalf("OpenExport() error = %v", err)
+	}
+	file.Close()
+	if result.ProjectID != project.ProjectID {
+		t.Errorf("expected the export of project %s, got %+v", project.ProjectID, result)
+	}
+
+	if _, _, err := s.OpenExport(ctx, owner.UserID, viewerJob); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the export of another user to be hidden, got %v", err)
+	}
+
+	// Removed members can no longer download what they exported
+	if err := members.RemoveMember(ctx, owner.UserID, project.ProjectID, viewer.UserID); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if _, _, err := s.OpenExport(ctx, viewer.UserID, viewerJob); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a removed member to be refused, got %v", err)
+	}
+
+	// Nor can anyone once the project is deleted
+	ownerJob := exportAs(owner.UserID)
+	if err := projects.DeleteProject(ctx, owner.UserID, project.ProjectID); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if _, _, err := s.OpenExport(ctx, owner.UserID, ownerJob); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the export of a deleted project to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// takeKey gets the value of a key and deletes it in one transaction, so
+// that concurrent requests cannot both use a single-use token. It returns
+// redis.Nil when the key does not exist.
+func takeKey(ctx context.Context, keydb database.KeyDBService, key string) (string, error) {
+	var get *redis.StringCmd
+	_, err := keydb.Client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
+}
+
+// countKey increments the counter at key, resetting its expiry to ttl, and
+// returns its new value.
+func countKey(ctx context.Context, keydb database.KeyDBService, key string, ttl time.Duration) (int64, error) {
+	var count *redis.IntCmd
+	_, err := keydb.Client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MemberService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewMemberService(db database.Service, projects *ProjectService) *MemberService {
+	return &MemberService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+// Member is a project member together with the user's email.
+type Member struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddMemberInput struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func validateRole(role string) error {
+	if !slices.Contains(models.Roles, role) {
+		return fmt.Errorf("%w: role must be one of %s", ErrInvalidInput, strings.Join(models.Roles, ", "))
+	}
+	return nil
+}
+
+func (s *MemberService) ListMembers(ctx context.Context, userID, projectID uuid.UUID) ([]Member, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	var members []Member
+	err := s.db.DB().WithContext(ctx).
+		Model(&models.ProjectMember{}).
+		Select("project_members.user_id, users.email, project_members.role, project_members.created_at").
+		Joins("JOIN users ON users.user_id = project_members.user_id").
+		Where("project_members.project_id = ?", projectID).
+		Order("project_members.created_at ASC").
+		Scan(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %v", err)
+	}
+	return members, nil
+}
+
+// AddMember adds an existing user to the project. Only owners can add members.
+func (s *MemberService) AddMember(ctx context.Context, userID, projectID uuid.UUID, input AddMemberInput) (*Member, error) {
+	if err := validateRole(input.Role); err != nil {
+		return nil, err
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err := s.db.Read(ctx, &user, "email = ?", input.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: user", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	member := &models.ProjectMember{
+		ProjectID: projectID,
+		UserID:    user.UserID,
+		Role:      input.Role,
+	}
+	result := s.db.DB().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(member)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to add member: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: user is already a member", ErrConflict)
+	}
+
+	return &Member{
+		UserID:    user.UserID,
+		Email:     user.Email,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}, nil
+}
+
+// UpdateMemberRole changes the role of a member. Only owners can change
+// roles, and the last owner cannot be demoted.
+func (s *MemberService) UpdateMemberRole(ctx context.Context, userID, projectID, memberID uuid.UUID, role string) error {
+	if err := validateRole(role); err != nil {
+		return err
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	return s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		member, err := lockMember(tx, projectID, memberID)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner && role != models.RoleOwner {
+			if err := ensureAnotherOwner(tx, projectID, memberID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(member).Update("role", role).Error
+	})
+}
+
+// RemoveMember removes a member from the project. Owners can remove anyone
+// and every member can remove themselves, but the last owner cannot leave.
+func (s *MemberService) RemoveMember(ctx context.Context, userID, projectID, memberID uuid.UUID) error {
+	role := models.RoleOwner
+	if memberID == userID {
+		role = models.RoleViewer
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, role); err != nil {
+		return err
+	}
+
+	return s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		member, err := lockMember(tx, projectID, memberID)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner {
+			if err := ensureAnotherOwner(tx, projectID, memberID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(member).Error
+	})
+}
+
+// TransferOwnership makes another member the owner of the project. The
+// current owner stays on the project as an editor.
+func (s *MemberService) TransferOwnership(ctx context.Context, userID, projectID, newOwnerID uuid.UUID) error {
+	if newOwnerID == userID {
+		return fmt.Errorf("%w: cannot transfer ownership to yourself", ErrInvalidInput)
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleOwner); err != nil {
+		return err
+	}
+
+	return s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		newOwner, err := lockMember(tx, projectID, newOwnerID)
+		if err != nil {
+			return err
+		}
+		current, err := lockMember(tx, projectID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(newOwner).Update("role", models.RoleOwner).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(current).Update("role", models.RoleEditor).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Project{}).
+			Where("project_id = ?", projectID).
+			Update("user_id", newOwnerID).Error
+	})
+}
+
+// lockMember loads a member row for update inside a transaction.
+func lockMember(tx *gorm.DB, projectID, userID uuid.UUID) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: member", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ensureAnotherOwner fails unless the project has an owner other than userID.
+func ensureAnotherOwner(tx *gorm.DB, projectID, userID uuid.UUID) error {
+	var owners int64
+	err := tx.Model(&models.ProjectMember{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND role = ? AND user_id <> ?", projectID, models.RoleOwner, userID).
+		Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return fmt.Errorf("%w: the last owner cannot leave or be demoted", ErrConflict)
+	}
+	return nil
+}
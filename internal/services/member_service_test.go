@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"TestAlchemy/internal/models"
+)
+
+func TestMemberManagement(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	s := NewMemberService(db, NewProjectService(db))
+	owner := createUser(t, db)
+	editor := createUser(t, db)
+	viewer := createUser(t, db)
+	outsider := createUser(t, db)
+	project := createProject(t, db, owner, map[*models.User]string{
+		editor: models.RoleEditor,
+		viewer: models.RoleViewer,
+	})
+
+	role := func(user *models.User) string {
+		t.Helper()
+		var member models.ProjectMember
+		if err := db.Read(ctx, &member, "project_id = ? AND user_id = ?", project.ProjectID, user.UserID); err != nil {
+			return ""
+		}
+		return member.Role
+	}
+
+	// Editors can edit the project but not manage its members
+	if _, err := s.AddMember(ctx, editor.UserID, project.ProjectID, AddMemberInput{Email: outsider.Email, Role: models.RoleViewer}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected editors not to add members, got %v", err)
+	}
+	if err := s.UpdateMemberRole(ctx, editor.UserID, project.ProjectID, viewer.UserID, models.RoleEditor); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected editors not to change roles, got %v", err)
+	}
+	if err := s.RemoveMember(ctx, editor.UserID, project.ProjectID, viewer.UserID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected editors not to remove members, got %v", err)
+	}
+	if err := s.TransferOwnership(ctx, editor.UserID, project.ProjectID, editor.UserID); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a transfer to oneself to be refused, got %v", err)
+	}
+	if err := s.TransferOwnership(ctx, editor.UserID, project.ProjectID, viewer.UserID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected editors not to transfer ownership, got %v", err)
+	}
+
+	// Non-members do not learn that the project exists
+	if _, err := s.ListMembers(ctx, outsider.UserID, project.ProjectID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected non-members not to list members, got %v", err)
+	}
+	if err := s.RemoveMember(ctx, outsider.UserID, project.ProjectID, outsider.UserID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected non-members not to leave, got %v", err)
+	}
+	if role(viewer) != models.RoleViewer || role(outsider) != "" {
+		t.Fatal("expected the members to be unchanged")
+	}
+
+	// The last owner can neither be demoted nor leave
+	if err := s.UpdateMemberRole(ctx, owner.UserID, project.ProjectID, owner.UserID, models.RoleEditor); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the last owner not to be demoted, got %v", err)
+	}
+	if err := s.RemoveMember(ctx, owner.UserID, project.ProjectID, owner.UserID); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the last owner not to leave, got %v", err)
+	}
+	if role(owner) != models.RoleOwner {
+		t.Fatalf("expected the owner to stay, got %q", role(owner))
+	}
+
+	// With a second owner, the first can step down
+	if err := s.UpdateMemberRole(ctx, owner.UserID, project.ProjectID, editor.UserID, models.RoleOwner); err != nil {
+		t.Fatalf("UpdateMemberRole() error = %v", err)
+	}
+	if err := s.UpdateMemberRole(ctx, editor.UserID, project.ProjectID, owner.UserID, models.RoleEditor); err != nil {
+		t.Fatalf("UpdateMemberRole() error = %v", err)
+	}
+	if err := s.RemoveMember(ctx, editor.UserID, project.ProjectID, editor.UserID); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected the new last owner not to leave, got %v", err)
+	}
+
+	// Members can leave on their own
+	if err := s.RemoveMember(ctx, viewer.UserID, project.ProjectID, viewer.UserID); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if role(viewer) != "" {
+		t.Error("expected the viewer to have left")
+	}
+}
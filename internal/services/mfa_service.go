@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/tokens"
+	"TestAlchemy/internal/totp"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer        = "Test Alchemy"
+	mfaEnrolmentTTL   = 10 * time.Minute
+	recoveryCodeCount = 10
+	// mfaCheckWindow is how long wrong passwords and codes given to manage
+	// two-factor authentication are counted, up to maxMFAAttempts
+	mfaCheckWindow = 15 * time.Minute
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAService struct {
+	db    database.Service
+	keydb database.KeyDBService
+	audit *AuditService
+}
+
+func NewMFAService(db database.Service, keydb database.KeyDBService, audit *AuditService) *MFAService {
+	return &MFAService{
+		db:    db,
+		keydb: keydb,
+		audit: audit,
+	}
+}
+
+// MFAStatus describes the two-factor authentication of a user.
+type MFAStatus struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// TOTPEnrolment is a TOTP secret waiting to be confirmed. URI is the
+// otpauth URI to show as a QR code; Secret is for manual entry.
+type TOTPEnrolment struct {
+	Secret    string    `json:"secret"`
+	URI       string    `json:"otpauth_uri"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RecoveryCodes are shown once, when they are generated.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code"`
+}
+
+type DisableMFAInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type RecoveryCodesInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (s *MFAService) Status(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{Enabled: user.MFAEnabled}
+	if user.MFAEnabled {
+		err := s.db.DB().WithContext(ctx).Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&status.RecoveryCodesRemaining).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %v", err)
+		}
+	}
+	return status, nil
+}
+
+// StartTOTP generates a TOTP secret for the user. Two-factor
+// authentication is enabled once ConfirmTOTP receives a code for it.
+func (s *MFAService) StartTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrolment, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+	}
+
+	secret := totp.GenerateSecret()
+	if err := s.keydb.Set(ctx, mfaEnrolmentKey(userID), secret, mfaEnrolmentTTL); err != nil {
+		return nil, fmt.Errorf("failed to store enrolment: %v", err)
+	}
+	return &TOTPEnrolment{
+		Secret:    secret,
+		URI:       totp.URI(totpIssuer, user.Email, secret),
+		ExpiresAt: time.Now().Add(mfaEnrolmentTTL),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication with the secret of
+// StartTOTP, given a code generated from it, and returns fresh recovery
+// codes.
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, input MFACodeInput) (*RecoveryCodes, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+	}
+
+	secret, err := s.keydb.Get(ctx, mfaEnrolmentKey(userID))
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: enrolment", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enrolment: %v", err)
+	}
+	counter, ok := totp.Validate(secret, normalizeCode(input.Code), time.Now(), 0)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid code", ErrInvalidInput)
+	}
+
+	var codes []string
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"mfa_enabled":  true,
+			"totp_secret":  secret,
+			"totp_counter": counter,
+		}).Error
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %v", err)
+	}
+	s.keydb.Delete(ctx, mfaEnrolmentKey(userID))
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditMFAEnable,
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+// DisableMFA turns two-factor authentication off. It requires both the
+// password and a code, so that a stolen session is not enough.
+func (s *MFAService) DisableMFA(ctx context.Context, userID uuid.UUID, input DisableMFAInput) error {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return fmt.Errorf("%w: two-factor authentication is not enabled", ErrConflict)
+	}
+	if err := s.checkCredentials(ctx, user, input.Password, input.Code); err != nil {
+		return err
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"mfa_enabled":  false,
+			"totp_secret":  "",
+			"totp_counter": 0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %v", err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditMFADisable,
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, given
+// the password and a TOTP or recovery code.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, input RecoveryCodesInput) (*RecoveryCodes, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is not enabled", ErrConflict)
+	}
+	if err := s.checkCredentials(ctx, user, input.Password, input.Code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %v", err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditMFARecoveryCodes,
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+// Verify checks a TOTP or recovery code of a user with two-factor
+// authentication enabled. Each code is accepted only once.
+func (s *MFAService) Verify(ctx context.Context, user *models.User, code string) error {
+	code = normalizeCode(code)
+	db := s.db.DB().WithContext(ctx)
+
+	if len(code) == totp.Digits {
+		counter, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPCounter)
+		if !ok {
+			return fmt.Errorf("%w: invalid code", ErrInvalidInput)
+		}
+		// The condition on the counter prevents concurrent requests from
+		// using the same code
+		result := db.Model(&models.User{}).
+			Where("user_id = ? AND totp_counter < ?", user.UserID, counter).
+			Update("totp_counter", counter)
+		if result.Error != nil {
+			return fmt.Errorf("failed to verify code: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: invalid code", ErrInvalidInput)
+		}
+		user.TOTPCounter = counter
+		return nil
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.UserID, tokens.Hash(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to verify code: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: invalid code", ErrInvalidInput)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditMFARecoveryCodeUse,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+	})
+	return nil
+}
+
+// checkCredentials checks the password and a code of a user changing their
+// two-factor authentication. Tries are counted before they are checked, so
+// that a stolen session, even with parallel requests, cannot try more than
+// maxMFAAttempts per mfaCheckWindow.
+func (s *MFAService) checkCredentials(ctx context.Context, user *models.User, password, code string) error {
+	key := mfaChecksKey(user.UserID)
+	attempts, err := countKey(ctx, s.keydb, key, mfaCheckWindow)
+	if err != nil {
+		return fmt.Errorf("failed to count MFA attempt: %v", err)
+	}
+	if attempts > maxMFAAttempts {
+		return fmt.Errorf("%w: too many wrong passwords or codes, try again later", ErrRateLimited)
+	}
+	if !user.ValidatePassword(password) {
+		return fmt.Errorf("%w: password is incorrect", ErrInvalidInput)
+	}
+	if err := s.Verify(ctx, user, code); err != nil {
+		return err
+	}
+	s.keydb.Delete(ctx, key)
+	return nil
+}
+
+func (s *MFAService) user(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("%w: user", ErrNotFound)
+	}
+	return &user, nil
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and stores new
+// ones, returned in clear text.
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = generateRecoveryCode()
+		records[i] = models.RecoveryCode{
+			RecoveryCodeID: uuid.New(),
+			UserID:         userID,
+			CodeHash:       tokens.Hash(normalizeCode(codes[i])),
+			CreatedAt:      now,
+		}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code of 10 base32 characters in
+// the form "xxxxx-xxxxx".
+func generateRecoveryCode() string {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+// normalizeCode removes the spaces and dashes users may type in a code,
+// and lower-cases recovery codes.
+func normalizeCode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)
+	return strings.ToLower(code)
+}
+
+func mfaEnrolmentKey(userID uuid.UUID) string {
+	return fmt.Sprintf("mfa_enrolment:%s", userID)
+}
+
+func mfaChecksKey(userID uuid.UUID) string {
+	return fmt.Sprintf("mfa_checks:%s", userID)
+}
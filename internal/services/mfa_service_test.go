@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/mailer"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/session"
+	"TestAlchemy/internal/totp"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code := generateRecoveryCode()
+		if !format.MatchString(code) {
+			t.Fatalf("unexpected recovery code %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"123456", "123456"},
+		{" 123 456 ", "123456"},
+		{"ABCDE-FGHIJ", "abcdefghij"},
+		{"abcde fghij", "abcdefghij"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeCode(tt.code); got != tt.want {
+			t.Errorf("normalizeCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+// mfaTest is a user with two-factor authentication enabled, and the
+// services to log them in.
+type mfaTest struct {
+	users         *UserService
+	mfa           *MFAService
+	store         *session.Store
+	user          *models.User
+	secret        string
+	recoveryCodes []string
+	// counter is the time step of the last TOTP code accepted
+	counter int64
+}
+
+func startMFATest(t *testing.T) *mfaTest {
+	t.Helper()
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	outbox := mailer.NewOutbox("")
+	projects := NewProjectService(db)
+	audit := NewAuditService(db, projects)
+	mfa := NewMFAService(db, keydb, audit)
+	store := newSessionStore(keydb)
+	users := NewUserService(db, keydb, store, outbox, NewInvitationService(db, keydb, projects, outbox), mfa, audit)
+	user := createUser(t, db)
+	secret, counter, recoveryCodes := enableMFA(t, mfa, user)
+	return &mfaTest{
+		users:         users,
+		mfa:           mfa,
+		store:         store,
+		user:          user,
+		secret:        secret,
+		recoveryCodes: recoveryCodes,
+		counter:       counter,
+	}
+}
+
+// enableMFA enables two-factor authentication for the user. It returns
+// the TOTP secret, the time step of the code used to enable it, and the
+// recovery codes.
+func enableMFA(t *testing.T, mfa *MFAService, user *models.User) (string, int64, []string) {
+	t.Helper()
+	ctx := context.Background()
+	enrolment, err := mfa.StartTOTP(ctx, user.UserID)
+	if err != nil {
+		t.Fatalf("StartTOTP() error = %v", err)
+	}
+	counter := totp.Counter(time.Now())
+	code, err := totp.Code(enrolment.Secret, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := mfa.ConfirmTOTP(ctx, user.UserID, MFACodeInput{Code: code})
+	if err != nil {
+		t.Fatalf("ConfirmTOTP() error = %v", err)
+	}
+	user.MFAEnabled = true
+	return enrolment.Secret, counter, recovery.Codes
+}
+
+// login checks the password of the user and returns the MFA token.
+func (m *mfaTest) login(t *testing.T) string {
+	t.Helper()
+	result, err := m.users.Login(context.Background(), LoginUserInput{Email: m.user.Email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if !result.MFARequired || result.MFAToken == "" || result.SessionID != "" {
+		t.Fatalf("expected an MFA token instead of a session, got %+v", result)
+	}
+	return result.MFAToken
+}
+
+// nextCode returns the TOTP code of the step after the last accepted one,
+// which is within the allowed clock skew.
+func (m *mfaTest) nextCode(t *testing.T) string {
+	t.Helper()
+	m.counter++
+	code, err := totp.Code(m.secret, m.counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func (m *mfaTest) complete(token, code string) (string, error) {
+	return m.users.CompleteMFALogin(context.Background(), MFALoginInput{MFAToken: token, Code: code})
+}
+
+func TestMFALogin(t *testing.T) {
+	m := startMFATest(t)
+	ctx := context.Background()
+
+	token := m.login(t)
+	code := m.nextCode(t)
+	sessionID, err := m.complete(token, code)
+	if err != nil {
+		t.Fatalf("CompleteMFALogin() error = %v", err)
+	}
+	sess, err := m.store.GetSession(ctx, sessionID)
+	if err != nil || sess == nil || sess.UserID != m.user.UserID {
+		t.Fatalf("expected a session of the user, got %+v, %v", sess, err)
+	}
+
+	if _, err := m.complete(token, m.nextCode(t)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a used MFA token to be refused, got %v", err)
+	}
+
+	// A TOTP code is accepted only once, even in another login
+	if _, err := m.complete(m.login(t), code); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a replayed TOTP code to be refused, got %v", err)
+	}
+}
+
+func TestMFARecoveryCode(t *testing.T) {
+	m := startMFATest(t)
+
+	code := strings.ToUpper(m.recoveryCodes[0])
+	if _, err := m.complete(m.login(t), code); err != nil {
+		t.Fatalf("CompleteMFALogin() error = %v", err)
+	}
+	if _, err := m.complete(m.login(t), code); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a used recovery code to be refused, got %v", err)
+	}
+	if _, err := m.complete(m.login(t), m.recoveryCodes[1]); err != nil {
+		t.Errorf("expected another recovery code to be accepted, got %v", err)
+	}
+}
+
+func TestMFAAttemptsLimit(t *testing.T) {
+	m := startMFATest(t)
+
+	token := m.login(t)
+	for i := 0; i < maxMFAAttempts; i++ {
+		if _, err := m.complete(token, "000000"); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("attempt %d: expected a wrong code to be refused, got %v", i+1, err)
+		}
+	}
+	if _, err := m.complete(token, m.nextCode(t)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected the MFA token to be discarded after %d wrong codes, got %v", maxMFAAttempts, err)
+	}
+}
+
+func TestMFAAttemptsLimitInParallel(t *testing.T) {
+	m := startMFATest(t)
+
+	token := m.login(t)
+	errs := make(chan error, 3*maxMFAAttempts)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.complete(token, "000000")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	checked := 0
+	for err := range errs {
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected a wrong code to be refused, got %v", err)
+		}
+		if err != nil && strings.HasSuffix(err.Error(), "invalid code") {
+			checked++
+		}
+	}
+	if checked > maxMFAAttempts {
+		t.Errorf("expected at most %d codes to be checked, got %d", maxMFAAttempts, checked)
+	}
+}
+
+func TestMFASettingsAttemptsLimit(t *testing.T) {
+	m := startMFATest(t)
+	ctx := context.Background()
+	userID := m.user.UserID
+
+	if _, err := m.mfa.RegenerateRecoveryCodes(ctx, userID, RecoveryCodesInput{Code: m.nextCode(t)}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected regenerating recovery codes to require the password, got %v", err)
+	}
+	codes, err := m.mfa.RegenerateRecoveryCodes(ctx, userID, RecoveryCodesInput{Password: testPassword, Code: m.nextCode(t)})
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes.Codes) != recoveryCodeCount {
+		t.Errorf("expected %d recovery codes, got %d", recoveryCodeCount, len(codes.Codes))
+	}
+
+	// The success reset the count, so the limit applies from here
+	for i := 0; i < maxMFAAttempts; i++ {
+		if _, err := m.mfa.RegenerateRecoveryCodes(ctx, userID, RecoveryCodesInput{Password: testPassword, Code: "000000"}); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("attempt %d: expected a wrong code to be refused, got %v", i+1, err)
+		}
+	}
+	if _, err := m.mfa.RegenerateRecoveryCodes(ctx, userID, RecoveryCodesInput{Password: testPassword, Code: m.nextCode(t)}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected further codes to be refused, got %v", err)
+	}
+	if err := m.mfa.DisableMFA(ctx, userID, DisableMFAInput{Password: testPassword, Code: m.nextCode(t)}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected the limit to apply to disabling two-factor authentication, got %v", err)
+	}
+	if status, err := m.mfa.Status(ctx, userID); err != nil || !status.Enabled {
+		t.Errorf("expected two-factor authentication to stay enabled, got %+v, %v", status, err)
+	}
+}
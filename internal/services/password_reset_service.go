@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/mailer"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/session"
+	"TestAlchemy/internal/tokens"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const passwordResetTTL = 30 * time.Minute
+
+type PasswordResetService struct {
+	db      database.Service
+	keydb   database.KeyDBService
+	session *session.Store
+	mailer  mailer.Mailer
+	audit   *AuditService
+	// now returns the current time. Tests replace it with a fake clock.
+	now func() time.Time
+}
+
+func NewPasswordResetService(db database.Service, keydb database.KeyDBService, sessionStore *session.Store, m mailer.Mailer, audit *AuditService) *PasswordResetService {
+	return &PasswordResetService{
+		db:      db,
+		keydb:   keydb,
+		session: sessionStore,
+		mailer:  m,
+		audit:   audit,
+		now:     time.Now,
+	}
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// passwordReset is a pending password reset. Only the hash of its token is
+// stored, as the key of the record.
+type passwordReset struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RequestReset emails a password reset token to the user with the given
+// address. Requesting a new token invalidates the previous one. Unknown
+// addresses are silently ignored, and failures only logged, so the
+// response does not tell whether an account exists.
+func (s *PasswordResetService) RequestReset(ctx context.Context, input ForgotPasswordInput) error {
+	var user models.User
+	if err := s.db.Read(ctx, &user, "email = ?", input.Email); err != nil {
+		return nil
+	}
+
+	if err := s.sendReset(ctx, &user); err != nil {
+		log.Printf("failed to send password reset to user %s: %v", user.UserID, err)
+		return nil
+	}
+	s.audit.Record(ctx, AuditEntry{
+		Action:     models.AuditPasswordResetRequest,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+	})
+	return nil
+}
+
+// sendReset stores a new password reset token for the user, replacing the
+// previous one, and emails it to them.
+func (s *PasswordResetService) sendReset(ctx context.Context, user *models.User) error {
+	token := tokens.Random(32)
+	reset := passwordReset{
+		UserID:    user.UserID,
+		ExpiresAt: s.now().Add(passwordResetTTL),
+	}
+	data, err := json.Marshal(reset)
+	if err != nil {
+		return fmt.Errorf("failed to marshal password reset: %v", err)
+	}
+
+	hash := tokens.Hash(token)
+	previous, err := s.keydb.Get(ctx, userPasswordResetKey(user.UserID))
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to get password reset: %v", err)
+	}
+	_, err = s.keydb.Client().TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, passwordResetKey(previous))
+		}
+		pipe.Set(ctx, passwordResetKey(hash), data, passwordResetTTL)
+		pipe.Set(ctx, userPasswordResetKey(user.UserID), hash, passwordResetTTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store password reset: %v", err)
+	}
+
+	if err := s.mailer.Send(ctx, resetMessage(user.Email, token, reset.ExpiresAt)); err != nil {
+		s.keydb.Delete(ctx, passwordResetKey(hash))
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a token sent by RequestReset.
+// A token can be used only once, and every session of the user is revoked.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, input ResetPasswordInput) error {
+	if err := validatePassword(input.Password); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	reset, err := s.consume(ctx, input.Token)
+	if err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", reset.UserID); err != nil {
+		return fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	if err := user.HashPassword(input.Password); err != nil {
+		return err
+	}
+	if err := s.db.Update(ctx, &user); err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	revoked, err := s.session.RevokeSessions(ctx, user.UserID, "")
+	if err != nil {
+		log.Printf("failed to revoke sessions of user %s: %v", user.UserID, err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditPasswordReset,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+		Metadata:   map[string]interface{}{"revoked_sessions": revoked},
+	})
+	return nil
+}
+
+// consume looks up a password reset token and deletes it, so that it can
+// be used only once.
+func (s *PasswordResetService) consume(ctx context.Context, token string) (*passwordReset, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: token is required", ErrInvalidInput)
+	}
+
+	data, err := takeKey(ctx, s.keydb, passwordResetKey(tokens.Hash(token)))
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password reset: %v", err)
+	}
+
+	var reset passwordReset
+	if err := json.Unmarshal([]byte(data), &reset); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal password reset: %v", err)
+	}
+	if s.now().After(reset.ExpiresAt) {
+		return nil, fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	s.keydb.Delete(ctx, userPasswordResetKey(reset.UserID))
+	return &reset, nil
+}
+
+func resetMessage(email, token string, expiresAt time.Time) mailer.Message {
+	return mailer.Message{
+		To:      []string{email},
+		Subject: "Reset your Test Alchemy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Test Alchemy account.\n\n"+
+			"To choose a new password, open:\n%s/reset-password?token=%s\n\n"+
+			"The link can be used once and expires on %s. If you did not ask for a reset, you can ignore this email.\n",
+			appBaseURL, url.QueryEscape(token), expiresAt.Format(time.RFC1123)),
+	}
+}
+
+func passwordResetKey(hash string) string {
+	return fmt.Sprintf("password_reset:%s", hash)
+}
+
+func userPasswordResetKey(userID uuid.UUID) string {
+	return fmt.Sprintf("password_reset:user:%s", userID)
+}
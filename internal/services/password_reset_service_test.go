@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/mailer"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/session"
+	"TestAlchemy/internal/tokens"
+	"github.com/google/uuid"
+)
+
+func TestResetMessage(t *testing.T) {
+	token := tokens.Random(32)
+	message := resetMessage("user@example.com", token, time.Now().Add(passwordResetTTL))
+
+	if len(message.To) != 1 || message.To[0] != "user@example.com" {
+		t.Errorf("unexpected recipients %v", message.To)
+	}
+	if !strings.Contains(message.Body, appBaseURL+"/reset-password?token="+token) {
+		t.Error("expected the body to link to the reset page with the token")
+	}
+}
+
+func TestPasswordResetKeys(t *testing.T) {
+	token := tokens.Random(32)
+	key := passwordResetKey(tokens.Hash(token))
+
+	if strings.Contains(key, token) {
+		t.Error("expected the key not to contain the token")
+	}
+	if key == userPasswordResetKey(uuid.New()) {
+		t.Error("expected token and user keys to differ")
+	}
+}
+
+// failingMailer is a Mailer that cannot deliver anything.
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("connection refused")
+}
+
+var resetTokenPattern = regexp.MustCompile(`/reset-password\?token=(\S+)`)
+
+// requestReset asks for a reset of the password of the user and returns
+// the token emailed to them.
+func requestReset(t *testing.T, s *PasswordResetService, outbox *mailer.Outbox, email string) string {
+	t.Helper()
+	if err := s.RequestReset(context.Background(), ForgotPasswordInput{Email: email}); err != nil {
+		t.Fatalf("RequestReset() error = %v", err)
+	}
+	message, ok := outbox.Last(email)
+	if !ok {
+		t.Fatal("expected a password reset email")
+	}
+	match := resetTokenPattern.FindStringSubmatch(message.Body)
+	if match == nil {
+		t.Fatalf("no token in the email %q", message.Body)
+	}
+	return match[1]
+}
+
+func TestPasswordReset(t *testing.T) {
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	store := newSessionStore(keydb)
+	outbox := mailer.NewOutbox("")
+	s := NewPasswordResetService(db, keydb, store, outbox, NewAuditService(db, NewProjectService(db)))
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	user := createUser(t, db)
+
+	for _, device := range []string{"laptop", "phone"} {
+		if _, err := store.CreateSession(ctx, user.UserID, session.Client{UserAgent: device}); err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+	}
+
+	token := requestReset(t, s, outbox, user.Email)
+	if err := s.ResetPassword(ctx, ResetPasswordInput{Token: token, Password: "weak"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a weak password to be refused, got %v", err)
+	}
+	if err := s.ResetPassword(ctx, ResetPasswordInput{Token: token, Password: "New-Secret-456"}); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+
+	var updated models.User
+	if err := db.Read(ctx, &updated, "user_id = ?", user.UserID); err != nil {
+		t.Fatal(err)
+	}
+	if !updated.ValidatePassword("New-Secret-456") {
+		t.Error("expected the new password to be set")
+	}
+	sessions, err := store.ListSessions(ctx, user.UserID, "")
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("expected every session to be revoked, got %d left", len(sessions))
+	}
+
+	if err := s.ResetPassword(ctx, ResetPasswordInput{Token: token, Password: "Other-Secret-789"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a used token to be refused, got %v", err)
+	}
+
+	// A new request replaces the previous token
+	first := requestReset(t, s, outbox, user.Email)
+	second := requestReset(t, s, outbox, user.Email)
+	if err := s.ResetPassword(ctx, ResetPasswordInput{Token: first, Password: "Other-Secret-789"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a replaced token to be refused, got %v", err)
+	}
+
+	now = now.Add(passwordResetTTL + time.Second)
+	if err := s.ResetPassword(ctx, ResetPasswordInput{Token: second, Password: "Other-Secret-789"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected an expired token to be refused, got %v", err)
+	}
+}
+
+func TestRequestResetDoesNotRevealAccounts(t *testing.T) {
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	outbox := mailer.NewOutbox("")
+	audit := NewAuditService(db, NewProjectService(db))
+	ctx := context.Background()
+	user := createUser(t, db)
+
+	s := NewPasswordResetService(db, keydb, newSessionStore(keydb), outbox, audit)
+	if err := s.RequestReset(ctx, ForgotPasswordInput{Email: "nobody-" + user.Email}); err != nil {
+		t.Errorf("expected unknown addresses to be ignored, got %v", err)
+	}
+	if len(outbox.Messages()) != 0 {
+		t.Error("expected no email for an unknown address")
+	}
+
+	s = NewPasswordResetService(db, keydb, newSessionStore(keydb), failingMailer{}, audit)
+	if err := s.RequestReset(ctx, ForgotPasswordInput{Email: user.Email}); err != nil {
+		t.Errorf("expected mail failures to be hidden, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ProjectService struct {
+	db database.Service
+}
+
+func NewProjectService(db database.Service) *ProjectService {
+	return &ProjectService{db: db}
+}
+
+type ProjectInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (s *ProjectService) ValidateProject(input ProjectInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len(name) > 255 {
+		return fmt.Errorf("%w: name must be at most 255 characters long", ErrInvalidInput)
+	}
+	return nil
+}
+
+// Authorize checks that the user is a member of the project with at least
+// the given role and returns the project. Projects the user is not a member
+// of are reported as not found so their existence is not revealed.
+func (s *ProjectService) Authorize(ctx context.Context, userID, projectID uuid.UUID, role string) (*models.Project, error) {
+	var project models.Project
+	err := s.db.Read(ctx, &project, "project_id = ?", projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: project", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %v", err)
+	}
+
+	var member models.ProjectMember
+	err = s.db.Read(ctx, &member, "project_id = ? AND user_id = ?", projectID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: project", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project member: %v", err)
+	}
+
+	if !member.HasRole(role) {
+		return nil, fmt.Errorf("%w: requires the %s role", ErrForbidden, role)
+	}
+	return &project, nil
+}
+
+// CreateProject creates a project with the user as its owner.
+func (s *ProjectService) CreateProject(ctx context.Context, userID uuid.UUID, input ProjectInput) (*models.Project, error) {
+	if err := s.ValidateProject(input); err != nil {
+		return nil, err
+	}
+
+	project := &models.Project{
+		ProjectID:   uuid.New(),
+		UserID:      userID,
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+	}
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ProjectMember{
+			ProjectID: project.ProjectID,
+			UserID:    userID,
+			Role:      models.RoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %v", err)
+	}
+
+	return project, nil
+}
+
+// ListProjects returns the projects the user is a member of, most recently updated first.
+func (s *ProjectService) ListProjects(ctx context.Context, userID uuid.UUID) ([]models.Project, error) {
+	var projects []models.Project
+	err := s.db.DB().WithContext(ctx).
+		Joins("JOIN project_members ON project_members.project_id = projects.project_id").
+		Where("project_members.user_id = ?", userID).
+		Order("projects.updated_at DESC").
+		Find(&projects).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %v", err)
+	}
+	return projects, nil
+}
+
+func (s *ProjectService) GetProject(ctx context.Context, userID, projectID uuid.UUID) (*models.Project, error) {
+	return s.Authorize(ctx, userID, projectID, models.RoleViewer)
+}
+
+func (s *ProjectService) UpdateProject(ctx context.Context, userID, projectID uuid.UUID, input ProjectInput) (*models.Project, error) {
+	if err := s.ValidateProject(input); err != nil {
+		return nil, err
+	}
+
+	project, err := s.Authorize(ctx, userID, projectID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	project.Name = strings.TrimSpace(input.Name)
+	project.Description = input.Description
+	if err := s.db.Update(ctx, project); err != nil {
+		return nil, fmt.Errorf("failed to update project: %v", err)
+	}
+
+	return project, nil
+}
+
+// DeleteProject soft deletes the project. Only owners can delete a project.
+func (s *ProjectService) DeleteProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	project, err := s.Authorize(ctx, userID, projectID, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(ctx, project); err != nil {
+		return fmt.Errorf("failed to delete project: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestProjectAccess(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	s := NewProjectService(db)
+	owner := createUser(t, db)
+	editor := createUser(t, db)
+	viewer := createUser(t, db)
+	outsider := createUser(t, db)
+	project := createProject(t, db, owner, map[*models.User]string{
+		editor: models.RoleEditor,
+		viewer: models.RoleViewer,
+	})
+	input := ProjectInput{Name: "Renamed"}
+
+	tests := []struct {
+		name      string
+		userID    uuid.UUID
+		projectID uuid.UUID
+		get       error
+		update    error
+		delete    error
+	}{
+		{"viewer", viewer.UserID, project.ProjectID, nil, ErrForbidden, ErrForbidden},
+		{"editor", editor.UserID, project.ProjectID, nil, nil, ErrForbidden},
+		{"non-member", outsider.UserID, project.ProjectID, ErrNotFound, ErrNotFound, ErrNotFound},
+		{"unknown project", owner.UserID, uuid.New(), ErrNotFound, ErrNotFound, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.GetProject(ctx, tt.userID, tt.projectID); !errors.Is(err, tt.get) {
+				t.Errorf("GetProject() expected %v, got %v", tt.get, err)
+			}
+			if _, err := s.UpdateProject(ctx, tt.userID, tt.projectID, input); !errors.Is(err, tt.update) {
+				t.Errorf("UpdateProject() expected %v, got %v", tt.update, err)
+			}
+			if err := s.DeleteProject(ctx, tt.userID, tt.projectID); !errors.Is(err, tt.delete) {
+				t.Errorf("DeleteProject() expected %v, got %v", tt.delete, err)
+			}
+		})
+	}
+
+	projects, err := s.ListProjects(ctx, outsider.UserID)
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(projects) != 0 {
+		t.Errorf("expected no projects for a non-member, got %d", len(projects))
+	}
+
+	if err := s.DeleteProject(ctx, owner.UserID, project.ProjectID); err != nil {
+		t.Fatalf("DeleteProject() error = %v", err)
+	}
+	if _, err := s.GetProject(ctx, owner.UserID, project.ProjectID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a deleted project to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+// reportRuns is how many of the latest runs the run report covers.
+const reportRuns = 20
+
+type ReportService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewReportService(db database.Service, projects *ProjectService) *ReportService {
+	return &ReportService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+// RunSummary counts the test cases of a run by status. PassRate is the
+// share of the run's test cases that passed.
+type RunSummary struct {
+	TestRunID  uuid.UUID  `json:"test_run_id"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ClosedAt   *time.Time `json:"closed_at"`
+	Total      int        `json:"total"`
+	Passed     int        `json:"passed"`
+	Failed     int        `json:"failed"`
+	Blocked    int        `json:"blocked"`
+	Skipped    int        `json:"skipped"`
+	InProgress int        `json:"in_progress"`
+	Untested   int        `json:"untested"`
+	PassRate   float64    `json:"pass_rate"`
+}
+
+// FlakyCase is a test case that both passed and failed across runs.
+type FlakyCase struct {
+	TestCaseID uuid.UUID `json:"test_case_id"`
+	Title      string    `json:"title"`
+	Runs       int       `json:"runs"`
+	Passed     int       `json:"passed"`
+	Failed     int       `json:"failed"`
+	LastRunAt  time.Time `json:"last_run_at"`
+}
+
+// TagCoverage counts the test cases with a tag that were executed in at
+// least one run.
+type TagCoverage struct {
+	Tag      string  `json:"tag"`
+	Total    int     `json:"total"`
+	Executed int     `json:"executed"`
+	Coverage float64 `json:"coverage"`
+}
+
+// UnexecutedCase is a test case that has no result in any run.
+type UnexecutedCase struct {
+	TestCaseID uuid.UUID `json:"test_case_id"`
+	Title      string    `json:"title"`
+	Priority   string    `json:"priority"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProjectReport gathers every report of a project for its dashboard.
+type ProjectReport struct {
+	Runs          []RunSummary     `json:"runs"`
+	Flaky         []FlakyCase      `json:"flaky"`
+	Coverage      []TagCoverage    `json:"coverage"`
+	NeverExecuted []UnexecutedCase `json:"never_executed"`
+}
+
+// ProjectReport returns every report of a project.
+func (s *ReportService) ProjectReport(ctx context.Context, userID, projectID uuid.UUID) (*ProjectReport, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	var report ProjectReport
+	var err error
+	if report.Runs, err = s.runSummaries(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if report.Flaky, err = s.flakyCases(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if report.Coverage, err = s.tagCoverage(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if report.NeverExecuted, err = s.neverExecuted(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RunSummaries returns the status counts of the latest runs, newest first.
+func (s *ReportService) RunSummaries(ctx context.Context, userID, projectID uuid.UUID) ([]RunSummary, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.runSummaries(ctx, projectID)
+}
+
+// FlakyCases returns the test cases that both passed and failed across
+// runs, the most unstable first.
+func (s *ReportService) FlakyCases(ctx context.Context, userID, projectID uuid.UUID) ([]FlakyCase, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.flakyCases(ctx, projectID)
+}
+
+// TagCoverage returns, for every tag of the project, how many of its test
+// cases were ever executed.
+func (s *ReportService) TagCoverage(ctx context.Context, userID, projectID uuid.UUID) ([]TagCoverage, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.tagCoverage(ctx, projectID)
+}
+
+// NeverExecuted returns the test cases without any result, oldest first.
+func (s *ReportService) NeverExecuted(ctx context.Context, userID, projectID uuid.UUID) ([]UnexecutedCase, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.neverExecuted(ctx, projectID)
+}
+
+func (s *ReportService) runSummaries(ctx context.Context, projectID uuid.UUID) ([]RunSummary, error) {
+	summaries := []RunSummary{}
+	err := s.db.DB().WithContext(ctx).
+		Table("test_runs").
+		Select(`test_runs.test_run_id, test_runs.name, test_runs.status, test_runs.created_at, test_runs.closed_at,
+			COUNT(test_run_cases.test_run_case_id) AS total,
+			COALESCE(SUM(test_run_cases.status = ?), 0) AS passed,
+			COALESCE(SUM(test_run_cases.status = ?), 0) AS failed,
+			COALESCE(SUM(test_run_cases.status = ?), 0) AS blocked,
+			COALESCE(SUM(test_run_cases.status = ?), 0) AS skipped,
+			COALESCE(SUM(test_run_cases.status = ?), 0) AS in_progress,
+			COALESCE(SUM(test_run_cases.status = ?), 0) AS untested`,
+			models.ResultPassed, models.ResultFailed, models.ResultBlocked, models.ResultSkipped,
+			models.ResultInProgress, models.ResultUntested).
+		Joins("LEFT JOIN test_run_cases ON test_run_cases.test_run_id = test_runs.test_run_id").
+		Where("test_runs.project_id = ?", projectID).
+		Group("test_runs.test_run_id, test_runs.name, test_runs.status, test_runs.created_at, test_runs.closed_at").
+		Order("test_runs.created_at DESC").
+		Limit(reportRuns).
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise runs: %v", err)
+	}
+
+	for i := range summaries {
+		summaries[i].PassRate = ratio(summaries[i].Passed, summaries[i].Total)
+	}
+	return summaries, nil
+}
+
+func (s *ReportService) flakyCases(ctx context.Context, projectID uuid.UUID) ([]FlakyCase, error) {
+	flaky := []FlakyCase{}
+	err := s.db.DB().WithContext(ctx).
+		Table("test_run_cases").
+		Select(`test_cases.test_case_id, test_cases.title,
+			COUNT(*) AS runs,
+			SUM(test_run_cases.status = ?) AS passed,
+			SUM(test_run_cases.status = ?) AS failed,
+			MAX(test_runs.created_at) AS last_run_at`,
+			models.ResultPassed, models.ResultFailed).
+		Joins("JOIN test_runs ON test_runs.test_run_id = test_run_cases.test_run_id").
+		Joins("JOIN test_cases ON test_cases.test_case_id = test_run_cases.test_case_id AND test_cases.deleted_at IS NULL").
+		Where("test_runs.project_id = ? AND test_run_cases.status IN ?", projectID, []string{models.ResultPassed, models.ResultFailed}).
+		Group("test_cases.test_case_id, test_cases.title").
+		Having("passed > 0 AND failed > 0").
+		Order("LEAST(passed, failed) DESC, last_run_at DESC").
+		Scan(&flaky).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find flaky test cases: %v", err)
+	}
+	return flaky, nil
+}
+
+func (s *ReportService) tagCoverage(ctx context.Context, projectID uuid.UUID) ([]TagCoverage, error) {
+	coverage := []TagCoverage{}
+	err := s.db.DB().WithContext(ctx).
+		Table("tags").
+		Select(`tags.name AS tag,
+			COUNT(DISTINCT test_cases.test_case_id) AS total,
+			COUNT(DISTINCT CASE WHEN test_run_cases.status <> ? THEN test_cases.test_case_id END) AS executed`,
+			models.ResultUntested).
+		Joins("JOIN test_case_tags ON test_case_tags.tag_id = tags.tag_id").
+		Joins("JOIN test_cases ON test_cases.test_case_id = test_case_tags.test_case_id AND test_cases.deleted_at IS NULL").
+		Joins("LEFT JOIN test_run_cases ON test_run_cases.test_case_id = test_cases.test_case_id").
+		Where("tags.project_id = ?", projectID).
+		Group("tags.tag_id, tags.name").
+		Order("tags.name ASC").
+		Scan(&coverage).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute tag coverage: %v", err)
+	}
+
+	for i := range coverage {
+		coverage[i].Coverage = ratio(coverage[i].Executed, coverage[i].Total)
+	}
+	return coverage, nil
+}
+
+func (s *ReportService) neverExecuted(ctx context.Context, projectID uuid.UUID) ([]UnexecutedCase, error) {
+	cases := []UnexecutedCase{}
+	err := s.db.DB().WithContext(ctx).
+		Model(&models.TestCase{}).
+		Select("test_case_id, title, priority, status, created_at").
+		Where("project_id = ?", projectID).
+		Where("NOT EXISTS (SELECT 1 FROM test_run_cases WHERE test_run_cases.test_case_id = test_cases.test_case_id AND test_run_cases.status <> ?)",
+			models.ResultUntested).
+		Order("created_at ASC").
+		Scan(&cases).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find never executed test cases: %v", err)
+	}
+	return cases, nil
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
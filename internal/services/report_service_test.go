@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestRatio(t *testing.T) {
+	if got := ratio(1, 4); got != 0.25 {
+		t.Errorf("ratio(1, 4) = %v, want 0.25", got)
+	}
+	if got := ratio(0, 0); got != 0 {
+		t.Errorf("ratio(0, 0) = %v, want 0", got)
+	}
+}
+
+func TestProjectReport(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	testCases := NewTestCaseService(db, projects)
+	runs := NewRunService(db, projects)
+	reports := NewReportService(db, projects)
+	owner := createUser(t, db)
+	project := createProject(t, db, owner, nil)
+
+	ids := map[string]uuid.UUID{}
+	create := func(title string, tags ...string) {
+		t.Helper()
+		testCase, err := testCases.CreateTestCase(ctx, owner.UserID, project.ProjectID, TestCaseInput{Title: title, Tags: tags})
+		if err != nil {
+			t.Fatalf("CreateTestCase() error = %v", err)
+		}
+		ids[title] = testCase.TestCaseID
+	}
+	create("A", "api")
+	create("B", "api", "ui")
+	create("C", "ui")
+	create("D")
+	create("E", "smoke")
+
+	// run starts a run of every test case and records the given results.
+	// Test cases without a result stay untested.
+	run := func(name string, results map[string]string) uuid.UUID {
+		t.Helper()
+		testRun, err := runs.StartRun(ctx, owner.UserID, project.ProjectID, StartRunInput{Name: name})
+		if err != nil {
+			t.Fatalf("StartRun() error = %v", err)
+		}
+		for _, runCase := range testRun.Cases {
+			for title, status := range results {
+				if ids[title] != runCase.TestCaseID {
+					continue
+				}
+				if _, err := runs.RecordResult(ctx, owner.UserID, project.ProjectID, testRun.TestRunID, runCase.TestRunCaseID, ResultInput{Status: status}); err != nil {
+					t.Fatalf("RecordResult() error = %v", err)
+				}
+			}
+		}
+		return testRun.TestRunID
+	}
+	first := run("Run 1", map[string]string{"A": models.ResultPassed, "B": models.ResultFailed, "D": models.ResultBlocked, "E": models.ResultPassed})
+	run("Run 2", map[string]string{"A": models.ResultFailed, "B": models.ResultFailed, "E": models.ResultFailed})
+	run("Run 3", map[string]string{"E": models.ResultPassed})
+	run("Run 4", map[string]string{"E": models.ResultFailed})
+
+	// Created after the runs, and deleted: neither is reported
+	create("F")
+	create("G")
+	if err := testCases.DeleteTestCase(ctx, owner.UserID, project.ProjectID, ids["G"]); err != nil {
+		t.Fatalf("DeleteTestCase() error = %v", err)
+	}
+
+	report, err := reports.ProjectReport(ctx, owner.UserID, project.ProjectID)
+	if err != nil {
+		t.Fatalf("ProjectReport() error = %v", err)
+	}
+
+	t.Run("runs", func(t *testing.T) {
+		if len(report.Runs) != 4 {
+			t.Fatalf("expected 4 runs, got %d", len(report.Runs))
+		}
+		for _, summary := range report.Runs {
+			if summary.TestRunID != first {
+				continue
+			}
+			if summary.Total != 5 || summary.Passed != 2 || summary.Failed != 1 || summary.Blocked != 1 || summary.Untested != 1 || summary.PassRate != 0.4 {
+				t.Errorf("unexpected summary of the first run %+v", summary)
+			}
+		}
+	})
+
+	t.Run("flaky", func(t *testing.T) {
+		// E passed and failed twice each, A once each; B only failed
+		if len(report.Flaky) != 2 {
+			t.Fatalf("expected 2 flaky test cases, got %+v", report.Flaky)
+		}
+		if e := report.Flaky[0]; e.TestCaseID != ids["E"] || e.Runs != 4 || e.Passed != 2 || e.Failed != 2 {
+			t.Errorf("expected E to be the most unstable, got %+v", e)
+		}
+		if a := report.Flaky[1]; a.TestCaseID != ids["A"] || a.Runs != 2 || a.Passed != 1 || a.Failed != 1 {
+			t.Errorf("expected A to come next, got %+v", a)
+		}
+	})
+
+	t.Run("coverage", func(t *testing.T) {
+		// C, the other test case tagged ui, was left untested in every run
+		want := []TagCoverage{
+			{Tag: "api", Total: 2, Executed: 2, Coverage: 1},
+			{Tag: "smoke", Total: 1, Executed: 1, Coverage: 1},
+			{Tag: "ui", Total: 2, Executed: 1, Coverage: 0.5},
+		}
+		if len(report.Coverage) != len(want) {
+			t.Fatalf("expected coverage %+v, got %+v", want, report.Coverage)
+		}
+		for i := range want {
+			if report.Coverage[i] != want[i] {
+				t.Errorf("expected %+v, got %+v", want[i], report.Coverage[i])
+			}
+		}
+	})
+
+	t.Run("never executed", func(t *testing.T) {
+		got := map[uuid.UUID]bool{}
+		for _, testCase := range report.NeverExecuted {
+			got[testCase.TestCaseID] = true
+		}
+		if len(got) != 2 || !got[ids["C"]] || !got[ids["F"]] {
+			t.Errorf("expected C and F only, got %+v", report.NeverExecuted)
+		}
+	})
+}
@@ -0,0 +1,436 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/diff"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Changes of a step between two revisions.
+const (
+	StepAdded    = "added"
+	StepRemoved  = "removed"
+	StepModified = "modified"
+)
+
+type RevisionService struct {
+	db        database.Service
+	testCases *TestCaseService
+}
+
+func NewRevisionService(db database.Service, testCases *TestCaseService) *RevisionService {
+	return &RevisionService{
+		db:        db,
+		testCases: testCases,
+	}
+}
+
+type RevisionPage struct {
+	Items []models.TestCaseRevision `json:"items"`
+	database.Page
+}
+
+// FieldChange is a field that differs between two revisions. Diff holds the
+// word diff of free text fields.
+type FieldChange struct {
+	Field string    `json:"field"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Diff  []diff.Op `json:"diff,omitempty"`
+}
+
+// StepChange is a step that was added, removed, moved or edited between two
+// revisions. Positions are nil on the side where the step does not exist.
+type StepChange struct {
+	TestStepID   uuid.UUID     `json:"test_step_id"`
+	Change       string        `json:"change"`
+	FromPosition *int          `json:"from_position"`
+	ToPosition   *int          `json:"to_position"`
+	Fields       []FieldChange `json:"fields"`
+}
+
+type RevisionDiff struct {
+	TestCaseID uuid.UUID     `json:"test_case_id"`
+	From       int           `json:"from"`
+	To         int           `json:"to"`
+	Fields     []FieldChange `json:"fields"`
+	Steps      []StepChange  `json:"steps"`
+}
+
+// ListRevisions returns the revisions of a test case, newest first, without
+// their steps.
+func (s *RevisionService) ListRevisions(ctx context.Context, userID, projectID, testCaseID uuid.UUID, cursor string, limit int) (*RevisionPage, error) {
+	if _, err := s.testCases.GetTestCase(ctx, userID, projectID, testCaseID); err != nil {
+		return nil, err
+	}
+
+	result := &RevisionPage{Items: []models.TestCaseRevision{}}
+	page, err := s.db.List(ctx, &result.Items, database.ListQuery{
+		Scopes: []func(*gorm.DB) *gorm.DB{func(db *gorm.DB) *gorm.DB {
+			return db.Where("test_case_id = ?", testCaseID)
+		}},
+		SortField: "number",
+		SortDesc:  true,
+		Cursor:    cursor,
+		Limit:     limit,
+	})
+	if errors.Is(err, database.ErrInvalidQuery) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %v", err)
+	}
+	result.Page = *page
+	return result, nil
+}
+
+// GetRevision returns a revision of a test case with its steps.
+func (s *RevisionService) GetRevision(ctx context.Context, userID, projectID, testCaseID uuid.UUID, number int) (*models.TestCaseRevision, error) {
+	if _, err := s.testCases.GetTestCase(ctx, userID, projectID, testCaseID); err != nil {
+		return nil, err
+	}
+	return getRevision(s.db.DB().WithContext(ctx), testCaseID, number)
+}
+
+// DiffRevisions compares two revisions of a test case field by field.
+func (s *RevisionService) DiffRevisions(ctx context.Context, userID, projectID, testCaseID uuid.UUID, from, to int) (*RevisionDiff, error) {
+	if _, err := s.testCases.GetTestCase(ctx, userID, projectID, testCaseID); err != nil {
+		return nil, err
+	}
+
+	db := s.db.DB().WithContext(ctx)
+	a, err := getRevision(db, testCaseID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := getRevision(db, testCaseID, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffRevisions(a, b), nil
+}
+
+// RestoreRevision brings a test case and its steps back to the state of a
+// revision. The restore is recorded as a new revision, so the history
+// between the two is kept.
+func (s *RevisionService) RestoreRevision(ctx context.Context, userID, projectID, testCaseID uuid.UUID, number int) (*models.TestCase, error) {
+	testCase, err := s.testCases.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		revision, err := getRevision(tx, testCaseID, number)
+		if err != nil {
+			return err
+		}
+		return reviseTestCase(tx, testCaseID, userID, &number, func() error {
+			return restoreRevision(tx, testCase, revision)
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to restore revision: %w", err)
+	}
+
+	return s.testCases.getTestCase(ctx, projectID, testCaseID)
+}
+
+func getRevision(db *gorm.DB, testCaseID uuid.UUID, number int) (*models.TestCaseRevision, error) {
+	var revision models.TestCaseRevision
+	err := db.
+		Preload("Steps", orderSteps).
+		Where("test_case_id = ? AND number = ?", testCaseID, number).
+		First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: revision", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %v", err)
+	}
+	return &revision, nil
+}
+
+// restoreRevision overwrites the fields, tags and steps of a test case with
+// those of a revision. Steps keep their IDs, so attachments of a step that
+// still exists stay linked to it.
+func restoreRevision(tx *gorm.DB, testCase *models.TestCase, revision *models.TestCaseRevision) error {
+	testCase.Title = revision.Title
+	testCase.Description = revision.Description
+	testCase.Preconditions = revision.Preconditions
+	testCase.UserLevel = revision.UserLevel
+	testCase.Priority = revision.Priority
+	testCase.Status = revision.Status
+	if err := tx.Omit(clause.Associations).Save(testCase).Error; err != nil {
+		return err
+	}
+
+	tags, err := resolveTags(tx, testCase.ProjectID, splitTagNames(revision.Tags))
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(testCase).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+
+	keep := make([]uuid.UUID, 0, len(revision.Steps))
+	for _, step := range revision.Steps {
+		keep = append(keep, step.TestStepID)
+	}
+	removed := tx.Where("test_case_id = ?", testCase.TestCaseID)
+	if len(keep) > 0 {
+		removed = removed.Where("test_step_id NOT IN ?", keep)
+	}
+	if err := removed.Delete(&models.TestStep{}).Error; err != nil {
+		return err
+	}
+	for _, step := range revision.Steps {
+		restored := models.TestStep{
+			TestStepID:     step.TestStepID,
+			TestCaseID:     testCase.TestCaseID,
+			Position:       step.Position,
+			Action:         step.Action,
+			ExpectedResult: step.ExpectedResult,
+		}
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"position", "action", "expected_result", "updated_at"}),
+		}).Create(&restored).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reviseTestCase applies a change to an existing test case and records the
+// result as a new revision. Test cases created before revisions existed get
+// their current state recorded first, so the change can be diffed and
+// reverted.
+func reviseTestCase(tx *gorm.DB, testCaseID, userID uuid.UUID, restoredFrom *int, change func() error) error {
+	return reviseTestCases(tx, []uuid.UUID{testCaseID}, userID, restoredFrom, change)
+}
+
+// reviseTestCases is reviseTestCase for a change to several test cases at
+// once, such as renaming a tag they share.
+func reviseTestCases(tx *gorm.DB, testCaseIDs []uuid.UUID, userID uuid.UUID, restoredFrom *int, change func() error) error {
+	for _, testCaseID := range testCaseIDs {
+		if err := recordBaseline(tx, testCaseID); err != nil {
+			return err
+		}
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+	for _, testCaseID := range testCaseIDs {
+		if err := recordRevision(tx, testCaseID, userID, restoredFrom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordBaseline locks a test case for the change about to be made, and
+// records its current state as its first revision if it has none yet.
+func recordBaseline(tx *gorm.DB, testCaseID uuid.UUID) error {
+	var testCase models.TestCase
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Steps", orderSteps).
+		Preload("Tags", orderTags).
+		Where("test_case_id = ?", testCaseID).
+		First(&testCase).Error
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.TestCaseRevision{}).Where("test_case_id = ?", testCaseID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	baseline := snapshotRevision(&testCase, 1, testCase.AuthorID)
+	baseline.CreatedAt = testCase.UpdatedAt
+	return tx.Create(&baseline).Error
+}
+
+// recordRevision snapshots a test case as its next revision. Nothing is
+// recorded when the case did not change since the latest revision.
+func recordRevision(tx *gorm.DB, testCaseID, userID uuid.UUID, restoredFrom *int) error {
+	var testCase models.TestCase
+	err := tx.
+		Preload("Steps", orderSteps).
+		Preload("Tags", orderTags).
+		Where("test_case_id = ?", testCaseID).
+		First(&testCase).Error
+	if err != nil {
+		return err
+	}
+
+	var latest models.TestCaseRevision
+	err = tx.Preload("Steps", orderSteps).
+		Where("test_case_id = ?", testCaseID).
+		Order("number DESC").
+		First(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	revision := snapshotRevision(&testCase, latest.Number+1, userID)
+	revision.RestoredFrom = restoredFrom
+	if latest.Number > 0 && !diffRevisions(&latest, &revision).changed() {
+		return nil
+	}
+	return tx.Create(&revision).Error
+}
+
+func snapshotRevision(testCase *models.TestCase, number int, authorID uuid.UUID) models.TestCaseRevision {
+	revision := models.TestCaseRevision{
+		TestCaseRevisionID: uuid.New(),
+		TestCaseID:         testCase.TestCaseID,
+		Number:             number,
+		AuthorID:           authorID,
+		Title:              testCase.Title,
+		Description:        testCase.Description,
+		Preconditions:      testCase.Preconditions,
+		UserLevel:          testCase.UserLevel,
+		Priority:           testCase.Priority,
+		Status:             testCase.Status,
+		Tags:               joinTagNames(testCase.Tags),
+		Steps:              []models.TestCaseRevisionStep{},
+		CreatedAt:          time.Now(),
+	}
+	for _, step := range testCase.Steps {
+		revision.Steps = append(revision.Steps, models.TestCaseRevisionStep{
+			TestCaseRevisionStepID: uuid.New(),
+			TestCaseRevisionID:     revision.TestCaseRevisionID,
+			TestStepID:             step.TestStepID,
+			Position:               step.Position,
+			Action:                 step.Action,
+			ExpectedResult:         step.ExpectedResult,
+		})
+	}
+	return revision
+}
+
+func diffRevisions(from, to *models.TestCaseRevision) *RevisionDiff {
+	result := &RevisionDiff{
+		TestCaseID: to.TestCaseID,
+		From:       from.Number,
+		To:         to.Number,
+		Fields: diffFields([]fieldPair{
+			{"title", from.Title, to.Title, true},
+			{"description", from.Description, to.Description, true},
+			{"preconditions", from.Preconditions, to.Preconditions, true},
+			{"user_level", from.UserLevel, to.UserLevel, false},
+			{"priority", from.Priority, to.Priority, false},
+			{"status", from.Status, to.Status, false},
+			{"tags", from.Tags, to.Tags, false},
+		}),
+		Steps: []StepChange{},
+	}
+
+	before := make(map[uuid.UUID]*models.TestCaseRevisionStep, len(from.Steps))
+	for i := range from.Steps {
+		before[from.Steps[i].TestStepID] = &from.Steps[i]
+	}
+	for i := range to.Steps {
+		step := &to.Steps[i]
+		old, ok := before[step.TestStepID]
+		delete(before, step.TestStepID)
+		if !ok {
+			result.Steps = append(result.Steps, StepChange{
+				TestStepID: step.TestStepID,
+				Change:     StepAdded,
+				ToPosition: &step.Position,
+				Fields: diffFields([]fieldPair{
+					{"action", "", step.Action, true},
+					{"expected_result", "", step.ExpectedResult, true},
+				}),
+			})
+			continue
+		}
+		fields := diffFields([]fieldPair{
+			{"action", old.Action, step.Action, true},
+			{"expected_result", old.ExpectedResult, step.ExpectedResult, true},
+		})
+		if len(fields) > 0 || old.Position != step.Position {
+			result.Steps = append(result.Steps, StepChange{
+				TestStepID:   step.TestStepID,
+				Change:       StepModified,
+				FromPosition: &old.Position,
+				ToPosition:   &step.Position,
+				Fields:       fields,
+			})
+		}
+	}
+
+	removed := make([]*models.TestCaseRevisionStep, 0, len(before))
+	for _, step := range before {
+		removed = append(removed, step)
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Position < removed[j].Position })
+	for _, step := range removed {
+		result.Steps = append(result.Steps, StepChange{
+			TestStepID:   step.TestStepID,
+			Change:       StepRemoved,
+			FromPosition: &step.Position,
+			Fields: diffFields([]fieldPair{
+				{"action", step.Action, "", true},
+				{"expected_result", step.ExpectedResult, "", true},
+			}),
+		})
+	}
+	return result
+}
+
+func (d *RevisionDiff) changed() bool {
+	return len(d.Fields) > 0 || len(d.Steps) > 0
+}
+
+type fieldPair struct {
+	field    string
+	from, to string
+	text     bool
+}
+
+// diffFields returns the fields whose values differ, with a word diff for
+// free text fields.
+func diffFields(pairs []fieldPair) []FieldChange {
+	changes := []FieldChange{}
+	for _, pair := range pairs {
+		if pair.from == pair.to {
+			continue
+		}
+		change := FieldChange{Field: pair.field, From: pair.from, To: pair.to}
+		if pair.text {
+			change.Diff = diff.Words(pair.from, pair.to)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// splitTagNames reverses joinTagNames.
+func splitTagNames(tags string) []string {
+	names := []string{}
+	for _, name := range strings.Split(tags, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	kept, edited, dropped, added := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	from := &models.TestCaseRevision{
+		Number:   1,
+		Title:    "Login",
+		Priority: models.PriorityLow,
+		Tags:     "auth",
+		Steps: []models.TestCaseRevisionStep{
+			{TestStepID: kept, Position: 1, Action: "Open the page"},
+			{TestStepID: edited, Position: 2, Action: "Submit", ExpectedResult: "Logged in"},
+			{TestStepID: dropped, Position: 3, Action: "Log out"},
+		},
+	}
+	to := &models.TestCaseRevision{
+		Number:   2,
+		Title:    "Login",
+		Priority: models.PriorityHigh,
+		Tags:     "auth",
+		Steps: []models.TestCaseRevisionStep{
+			{TestStepID: kept, Position: 1, Action: "Open the page"},
+			{TestStepID: added, Position: 2, Action: "Fill in the form"},
+			{TestStepID: edited, Position: 3, Action: "Submit", ExpectedResult: "Dashboard shown"},
+		},
+	}
+
+	result := diffRevisions(from, to)
+	if result.From != 1 || result.To != 2 {
+		t.Errorf("expected diff from 1 to 2, got %d to %d", result.From, result.To)
+	}
+	if len(result.Fields) != 1 || result.Fields[0].Field != "priority" || result.Fields[0].Diff != nil {
+		t.Fatalf("expected only a priority change without word diff, got %+v", result.Fields)
+	}
+
+	want := []struct {
+		id     uuid.UUID
+		change string
+		fields int
+	}{
+		{added, StepAdded, 1},
+		{edited, StepModified, 1},
+		{dropped, StepRemoved, 1},
+	}
+	if len(result.Steps) != len(want) {
+		t.Fatalf("expected %d step changes, got %+v", len(want), result.Steps)
+	}
+	for i, w := range want {
+		step := result.Steps[i]
+		if step.TestStepID != w.id || step.Change != w.change || len(step.Fields) != w.fields {
+			t.Errorf("step change %d: got %+v, want %s of %s with %d fields", i, step, w.change, w.id, w.fields)
+		}
+	}
+	if moved := result.Steps[1]; *moved.FromPosition != 2 || *moved.ToPosition != 3 {
+		t.Errorf("expected edited step to move from 2 to 3, got %d to %d", *moved.FromPosition, *moved.ToPosition)
+	}
+	if result.Steps[0].FromPosition != nil || result.Steps[2].ToPosition != nil {
+		t.Error("expected no position on the side where the step does not exist")
+	}
+
+	if diffRevisions(from, from).changed() {
+		t.Error("expected no changes between a revision and itself")
+	}
+}
+
+func TestSplitTagNames(t *testing.T) {
+	tags := []models.Tag{{Name: "auth"}, {Name: "smoke test"}}
+	names := splitTagNames(joinTagNames(tags))
+	if len(names) != 2 || names[0] != "auth" || names[1] != "smoke test" {
+		t.Errorf("expected tag names to round trip, got %q", names)
+	}
+	if names := splitTagNames(""); len(names) != 0 {
+		t.Errorf("expected no tag names, got %q", names)
+	}
+}
+
+func TestTagChangesRecordRevisions(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	members := NewMemberService(db, projects)
+	testCases := NewTestCaseService(db, projects)
+	tags := NewTagService(db, projects)
+
+	owner := createUser(t, db)
+	editor := createUser(t, db)
+	project, err := projects.CreateProject(ctx, owner.UserID, ProjectInput{Name: "Tagged"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	if _, err := members.AddMember(ctx, owner.UserID, project.ProjectID, AddMemberInput{Email: editor.Email, Role: models.RoleEditor}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+	testCase, err := testCases.CreateTestCase(ctx, owner.UserID, project.ProjectID, TestCaseInput{Title: "Login", Tags: []string{"api", "smoke"}})
+	if err != nil {
+		t.Fatalf("CreateTestCase() error = %v", err)
+	}
+	untagged, err := testCases.CreateTestCase(ctx, owner.UserID, project.ProjectID, TestCaseInput{Title: "Logout"})
+	if err != nil {
+		t.Fatalf("CreateTestCase() error = %v", err)
+	}
+	tagIDs := map[string]uuid.UUID{}
+	for _, tag := range testCase.Tags {
+		tagIDs[tag.Name] = tag.TagID
+	}
+
+	latest := func(testCaseID uuid.UUID) models.TestCaseRevision {
+		t.Helper()
+		var revision models.TestCaseRevision
+		if err := db.DB().Where("test_case_id = ?", testCaseID).Order("number DESC").First(&revision).Error; err != nil {
+			t.Fatalf("failed to get the latest revision: %v", err)
+		}
+		return revision
+	}
+	check := func(step string, number int, authorID uuid.UUID, tags string) {
+		t.Helper()
+		revision := latest(testCase.TestCaseID)
+		if revision.Number != number || revision.AuthorID != authorID || revision.Tags != tags {
+			t.Errorf("after %s: expected revision %d by %s with tags %q, got revision %d by %s with tags %q",
+				step, number, authorID, tags, revision.Number, revision.AuthorID, revision.Tags)
+		}
+	}
+
+	if _, err := tags.RenameTag(ctx, editor.UserID, project.ProjectID, tagIDs["smoke"], TagInput{Name: "regression"}); err != nil {
+		t.Fatalf("RenameTag() error = %v", err)
+	}
+	check("renaming", 2, editor.UserID, "api, regression")
+
+	if _, err := tags.MergeTags(ctx, editor.UserID, project.ProjectID, tagIDs["smoke"], []uuid.UUID{tagIDs["api"]}); err != nil {
+		t.Fatalf("MergeTags() error = %v", err)
+	}
+	check("merging", 3, editor.UserID, "regression")
+
+	if err := tags.DeleteTag(ctx, owner.UserID, project.ProjectID, tagIDs["smoke"]); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	check("deleting", 4, owner.UserID, "")
+
+	if revision := latest(untagged.TestCaseID); revision.Number != 1 {
+		t.Errorf("expected untagged test cases to keep a single revision, got %d", revision.Number)
+	}
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RunService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewRunService(db database.Service, projects *ProjectService) *RunService {
+	return &RunService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+// StartRunInput selects the test cases of a run: the listed test cases when
+// TestCaseIDs is set, otherwise the cases with the given tags, or every
+// test case of the project when neither is set. Tags stand in for suites.
+type StartRunInput struct {
+	Name        string      `json:"name"`
+	TestCaseIDs []uuid.UUID `json:"test_case_ids"`
+	Tags        []string    `json:"tags"`
+	MatchAll    bool        `json:"match_all"`
+}
+
+type ResultInput struct {
+	// StepID is the snapshot step the result is for. It is only omitted for
+	// test cases without steps.
+	StepID     *uuid.UUID `json:"step_id"`
+	Status     string     `json:"status"`
+	Notes      string     `json:"notes"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+type RunPage struct {
+	Items []models.TestRun `json:"items"`
+	database.Page
+}
+
+// ListRuns returns the runs of a project, newest first, without their cases.
+func (s *RunService) ListRuns(ctx context.Context, userID, projectID uuid.UUID, cursor string, limit int) (*RunPage, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	result := &RunPage{Items: []models.TestRun{}}
+	page, err := s.db.List(ctx, &result.Items, database.ListQuery{
+		Scopes: []func(*gorm.DB) *gorm.DB{func(db *gorm.DB) *gorm.DB {
+			return db.Where("project_id = ?", projectID)
+		}},
+		SortField: "created_at",
+		SortDesc:  true,
+		Cursor:    cursor,
+		Limit:     limit,
+	})
+	if errors.Is(err, database.ErrInvalidQuery) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %v", err)
+	}
+	result.Page = *page
+	return result, nil
+}
+
+// StartRun snapshots the selected test cases into a new open run.
+func (s *RunService) StartRun(ctx context.Context, userID, projectID uuid.UUID, input StartRunInput) (*models.TestRun, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if len(input.Name) > 255 {
+		return nil, fmt.Errorf("%w: name must be at most 255 characters long", ErrInvalidInput)
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	query := s.db.DB().WithContext(ctx).
+		Preload("Steps", orderSteps).
+		Preload("Tags", orderTags).
+		Where("project_id = ?", projectID).
+		Order("created_at ASC")
+	if len(input.TestCaseIDs) > 0 {
+		query = query.Where("test_case_id IN ?", input.TestCaseIDs)
+	} else {
+		query = query.Scopes(withTags(projectID, input.Tags, input.MatchAll))
+	}
+	var testCases []models.TestCase
+	if err := query.Find(&testCases).Error; err != nil {
+		return nil, fmt.Errorf("failed to select test cases: %v", err)
+	}
+	if len(input.TestCaseIDs) > 0 && len(testCases) != len(slices.Compact(sortedIDs(input.TestCaseIDs))) {
+		return nil, fmt.Errorf("%w: test case", ErrNotFound)
+	}
+	if len(testCases) == 0 {
+		return nil, fmt.Errorf("%w: no test cases match the selection", ErrInvalidInput)
+	}
+
+	run := &models.TestRun{
+		TestRunID: uuid.New(),
+		ProjectID: projectID,
+		Name:      input.Name,
+		Status:    models.RunOpen,
+		CreatedBy: userID,
+	}
+	for i, testCase := range testCases {
+		run.Cases = append(run.Cases, snapshotCase(run.TestRunID, i+1, &testCase))
+	}
+
+	if err := s.db.DB().WithContext(ctx).Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to start run: %v", err)
+	}
+	return run, nil
+}
+
+// GetRun returns a run with its cases, steps and results.
+func (s *RunService) GetRun(ctx context.Context, userID, projectID, runID uuid.UUID) (*models.TestRun, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	var run models.TestRun
+	err := s.db.DB().WithContext(ctx).
+		Preload("Cases", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Cases.Steps", orderSteps).
+		Preload("Cases.Results", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("test_run_id = ? AND project_id = ?", runID, projectID).
+		First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: run", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get run: %v", err)
+	}
+	return &run, nil
+}
+
+// RecordResult records the result of a step of a test case in an open run,
+// replacing any earlier result for the step, and updates the status of the
+// test case.
+func (s *RunService) RecordResult(ctx context.Context, userID, projectID, runID, runCaseID uuid.UUID, input ResultInput) (*models.TestResult, error) {
+	if !slices.Contains(models.ResultStatuses, input.Status) {
+		return nil, fmt.Errorf("%w: status must be one of %s", ErrInvalidInput, strings.Join(models.ResultStatuses, ", "))
+	}
+	if input.DurationMs < 0 {
+		return nil, fmt.Errorf("%w: duration must not be negative", ErrInvalidInput)
+	}
+	input.Notes = strings.TrimSpace(input.Notes)
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	var result *models.TestResult
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the run so results cannot be recorded while it is closed
+		var run models.TestRun
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("test_run_id = ? AND project_id = ?", runID, projectID).
+			First(&run).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: run", ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if run.Status != models.RunOpen {
+			return fmt.Errorf("%w: the run is closed", ErrConflict)
+		}
+
+		var runCase models.TestRunCase
+		err = tx.Preload("Steps").
+			Where("test_run_case_id = ? AND test_run_id = ?", runCaseID, runID).
+			First(&runCase).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: test case", ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkResultStep(&runCase, input.StepID); err != nil {
+			return err
+		}
+
+		result, err = upsertResult(tx, &runCase, userID, input)
+		if err != nil {
+			return err
+		}
+
+		var results []models.TestResult
+		if err := tx.Where("test_run_case_id = ?", runCaseID).Find(&results).Error; err != nil {
+			return err
+		}
+		runCase.Status = models.CaseStatus(len(runCase.Steps), results)
+		if err := tx.Model(&runCase).Update("status", runCase.Status).Error; err != nil {
+			return err
+		}
+		return tx.Model(&run).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to record result: %v", err)
+	}
+	return result, nil
+}
+
+// CloseRun closes an open run. No results can be recorded afterwards.
+func (s *RunService) CloseRun(ctx context.Context, userID, projectID, runID uuid.UUID) (*models.TestRun, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	var run models.TestRun
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("test_run_id = ? AND project_id = ?", runID, projectID).
+			First(&run).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: run", ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if run.Status != models.RunOpen {
+			return fmt.Errorf("%w: the run is already closed", ErrConflict)
+		}
+
+		now := time.Now()
+		run.Status = models.RunClosed
+		run.ClosedAt = &now
+		return tx.Omit(clause.Associations).Save(&run).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to close run: %v", err)
+	}
+	return &run, nil
+}
+
+// snapshotCase copies a test case and its steps into a run.
+func snapshotCase(runID uuid.UUID, position int, testCase *models.TestCase) models.TestRunCase {
+	runCase := models.TestRunCase{
+		TestRunCaseID: uuid.New(),
+		TestRunID:     runID,
+		TestCaseID:    testCase.TestCaseID,
+		Position:      position,
+		Title:         testCase.Title,
+		Description:   testCase.Description,
+		Preconditions: testCase.Preconditions,
+		Priority:      testCase.Priority,
+		Tags:          joinTagNames(testCase.Tags),
+		Status:        models.ResultUntested,
+		Steps:         []models.TestRunStep{},
+		Results:       []models.TestResult{},
+	}
+	for _, step := range testCase.Steps {
+		runCase.Steps = append(runCase.Steps, models.TestRunStep{
+			TestRunStepID:  uuid.New(),
+			TestRunCaseID:  runCase.TestRunCaseID,
+			Position:       step.Position,
+			Action:         step.Action,
+			ExpectedResult: step.ExpectedResult,
+		})
+	}
+	return runCase
+}
+
+// checkResultStep checks that a result is for a step of the test case, or
+// for the test case itself when it has no steps.
+func checkResultStep(runCase *models.TestRunCase, stepID *uuid.UUID) error {
+	if stepID == nil {
+		if len(runCase.Steps) > 0 {
+			return fmt.Errorf("%w: step_id is required for test cases with steps", ErrInvalidInput)
+		}
+		return nil
+	}
+	for _, step := range runCase.Steps {
+		if step.TestRunStepID == *stepID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: step", ErrNotFound)
+}
+
+// upsertResult creates or replaces the result of a step.
+func upsertResult(tx *gorm.DB, runCase *models.TestRunCase, executorID uuid.UUID, input ResultInput) (*models.TestResult, error) {
+	var result models.TestResult
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("test_run_case_id = ?", runCase.TestRunCaseID)
+	if input.StepID != nil {
+		query = query.Where("test_run_step_id = ?", *input.StepID)
+	} else {
+		query = query.Where("test_run_step_id IS NULL")
+	}
+	err := query.First(&result).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		result = models.TestResult{
+			TestResultID:  uuid.New(),
+			TestRunID:     runCase.TestRunID,
+			TestRunCaseID: runCase.TestRunCaseID,
+			TestRunStepID: input.StepID,
+		}
+	}
+
+	result.Status = input.Status
+	result.Notes = input.Notes
+	result.ExecutorID = executorID
+	result.DurationMs = input.DurationMs
+	if err := tx.Save(&result).Error; err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func joinTagNames(tags []models.Tag) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func sortedIDs(ids []uuid.UUID) []uuid.UUID {
+	sorted := slices.Clone(ids)
+	slices.SortFunc(sorted, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	return sorted
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestSnapshotCase(t *testing.T) {
+	runID := uuid.New()
+	testCase := &models.TestCase{
+		TestCaseID: uuid.New(),
+		Title:      "Login",
+		Priority:   models.PriorityHigh,
+		Tags:       []models.Tag{{Name: "auth"}, {Name: "smoke"}},
+		Steps: []models.TestStep{
+			{Position: 1, Action: "Open the login page", ExpectedResult: "The form is shown"},
+			{Position: 2, Action: "Submit", ExpectedResult: "The dashboard is shown"},
+		},
+	}
+
+	runCase := snapshotCase(runID, 3, testCase)
+
+	if runCase.TestRunID != runID || runCase.TestCaseID != testCase.TestCaseID || runCase.Position != 3 {
+		t.Errorf("unexpected snapshot %+v", runCase)
+	}
+	if runCase.Tags != "auth, smoke" || runCase.Status != models.ResultUntested {
+		t.Errorf("unexpected tags %q or status %q", runCase.Tags, runCase.Status)
+	}
+	if len(runCase.Steps) != 2 || runCase.Steps[1].Action != "Submit" || runCase.Steps[1].TestRunCaseID != runCase.TestRunCaseID {
+		t.Errorf("unexpected steps %+v", runCase.Steps)
+	}
+
+	testCase.Steps[1].Action = "Changed later"
+	if runCase.Steps[1].Action != "Submit" {
+		t.Error("expected the snapshot not to follow changes to the test case")
+	}
+}
+
+func TestCheckResultStep(t *testing.T) {
+	stepID := uuid.New()
+	other := uuid.New()
+	withSteps := &models.TestRunCase{Steps: []models.TestRunStep{{TestRunStepID: stepID}}}
+	withoutSteps := &models.TestRunCase{}
+
+	if err := checkResultStep(withSteps, &stepID); err != nil {
+		t.Errorf("unexpected error for a step of the case: %v", err)
+	}
+	if err := checkResultStep(withSteps, &other); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another step, got %v", err)
+	}
+	if err := checkResultStep(withSteps, nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput without a step, got %v", err)
+	}
+	if err := checkResultStep(withoutSteps, nil); err != nil {
+		t.Errorf("unexpected error for a case without steps: %v", err)
+	}
+}
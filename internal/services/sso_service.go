@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/oidc"
+	"TestAlchemy/internal/tokens"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ssoLoginTTL = 10 * time.Minute
+	ssoLinkTTL  = 10 * time.Minute
+)
+
+// SSOService signs users in with an OpenID provider. Accounts are created
+// on first sign-in, or linked to the existing account with the same
+// address, which the provider must have verified. Accounts with two-factor
+// authentication are only linked once the user confirms with their
+// password and a code; after that, two-factor authentication is left to
+// the provider.
+type SSOService struct {
+	db          database.Service
+	keydb       database.KeyDBService
+	provider    *oidc.Provider
+	users       *UserService
+	invitations *InvitationService
+	audit       *AuditService
+	adminGroup  string
+	groupsClaim string
+}
+
+// NewSSOService returns the single sign-on service. provider is nil when
+// single sign-on is disabled.
+func NewSSOService(db database.Service, keydb database.KeyDBService, provider *oidc.Provider, users *UserService, invitations *InvitationService, audit *AuditService) *SSOService {
+	return &SSOService{
+		db:          db,
+		keydb:       keydb,
+		provider:    provider,
+		users:       users,
+		invitations: invitations,
+		audit:       audit,
+		adminGroup:  oidcAdminGroup,
+		groupsClaim: oidcGroupsClaim,
+	}
+}
+
+// SSOStart is where to send the user to sign in. State must come back with
+// the user, and should be kept in a cookie to tie the flow to the browser.
+type SSOStart struct {
+	URL   string
+	State string
+}
+
+type SSOCallbackInput struct {
+	Code  string
+	State string
+	// Error is set by the provider when the user did not sign in
+	Error string
+	// CookieState is the state kept by the browser that started the flow
+	CookieState string
+	// SessionID is the session the client already has, if any. It is
+	// replaced by the new session.
+	SessionID string
+}
+
+// SSOResult is the outcome of a sign-in. When the account has to be
+// linked first, LinkToken is to be passed to Link instead of a session.
+type SSOResult struct {
+	SessionID    string `json:"-"`
+	LinkRequired bool   `json:"link_required"`
+	LinkToken    string `json:"link_token,omitempty"`
+}
+
+// SSOLinkInput confirms the link of an account with two-factor
+// authentication to the identity of the user at the provider.
+type SSOLinkInput struct {
+	LinkToken string `json:"link_token" form:"link_token"`
+	Password  string `json:"password" form:"password"`
+	Code      string `json:"code" form:"code"`
+	// SessionID is the session the client already has, if any. It is
+	// replaced by the new session.
+	SessionID string `json:"-" form:"-"`
+}
+
+// ssoLogin is a sign-in in progress, stored under the hash of its state.
+type ssoLogin struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// ssoLink is an account waiting to be linked, stored under the hash of its
+// token. Groups are the groups of the user at the provider.
+type ssoLink struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Subject string    `json:"subject"`
+	Groups  []string  `json:"groups"`
+}
+
+// Start begins a sign-in with the authorization code flow and PKCE.
+func (s *SSOService) Start(ctx context.Context) (*SSOStart, error) {
+	if s.provider == nil {
+		return nil, fmt.Errorf("%w: single sign-on is not configured", ErrNotFound)
+	}
+
+	state := tokens.Random(32)
+	login := ssoLogin{Nonce: tokens.Random(32), Verifier: tokens.Random(32)}
+	data, err := json.Marshal(login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sign-in: %v", err)
+	}
+	if err := s.keydb.Set(ctx, ssoLoginKey(state), data, ssoLoginTTL); err != nil {
+		return nil, fmt.Errorf("failed to store sign-in: %v", err)
+	}
+
+	url, err := s.provider.AuthCodeURL(ctx, state, login.Nonce, login.Verifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return &SSOStart{URL: url, State: state}, nil
+}
+
+// Callback completes a sign-in when the provider sends the user back.
+func (s *SSOService) Callback(ctx context.Context, input SSOCallbackInput) (*SSOResult, error) {
+	if s.provider == nil {
+		return nil, fmt.Errorf("%w: single sign-on is not configured", ErrNotFound)
+	}
+	if input.Error != "" {
+		return nil, fmt.Errorf("%w: sign-in was not completed: %s", ErrForbidden, input.Error)
+	}
+	if input.State == "" || input.State != input.CookieState {
+		return nil, fmt.Errorf("%w: sign-in was started in another browser", ErrInvalidInput)
+	}
+
+	data, err := takeKey(ctx, s.keydb, ssoLoginKey(input.State))
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: sign-in has expired, try again", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sign-in: %v", err)
+	}
+	var login ssoLogin
+	if err := json.Unmarshal([]byte(data), &login); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sign-in: %v", err)
+	}
+
+	claims, err := s.provider.Exchange(ctx, input.Code, login.Verifier, login.Nonce)
+	if errors.Is(err, oidc.ErrInvalidToken) {
+		return nil, fmt.Errorf("%w: %v", ErrForbidden, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	user, confirm, err := s.provision(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	groups := claims.Strings(s.groupsClaim)
+	if confirm {
+		token, err := s.startLink(ctx, ssoLink{UserID: user.UserID, Subject: claims.Subject, Groups: groups})
+		if err != nil {
+			return nil, err
+		}
+		return &SSOResult{LinkRequired: true, LinkToken: token}, nil
+	}
+	if err := s.syncAdmin(ctx, user, groups); err != nil {
+		return nil, err
+	}
+	sessionID, err := s.users.startSession(ctx, user, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &SSOResult{SessionID: sessionID}, nil
+}
+
+// Link links an account with two-factor authentication, given the token
+// returned by Callback, the password of the account and a TOTP or recovery
+// code. The token can be used once, so a mistake means signing in again.
+// It returns the ID of the new session.
+func (s *SSOService) Link(ctx context.Context, input SSOLinkInput) (string, error) {
+	if input.LinkToken == "" {
+		return "", fmt.Errorf("%w: link_token is required", ErrInvalidInput)
+	}
+	data, err := takeKey(ctx, s.keydb, ssoLinkKey(input.LinkToken))
+	if errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("%w: invalid or expired token, sign in again", ErrInvalidInput)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get pending link: %v", err)
+	}
+	var link ssoLink
+	if err := json.Unmarshal([]byte(data), &link); err != nil {
+		return "", fmt.Errorf("failed to unmarshal pending link: %v", err)
+	}
+
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", link.UserID); err != nil {
+		return "", fmt.Errorf("%w: invalid or expired token, sign in again", ErrInvalidInput)
+	}
+	if err := s.users.checkLockout(ctx, user.Email); err != nil {
+		return "", err
+	}
+	if !user.ValidatePassword(input.Password) {
+		s.failLink(ctx, &user, "wrong password")
+		return "", fmt.Errorf("%w: invalid password or code, sign in again", ErrInvalidInput)
+	}
+	if err := s.users.mfa.Verify(ctx, &user, input.Code); err != nil {
+		if !errors.Is(err, ErrInvalidInput) {
+			return "", err
+		}
+		s.failLink(ctx, &user, "wrong mfa code")
+		return "", fmt.Errorf("%w: invalid password or code, sign in again", ErrInvalidInput)
+	}
+
+	if err := s.link(ctx, &user, link.Subject); err != nil {
+		return "", err
+	}
+	if err := s.syncAdmin(ctx, &user, link.Groups); err != nil {
+		return "", err
+	}
+	return s.users.startSession(ctx, &user, input.SessionID)
+}
+
+// provision returns the user signing in, creating or linking their
+// account by address on their first sign-in. The provider must state that
+// the address is verified. confirm is set for an existing account with
+// two-factor authentication, which is returned unlinked.
+func (s *SSOService) provision(ctx context.Context, claims *oidc.Claims) (user *models.User, confirm bool, err error) {
+	var existing models.User
+	if err := s.db.Read(ctx, &existing, "oidc_subject = ?", claims.Subject); err == nil {
+		return &existing, false, nil
+	}
+
+	if claims.Email == "" {
+		return nil, false, fmt.Errorf("%w: the identity provider did not share an email address", ErrForbidden)
+	}
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		return nil, false, fmt.Errorf("%w: the email address is not verified by the identity provider", ErrForbidden)
+	}
+
+	if err := s.db.Read(ctx, &existing, "email = ?", claims.Email); err == nil {
+		if existing.MFAEnabled {
+			return &existing, true, nil
+		}
+		if err := s.link(ctx, &existing, claims.Subject); err != nil {
+			return nil, false, err
+		}
+		return &existing, false, nil
+	}
+
+	// The password is random and unknown, so the account can only be used
+	// with single sign-on, or after a password reset
+	subject := claims.Subject
+	user = &models.User{
+		UserID:        uuid.New(),
+		Email:         claims.Email,
+		EmailVerified: true,
+		OIDCSubject:   &subject,
+	}
+	if err := user.HashPassword(tokens.Random(32)); err != nil {
+		return nil, false, err
+	}
+	if err := s.db.Create(ctx, user); err != nil {
+		return nil, false, fmt.Errorf("failed to create user: %v", err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserRegister,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+		Metadata:   map[string]interface{}{"method": "oidc"},
+	})
+	s.linkInvitations(ctx, user)
+	return user, false, nil
+}
+
+// link ties an existing account to the subject of the user at the
+// provider, which has verified their address. An account whose address
+// was never verified may have been registered by someone else, so its
+// password is replaced and its sessions are revoked.
+func (s *SSOService) link(ctx context.Context, user *models.User, subject string) error {
+	wasVerified := user.EmailVerified
+	user.OIDCSubject = &subject
+	user.EmailVerified = true
+	if !wasVerified {
+		if err := user.HashPassword(tokens.Random(32)); err != nil {
+			return err
+		}
+	}
+	if err := s.db.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to link user: %v", err)
+	}
+	if !wasVerified {
+		if _, err := s.users.session.RevokeSessions(ctx, user.UserID, ""); err != nil {
+			log.Printf("failed to revoke sessions of user %s: %v", user.UserID, err)
+		}
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserSSOLink,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+	})
+	if !wasVerified {
+		s.linkInvitations(ctx, user)
+	}
+	return nil
+}
+
+// startLink stores an account waiting to be linked and returns the token
+// to confirm the link with.
+func (s *SSOService) startLink(ctx context.Context, link ssoLink) (string, error) {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pending link: %v", err)
+	}
+	token := tokens.Random(32)
+	if err := s.keydb.Set(ctx, ssoLinkKey(token), data, ssoLinkTTL); err != nil {
+		return "", fmt.Errorf("failed to store pending link: %v", err)
+	}
+	return token, nil
+}
+
+// failLink records a failed confirmation of a link, which counts as a
+// failed login.
+func (s *SSOService) failLink(ctx context.Context, user *models.User, reason string) {
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserLoginFailed,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+		Metadata:   map[string]interface{}{"email": user.Email, "reason": reason, "method": "oidc"},
+	})
+	s.users.countLoginFailure(ctx, user.Email, user.UserID)
+}
+
+// syncAdmin grants or removes the admin flag of the user from their
+// groups at the provider, when an admin group is configured.
+func (s *SSOService) syncAdmin(ctx context.Context, user *models.User, groups []string) error {
+	if s.adminGroup == "" {
+		return nil
+	}
+	isAdmin := slices.Contains(groups, s.adminGroup)
+	if user.IsAdmin == isAdmin {
+		return nil
+	}
+
+	user.IsAdmin = isAdmin
+	if err := s.db.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserAdminChange,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+		Metadata:   map[string]interface{}{"is_admin": isAdmin, "group": s.adminGroup},
+	})
+	return nil
+}
+
+func (s *SSOService) linkInvitations(ctx context.Context, user *models.User) {
+	if err := s.invitations.LinkPendingInvitations(ctx, user.UserID, user.Email); err != nil {
+		log.Printf("failed to link pending invitations for user %s: %v", user.UserID, err)
+	}
+}
+
+func ssoLoginKey(state string) string {
+	return fmt.Sprintf("sso_login:%s", tokens.Hash(state))
+}
+
+func ssoLinkKey(token string) string {
+	return fmt.Sprintf("sso_link:%s", tokens.Hash(token))
+}
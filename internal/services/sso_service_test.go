@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/mailer"
+	"TestAlchemy/internal/models"
+	"TestAlchemy/internal/oidc"
+	"TestAlchemy/internal/oidc/oidctest"
+	"TestAlchemy/internal/session"
+	"TestAlchemy/internal/totp"
+	"github.com/google/uuid"
+)
+
+func TestSSOCallbackChecks(t *testing.T) {
+	ctx := context.Background()
+
+	disabled := &SSOService{}
+	if _, err := disabled.Callback(ctx, SSOCallbackInput{State: "s", CookieState: "s"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound when single sign-on is disabled, got %v", err)
+	}
+
+	s := &SSOService{provider: oidc.NewProvider(oidc.Config{IssuerURL: "http://127.0.0.1:0"})}
+	tests := []struct {
+		name  string
+		input SSOCallbackInput
+		want  error
+	}{
+		{"refused by the provider", SSOCallbackInput{Error: "access_denied", State: "s", CookieState: "s"}, ErrForbidden},
+		{"no state", SSOCallbackInput{Code: "c"}, ErrInvalidInput},
+		{"state from another browser", SSOCallbackInput{Code: "c", State: "s", CookieState: "other"}, ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Callback(ctx, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+const ssoAdminGroup = "test-alchemy-admins"
+
+// ssoTest signs users in with a test identity provider.
+type ssoTest struct {
+	sso    *SSOService
+	mfa    *MFAService
+	db     database.Service
+	store  *session.Store
+	issuer *oidctest.Issuer
+}
+
+func startSSOTest(t *testing.T) *ssoTest {
+	t.Helper()
+	issuer, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatalf("failed to start issuer: %v", err)
+	}
+	t.Cleanup(issuer.Close)
+
+	db := startDB(t)
+	keydb := startKeyDB(t)
+	outbox := mailer.NewOutbox("")
+	projects := NewProjectService(db)
+	audit := NewAuditService(db, projects)
+	mfa := NewMFAService(db, keydb, audit)
+	store := newSessionStore(keydb)
+	invitations := NewInvitationService(db, keydb, projects, outbox)
+	users := NewUserService(db, keydb, store, outbox, invitations, mfa, audit)
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:   issuer.URL,
+		ClientID:    "test-alchemy",
+		RedirectURL: "http://localhost:8080/api/sso/callback",
+		Scopes:      []string{"email", "profile"},
+	})
+
+	sso := NewSSOService(db, keydb, provider, users, invitations, audit)
+	sso.adminGroup = ssoAdminGroup
+	sso.groupsClaim = "groups"
+	return &ssoTest{sso: sso, mfa: mfa, db: db, store: store, issuer: issuer}
+}
+
+// signIn signs in at the provider with the given claims and completes the
+// callback.
+func (s *ssoTest) signIn(t *testing.T, claims map[string]interface{}) (*SSOResult, error) {
+	t.Helper()
+	ctx := context.Background()
+	start, err := s.sso.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	callback, err := s.issuer.Authorize(start.URL, claims)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := u.Query().Get("state")
+	return s.sso.Callback(ctx, SSOCallbackInput{Code: u.Query().Get("code"), State: state, CookieState: state})
+}
+
+// session checks that the sign-in started a session of the user.
+func (s *ssoTest) session(t *testing.T, result *SSOResult, err error, userID uuid.UUID) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.LinkRequired || result.SessionID == "" {
+		t.Fatalf("expected a session, got %+v", result)
+	}
+	sess, err := s.store.GetSession(context.Background(), result.SessionID)
+	if err != nil || sess == nil || sess.UserID != userID {
+		t.Fatalf("expected a session of user %s, got %+v, %v", userID, sess, err)
+	}
+}
+
+func (s *ssoTest) user(t *testing.T, query string, args ...interface{}) *models.User {
+	t.Helper()
+	var user models.User
+	if err := s.db.Read(context.Background(), &user, query, args...); err != nil {
+		t.Fatalf("failed to read user: %v", err)
+	}
+	return &user
+}
+
+func TestSSOProvisioning(t *testing.T) {
+	s := startSSOTest(t)
+	subject := "sub-" + uuid.NewString()
+	email := uuid.NewString() + "@example.com"
+
+	result, err := s.signIn(t, map[string]interface{}{
+		"sub":            subject,
+		"email":          email,
+		"email_verified": true,
+		"groups":         []string{"qa", ssoAdminGroup},
+	})
+	user := s.user(t, "oidc_subject = ?", subject)
+	s.session(t, result, err, user.UserID)
+	if user.Email != email || !user.EmailVerified {
+		t.Errorf("expected a verified account for %s, got %+v", email, user)
+	}
+	if !user.IsAdmin {
+		t.Error("expected members of the admin group to be admins")
+	}
+
+	// Returning users are found by subject, whatever their address now
+	result, err = s.signIn(t, map[string]interface{}{
+		"sub":            subject,
+		"email":          "renamed-" + email,
+		"email_verified": true,
+		"groups":         []string{"qa"},
+	})
+	s.session(t, result, err, user.UserID)
+	if user := s.user(t, "user_id = ?", user.UserID); user.IsAdmin {
+		t.Error("expected the admin flag to be removed with the group")
+	}
+	var other models.User
+	if err := s.db.Read(context.Background(), &other, "email = ?", "renamed-"+email); err == nil {
+		t.Error("expected no account for the new address")
+	}
+}
+
+func TestSSOLinksExistingAccount(t *testing.T) {
+	s := startSSOTest(t)
+	existing := createUser(t, s.db)
+	subject := "sub-" + uuid.NewString()
+
+	result, err := s.signIn(t, map[string]interface{}{
+		"sub":            subject,
+		"email":          existing.Email,
+		"email_verified": true,
+	})
+	s.session(t, result, err, existing.UserID)
+	user := s.user(t, "user_id = ?", existing.UserID)
+	if user.OIDCSubject == nil || *user.OIDCSubject != subject {
+		t.Errorf("expected the account to be linked to %s, got %v", subject, user.OIDCSubject)
+	}
+}
+
+func TestSSOTakesOverUnverifiedAccount(t *testing.T) {
+	s := startSSOTest(t)
+	ctx := context.Background()
+	// Someone registered the address of the user, who never verified it
+	existing := createUser(t, s.db)
+	existing.EmailVerified = false
+	if err := s.db.Update(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+	planted, err := s.store.CreateSession(ctx, existing.UserID, session.Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.signIn(t, map[string]interface{}{
+		"sub":            "sub-" + uuid.NewString(),
+		"email":          existing.Email,
+		"email_verified": true,
+	})
+	s.session(t, result, err, existing.UserID)
+	user := s.user(t, "user_id = ?", existing.UserID)
+	if !user.EmailVerified || user.OIDCSubject == nil {
+		t.Errorf("expected a verified and linked account, got %+v", user)
+	}
+	if user.ValidatePassword(testPassword) {
+		t.Error("expected the password of the registrant to be replaced")
+	}
+	if sess, err := s.store.GetSession(ctx, planted); err != nil || sess != nil {
+		t.Errorf("expected the earlier sessions to be revoked, got %+v, %v", sess, err)
+	}
+}
+
+func TestSSORequiresVerifiedEmail(t *testing.T) {
+	s := startSSOTest(t)
+	existing := createUser(t, s.db)
+	newEmail := uuid.NewString() + "@example.com"
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"unverified address of an account", map[string]interface{}{"email": existing.Email, "email_verified": false}},
+		{"address of an account not stated verified", map[string]interface{}{"email": existing.Email}},
+		{"new address not stated verified", map[string]interface{}{"email": newEmail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.claims["sub"] = "sub-" + uuid.NewString()
+			if _, err := s.signIn(t, tt.claims); !errors.Is(err, ErrForbidden) {
+				t.Errorf("expected ErrForbidden, got %v", err)
+			}
+		})
+	}
+
+	if user := s.user(t, "user_id = ?", existing.UserID); user.OIDCSubject != nil {
+		t.Errorf("expected the account not to be linked, got %s", *user.OIDCSubject)
+	}
+	var user models.User
+	if err := s.db.Read(context.Background(), &user, "email = ?", newEmail); err == nil {
+		t.Error("expected no account to be created")
+	}
+}
+
+func TestSSOLinkWithMFA(t *testing.T) {
+	s := startSSOTest(t)
+	ctx := context.Background()
+	existing := createUser(t, s.db)
+	secret, counter, _ := enableMFA(t, s.mfa, existing)
+	subject := "sub-" + uuid.NewString()
+	claims := map[string]interface{}{
+		"sub":            subject,
+		"email":          existing.Email,
+		"email_verified": true,
+		"groups":         []string{ssoAdminGroup},
+	}
+
+	startLink := func(t *testing.T) string {
+		t.Helper()
+		result, err := s.signIn(t, claims)
+		if err != nil {
+			t.Fatalf("Callback() error = %v", err)
+		}
+		if !result.LinkRequired || result.LinkToken == "" || result.SessionID != "" {
+			t.Fatalf("expected a link token instead of a session, got %+v", result)
+		}
+		return result.LinkToken
+	}
+
+	token := startLink(t)
+	if user := s.user(t, "user_id = ?", existing.UserID); user.OIDCSubject != nil || user.IsAdmin {
+		t.Fatalf("expected the account to wait for the link, got %+v", user)
+	}
+
+	// A wrong password spends the token
+	if _, err := s.sso.Link(ctx, SSOLinkInput{LinkToken: token, Password: "wrong", Code: "000000"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected a wrong password to be rejected, got %v", err)
+	}
+	code, err := totp.Code(secret, counter+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.sso.Link(ctx, SSOLinkInput{LinkToken: token, Password: testPassword, Code: code}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected a spent token to be rejected, got %v", err)
+	}
+
+	token = startLink(t)
+	if _, err := s.sso.Link(ctx, SSOLinkInput{LinkToken: token, Password: testPassword, Code: "000000"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected a wrong code to be rejected, got %v", err)
+	}
+
+	token = startLink(t)
+	sessionID, err := s.sso.Link(ctx, SSOLinkInput{LinkToken: token, Password: testPassword, Code: code})
+	if err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	s.session(t, &SSOResult{SessionID: sessionID}, nil, existing.UserID)
+	user := s.user(t, "user_id = ?", existing.UserID)
+	if user.OIDCSubject == nil || *user.OIDCSubject != subject {
+		t.Errorf("expected the account to be linked to %s, got %v", subject, user.OIDCSubject)
+	}
+	if !user.IsAdmin {
+		t.Error("expected the groups to be applied once linked")
+	}
+
+	// Once linked, the account is found by subject
+	result, err := s.signIn(t, claims)
+	s.session(t, result, err, existing.UserID)
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewTagService(db database.Service, projects *ProjectService) *TagService {
+	return &TagService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+type TagInput struct {
+	Name string `json:"name"`
+}
+
+// normalizeTagName trims the name and checks that it can be used as a tag.
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: tag name is required", ErrInvalidInput)
+	}
+	if len(name) > 64 {
+		return "", fmt.Errorf("%w: tag name must be at most 64 characters long", ErrInvalidInput)
+	}
+	// Commas separate tags in filters and imports
+	if strings.Contains(name, ",") {
+		return "", fmt.Errorf("%w: tag name cannot contain a comma", ErrInvalidInput)
+	}
+	return name, nil
+}
+
+func (s *TagService) ListTags(ctx context.Context, userID, projectID uuid.UUID) ([]models.Tag, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	var tags []models.Tag
+	err := s.db.DB().WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("name ASC").
+		Find(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %v", err)
+	}
+	return tags, nil
+}
+
+func (s *TagService) CreateTag(ctx context.Context, userID, projectID uuid.UUID, input TagInput) (*models.Tag, error) {
+	name, err := normalizeTagName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{
+		TagID:     uuid.New(),
+		ProjectID: projectID,
+		Name:      name,
+	}
+	result := s.db.DB().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(tag)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create tag: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: tag %q already exists", ErrConflict, name)
+	}
+	return tag, nil
+}
+
+// RenameTag renames a tag. Test cases reference tags by ID, so every case
+// using the tag shows the new name, and gets a revision recording it.
+// Renaming to the name of another tag is refused; merge the tags instead.
+func (s *TagService) RenameTag(ctx context.Context, userID, projectID, tagID uuid.UUID, input TagInput) (*models.Tag, error) {
+	name, err := normalizeTagName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.getTag(ctx, projectID, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	err = s.db.DB().WithContext(ctx).Model(&models.Tag{}).
+		Where("project_id = ? AND name = ? AND tag_id <> ?", projectID, name, tagID).
+		Count(&existing).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %v", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: tag %q already exists, merge the tags instead", ErrConflict, name)
+	}
+
+	tag.Name = name
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		testCaseIDs, err := taggedTestCases(tx, []uuid.UUID{tag.TagID})
+		if err != nil {
+			return err
+		}
+		return reviseTestCases(tx, testCaseIDs, userID, nil, func() error {
+			return tx.Save(tag).Error
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename tag: %v", err)
+	}
+	return tag, nil
+}
+
+// MergeTags moves every test case tagged with one of the source tags to the
+// target tag and deletes the source tags. Each moved case gets a revision.
+func (s *TagService) MergeTags(ctx context.Context, userID, projectID, targetID uuid.UUID, sourceIDs []uuid.UUID) (*models.Tag, error) {
+	if len(sourceIDs) == 0 {
+		return nil, fmt.Errorf("%w: source_ids is required", ErrInvalidInput)
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	target, err := s.getTag(ctx, projectID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sourceID := range sourceIDs {
+		if sourceID == targetID {
+			return nil, fmt.Errorf("%w: cannot merge a tag into itself", ErrInvalidInput)
+		}
+		if _, err := s.getTag(ctx, projectID, sourceID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		testCaseIDs, err := taggedTestCases(tx, sourceIDs)
+		if err != nil {
+			return err
+		}
+		return reviseTestCases(tx, testCaseIDs, userID, nil, func() error {
+			err := tx.Exec(
+				"INSERT IGNORE INTO test_case_tags (test_case_id, tag_id) "+
+					"SELECT test_case_id, ? FROM test_case_tags WHERE tag_id IN ?",
+				targetID, sourceIDs,
+			).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM test_case_tags WHERE tag_id IN ?", sourceIDs).Error; err != nil {
+				return err
+			}
+			return tx.Where("tag_id IN ?", sourceIDs).Delete(&models.Tag{}).Error
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge tags: %v", err)
+	}
+	return target, nil
+}
+
+// DeleteTag deletes a tag and removes it from every test case, recording a
+// revision of each.
+func (s *TagService) DeleteTag(ctx context.Context, userID, projectID, tagID uuid.UUID) error {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return err
+	}
+
+	tag, err := s.getTag(ctx, projectID, tagID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		testCaseIDs, err := taggedTestCases(tx, []uuid.UUID{tag.TagID})
+		if err != nil {
+			return err
+		}
+		return reviseTestCases(tx, testCaseIDs, userID, nil, func() error {
+			if err := tx.Exec("DELETE FROM test_case_tags WHERE tag_id = ?", tag.TagID).Error; err != nil {
+				return err
+			}
+			return tx.Delete(tag).Error
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %v", err)
+	}
+	return nil
+}
+
+func (s *TagService) getTag(ctx context.Context, projectID, tagID uuid.UUID) (*models.Tag, error) {
+	var tag models.Tag
+	err := s.db.Read(ctx, &tag, "tag_id = ? AND project_id = ?", tagID, projectID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: tag", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag: %v", err)
+	}
+	return &tag, nil
+}
+
+// taggedTestCases returns the IDs of the test cases tagged with one of the
+// tags. They are sorted, so that concurrent changes lock them in the same
+// order. Deleted test cases keep their tags but are left out.
+func taggedTestCases(tx *gorm.DB, tagIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var testCaseIDs []uuid.UUID
+	err := tx.Table("test_case_tags").
+		Joins("JOIN test_cases ON test_cases.test_case_id = test_case_tags.test_case_id").
+		Where("test_case_tags.tag_id IN ? AND test_cases.deleted_at IS NULL", tagIDs).
+		Distinct("test_case_tags.test_case_id").
+		Order("test_case_tags.test_case_id").
+		Pluck("test_case_tags.test_case_id", &testCaseIDs).Error
+	return testCaseIDs, err
+}
+
+// resolveTags returns the project's tags with the given names, creating the
+// ones that do not exist yet.
+func resolveTags(tx *gorm.DB, projectID uuid.UUID, names []string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name, err := normalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		created := models.Tag{TagID: uuid.New(), ProjectID: projectID, Name: name}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&created).Error; err != nil {
+			return nil, err
+		}
+		var tag models.Tag
+		if err := tx.Where("project_id = ? AND name = ?", projectID, name).First(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
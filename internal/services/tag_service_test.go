@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"  smoke ", "smoke", false},
+		{"", "", true},
+		{"a,b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTagName(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("normalizeTagName(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestTagChanges(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	projects := NewProjectService(db)
+	testCases := NewTestCaseService(db, projects)
+	s := NewTagService(db, projects)
+	owner := createUser(t, db)
+	viewer := createUser(t, db)
+	project := createProject(t, db, owner, map[*models.User]string{viewer: models.RoleViewer})
+	other := createProject(t, db, owner, nil)
+
+	for title, tags := range map[string][]string{
+		"A": {"api", "rest", "smoke"},
+		"B": {"rest"},
+		"C": {"api"},
+		"D": {"ui"},
+	} {
+		if _, err := testCases.CreateTestCase(ctx, owner.UserID, project.ProjectID, TestCaseInput{Title: title, Tags: tags}); err != nil {
+			t.Fatalf("CreateTestCase() error = %v", err)
+		}
+	}
+	if _, err := testCases.CreateTestCase(ctx, owner.UserID, other.ProjectID, TestCaseInput{Title: "Elsewhere", Tags: []string{"api"}}); err != nil {
+		t.Fatalf("CreateTestCase() error = %v", err)
+	}
+
+	tagIDs := func(projectID uuid.UUID) map[string]uuid.UUID {
+		t.Helper()
+		tags, err := s.ListTags(ctx, owner.UserID, projectID)
+		if err != nil {
+			t.Fatalf("ListTags() error = %v", err)
+		}
+		ids := map[string]uuid.UUID{}
+		for _, tag := range tags {
+			ids[tag.Name] = tag.TagID
+		}
+		return ids
+	}
+	// caseTags returns the tags of each test case of the project by title.
+	caseTags := func() map[string]string {
+		t.Helper()
+		list, err := testCases.ListTestCases(ctx, owner.UserID, project.ProjectID, TestCaseFilter{})
+		if err != nil {
+			t.Fatalf("ListTestCases() error = %v", err)
+		}
+		tags := map[string]string{}
+		for _, testCase := range list {
+			tags[testCase.Title] = joinTagNames(testCase.Tags)
+		}
+		return tags
+	}
+	check := func(step string, want map[string]string) {
+		t.Helper()
+		if got := caseTags(); !reflect.DeepEqual(got, want) {
+			t.Errorf("after %s: expected tags %v, got %v", step, want, got)
+		}
+	}
+	ids := tagIDs(project.ProjectID)
+
+	if _, err := s.RenameTag(ctx, viewer.UserID, project.ProjectID, ids["ui"], TagInput{Name: "web"}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected viewers not to rename tags, got %v", err)
+	}
+	if _, err := s.RenameTag(ctx, owner.UserID, project.ProjectID, ids["ui"], TagInput{Name: "api"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected renaming to an existing name to be refused, got %v", err)
+	}
+	if _, err := s.RenameTag(ctx, owner.UserID, project.ProjectID, ids["ui"], TagInput{Name: "web"}); err != nil {
+		t.Fatalf("RenameTag() error = %v", err)
+	}
+	check("renaming", map[string]string{"A": "api, rest, smoke", "B": "rest", "C": "api", "D": "web"})
+
+	if _, err := s.MergeTags(ctx, owner.UserID, project.ProjectID, ids["api"], []uuid.UUID{ids["api"]}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected merging a tag into itself to be refused, got %v", err)
+	}
+	if _, err := s.MergeTags(ctx, owner.UserID, project.ProjectID, ids["api"], []uuid.UUID{tagIDs(other.ProjectID)["api"]}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected merging a tag of another project to be refused, got %v", err)
+	}
+
+	// A has all three tags, B and C only one of them
+	if _, err := s.MergeTags(ctx, owner.UserID, project.ProjectID, ids["api"], []uuid.UUID{ids["rest"], ids["smoke"]}); err != nil {
+		t.Fatalf("MergeTags() error = %v", err)
+	}
+	check("merging", map[string]string{"A": "api", "B": "api", "C": "api", "D": "web"})
+	if got := tagIDs(project.ProjectID); len(got) != 2 || got["api"] != ids["api"] || got["web"] != ids["ui"] {
+		t.Errorf("expected the source tags to be deleted, got %v", got)
+	}
+
+	if err := s.DeleteTag(ctx, owner.UserID, project.ProjectID, ids["api"]); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	check("deleting", map[string]string{"A": "", "B": "", "C": "", "D": "web"})
+	if got := tagIDs(other.ProjectID); len(got) != 1 {
+		t.Errorf("expected the tags of another project to be left alone, got %v", got)
+	}
+}
@@ -0,0 +1,585 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TestCaseService struct {
+	db       database.Service
+	projects *ProjectService
+}
+
+func NewTestCaseService(db database.Service, projects *ProjectService) *TestCaseService {
+	return &TestCaseService{
+		db:       db,
+		projects: projects,
+	}
+}
+
+type TestCaseInput struct {
+	Title         string          `json:"title"`
+	Description   string          `json:"description"`
+	Preconditions string          `json:"preconditions"`
+	UserLevel     string          `json:"user_level"`
+	Priority      string          `json:"priority"`
+	Status        string          `json:"status"`
+	Steps         []TestStepInput `json:"steps"`
+	// Tags are tag names; missing tags are created. When updating a test
+	// case, nil leaves the tags unchanged and an empty list removes them.
+	Tags []string `json:"tags"`
+}
+
+// SearchInput describes a test case search. Every filter is optional.
+type SearchInput struct {
+	Query     string
+	Tags      []string
+	MatchAll  bool
+	UserLevel string
+	Status    string
+	AuthorID  uuid.UUID
+	Sort      string
+	Desc      bool
+	Cursor    string
+	Limit     int
+}
+
+// TestCasePage is one page of search results.
+type TestCasePage struct {
+	Items []models.TestCase `json:"items"`
+	database.Page
+}
+
+var searchSortFields = []string{"created_at", "updated_at", "title"}
+
+// TestCaseFilter narrows down a test case listing.
+type TestCaseFilter struct {
+	// Tags are tag names. With MatchAll a case must have every tag, otherwise
+	// any one of them is enough.
+	Tags     []string
+	MatchAll bool
+}
+
+type TestStepInput struct {
+	// Position is the 1-based position to insert the step at. It is only
+	// used when adding a single step; nil appends the step to the end.
+	Position       *int   `json:"position"`
+	Action         string `json:"action"`
+	ExpectedResult string `json:"expected_result"`
+}
+
+func (s *TestCaseService) ValidateTestCase(input *TestCaseInput) error {
+	input.Title = strings.TrimSpace(input.Title)
+	if input.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidInput)
+	}
+	if len(input.Title) > 255 {
+		return fmt.Errorf("%w: title must be at most 255 characters long", ErrInvalidInput)
+	}
+	if len(input.UserLevel) > 64 {
+		return fmt.Errorf("%w: user level must be at most 64 characters long", ErrInvalidInput)
+	}
+
+	if input.Priority == "" {
+		input.Priority = models.PriorityMedium
+	}
+	if !slices.Contains(models.Priorities, input.Priority) {
+		return fmt.Errorf("%w: priority must be one of %s", ErrInvalidInput, strings.Join(models.Priorities, ", "))
+	}
+	if input.Status == "" {
+		input.Status = models.StatusDraft
+	}
+	if !slices.Contains(models.Statuses, input.Status) {
+		return fmt.Errorf("%w: status must be one of %s", ErrInvalidInput, strings.Join(models.Statuses, ", "))
+	}
+
+	for i := range input.Steps {
+		if err := s.ValidateStep(input.Steps[i]); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	for _, tag := range input.Tags {
+		if _, err := normalizeTagName(tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *TestCaseService) ValidateStep(input TestStepInput) error {
+	if strings.TrimSpace(input.Action) == "" {
+		return fmt.Errorf("%w: action is required", ErrInvalidInput)
+	}
+	if input.Position != nil && *input.Position < 1 {
+		return fmt.Errorf("%w: position must be at least 1", ErrInvalidInput)
+	}
+	return nil
+}
+
+func (s *TestCaseService) ListTestCases(ctx context.Context, userID, projectID uuid.UUID, filter TestCaseFilter) ([]models.TestCase, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	var testCases []models.TestCase
+	err := s.db.DB().WithContext(ctx).
+		Preload("Steps", orderSteps).
+		Preload("Tags", orderTags).
+		Where("project_id = ?", projectID).
+		Scopes(withTags(projectID, filter.Tags, filter.MatchAll)).
+		Order("created_at ASC").
+		Find(&testCases).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list test cases: %v", err)
+	}
+	return testCases, nil
+}
+
+// SearchTestCases runs a full-text search over the titles, descriptions and
+// step text of a project's test cases, returning one page of results.
+func (s *TestCaseService) SearchTestCases(ctx context.Context, userID, projectID uuid.UUID, input SearchInput) (*TestCasePage, error) {
+	sortField := input.Sort
+	if sortField == "" {
+		sortField = "created_at"
+	}
+	if !slices.Contains(searchSortFields, sortField) {
+		return nil, fmt.Errorf("%w: sort must be one of %s", ErrInvalidInput, strings.Join(searchSortFields, ", "))
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	scopes := []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Steps", orderSteps).Preload("Tags", orderTags).
+				Where("test_cases.project_id = ?", projectID)
+		},
+		withTags(projectID, input.Tags, input.MatchAll),
+	}
+	if terms := fullTextQuery(input.Query); terms != "" {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where(
+				"MATCH(test_cases.title, test_cases.description) AGAINST (? IN BOOLEAN MODE) OR "+
+					"test_cases.test_case_id IN (SELECT test_case_id FROM test_steps WHERE MATCH(action, expected_result) AGAINST (? IN BOOLEAN MODE))",
+				terms, terms,
+			)
+		})
+	}
+	if input.UserLevel != "" {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("test_cases.user_level = ?", input.UserLevel)
+		})
+	}
+	if input.Status != "" {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("test_cases.status = ?", input.Status)
+		})
+	}
+	if input.AuthorID != uuid.Nil {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("test_cases.author_id = ?", input.AuthorID)
+		})
+	}
+
+	result := &TestCasePage{Items: []models.TestCase{}}
+	page, err := s.db.List(ctx, &result.Items, database.ListQuery{
+		Scopes:    scopes,
+		SortField: sortField,
+		SortDesc:  input.Desc,
+		Cursor:    input.Cursor,
+		Limit:     input.Limit,
+	})
+	if errors.Is(err, database.ErrInvalidQuery) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search test cases: %v", err)
+	}
+	result.Page = *page
+	return result, nil
+}
+
+func (s *TestCaseService) GetTestCase(ctx context.Context, userID, projectID, testCaseID uuid.UUID) (*models.TestCase, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.getTestCase(ctx, projectID, testCaseID)
+}
+
+// editTestCase checks that the user may modify test cases of the project and
+// returns the test case.
+func (s *TestCaseService) editTestCase(ctx context.Context, userID, projectID, testCaseID uuid.UUID) (*models.TestCase, error) {
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	return s.getTestCase(ctx, projectID, testCaseID)
+}
+
+func (s *TestCaseService) getTestCase(ctx context.Context, projectID, testCaseID uuid.UUID) (*models.TestCase, error) {
+	var testCase models.TestCase
+	err := s.db.DB().WithContext(ctx).
+		Preload("Steps", orderSteps).
+		Preload("Tags", orderTags).
+		Where("test_case_id = ? AND project_id = ?", testCaseID, projectID).
+		First(&testCase).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: test case", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test case: %v", err)
+	}
+	return &testCase, nil
+}
+
+func (s *TestCaseService) CreateTestCase(ctx context.Context, userID, projectID uuid.UUID, input TestCaseInput) (*models.TestCase, error) {
+	if err := s.ValidateTestCase(&input); err != nil {
+		return nil, err
+	}
+	if _, err := s.projects.Authorize(ctx, userID, projectID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	testCase := newTestCase(userID, projectID, input)
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createTestCase(tx, testCase, input.Tags)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test case: %w", err)
+	}
+
+	return testCase, nil
+}
+
+// UpdateTestCase updates the fields of a test case. Steps are left untouched;
+// they are managed with the step operations below.
+func (s *TestCaseService) UpdateTestCase(ctx context.Context, userID, projectID, testCaseID uuid.UUID, input TestCaseInput) (*models.TestCase, error) {
+	input.Steps = nil
+	if err := s.ValidateTestCase(&input); err != nil {
+		return nil, err
+	}
+
+	testCase, err := s.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	testCase.Title = input.Title
+	testCase.Description = input.Description
+	testCase.Preconditions = input.Preconditions
+	testCase.UserLevel = input.UserLevel
+	testCase.Priority = input.Priority
+	testCase.Status = input.Status
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reviseTestCase(tx, testCase.TestCaseID, userID, nil, func() error {
+			if err := tx.Omit(clause.Associations).Save(testCase).Error; err != nil {
+				return err
+			}
+			if input.Tags == nil {
+				return nil
+			}
+			tags, err := resolveTags(tx, projectID, input.Tags)
+			if err != nil {
+				return err
+			}
+			testCase.Tags = tags
+			return tx.Model(testCase).Association("Tags").Replace(tags)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update test case: %w", err)
+	}
+
+	return testCase, nil
+}
+
+// DeleteTestCase soft deletes the test case. Its steps are kept so the case
+// can be recovered.
+func (s *TestCaseService) DeleteTestCase(ctx context.Context, userID, projectID, testCaseID uuid.UUID) error {
+	testCase, err := s.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(ctx, testCase); err != nil {
+		return fmt.Errorf("failed to delete test case: %v", err)
+	}
+	return nil
+}
+
+// AddStep inserts a step at input.Position, shifting the following steps
+// down, or appends it when no position is given.
+func (s *TestCaseService) AddStep(ctx context.Context, userID, projectID, testCaseID uuid.UUID, input TestStepInput) (*models.TestStep, error) {
+	if err := s.ValidateStep(input); err != nil {
+		return nil, err
+	}
+
+	testCase, err := s.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	position := len(testCase.Steps) + 1
+	if input.Position != nil && *input.Position < position {
+		position = *input.Position
+	}
+
+	step := &models.TestStep{
+		TestStepID:     uuid.New(),
+		TestCaseID:     testCase.TestCaseID,
+		Position:       position,
+		Action:         input.Action,
+		ExpectedResult: input.ExpectedResult,
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reviseTestCase(tx, testCase.TestCaseID, userID, nil, func() error {
+			err := tx.Model(&models.TestStep{}).
+				Where("test_case_id = ? AND position >= ?", testCase.TestCaseID, position).
+				Update("position", gorm.Expr("position + 1")).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(step).Error; err != nil {
+				return err
+			}
+			return touchTestCase(tx, testCase.TestCaseID)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add step: %v", err)
+	}
+
+	return step, nil
+}
+
+// UpdateStep changes the action and expected result of a step, keeping its position.
+func (s *TestCaseService) UpdateStep(ctx context.Context, userID, projectID, testCaseID, stepID uuid.UUID, input TestStepInput) (*models.TestStep, error) {
+	input.Position = nil
+	if err := s.ValidateStep(input); err != nil {
+		return nil, err
+	}
+
+	testCase, err := s.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+	step, err := findStep(testCase, stepID)
+	if err != nil {
+		return nil, err
+	}
+
+	step.Action = input.Action
+	step.ExpectedResult = input.ExpectedResult
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reviseTestCase(tx, testCase.TestCaseID, userID, nil, func() error {
+			if err := tx.Save(step).Error; err != nil {
+				return err
+			}
+			return touchTestCase(tx, testCase.TestCaseID)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update step: %v", err)
+	}
+
+	return step, nil
+}
+
+// DeleteStep removes a step and closes the gap it leaves in the ordering.
+func (s *TestCaseService) DeleteStep(ctx context.Context, userID, projectID, testCaseID, stepID uuid.UUID) error {
+	testCase, err := s.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return err
+	}
+	step, err := findStep(testCase, stepID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reviseTestCase(tx, testCase.TestCaseID, userID, nil, func() error {
+			if err := tx.Delete(step).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&models.TestStep{}).
+				Where("test_case_id = ? AND position > ?", testCase.TestCaseID, step.Position).
+				Update("position", gorm.Expr("position - 1")).Error
+			if err != nil {
+				return err
+			}
+			return touchTestCase(tx, testCase.TestCaseID)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete step: %v", err)
+	}
+	return nil
+}
+
+// ReorderSteps sets the order of the steps of a test case. stepIDs must
+// contain every step of the case exactly once.
+func (s *TestCaseService) ReorderSteps(ctx context.Context, userID, projectID, testCaseID uuid.UUID, stepIDs []uuid.UUID) (*models.TestCase, error) {
+	testCase, err := s.editTestCase(ctx, userID, projectID, testCaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stepIDs) != len(testCase.Steps) {
+		return nil, fmt.Errorf("%w: step_ids must list all %d steps", ErrInvalidInput, len(testCase.Steps))
+	}
+	seen := make(map[uuid.UUID]bool, len(stepIDs))
+	for _, id := range stepIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: step %s is listed twice", ErrInvalidInput, id)
+		}
+		if _, err := findStep(testCase, id); err != nil {
+			return nil, fmt.Errorf("%w: step %s does not belong to the test case", ErrInvalidInput, id)
+		}
+		seen[id] = true
+	}
+
+	err = s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return reviseTestCase(tx, testCase.TestCaseID, userID, nil, func() error {
+			for i, id := range stepIDs {
+				err := tx.Model(&models.TestStep{}).
+					Where("test_step_id = ?", id).
+					Update("position", i+1).Error
+				if err != nil {
+					return err
+				}
+			}
+			return touchTestCase(tx, testCase.TestCaseID)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reorder steps: %v", err)
+	}
+
+	return s.getTestCase(ctx, projectID, testCaseID)
+}
+
+// newTestCase builds a test case and its steps from validated input.
+func newTestCase(userID, projectID uuid.UUID, input TestCaseInput) *models.TestCase {
+	testCase := &models.TestCase{
+		TestCaseID:    uuid.New(),
+		ProjectID:     projectID,
+		AuthorID:      userID,
+		Title:         input.Title,
+		Description:   input.Description,
+		Preconditions: input.Preconditions,
+		UserLevel:     input.UserLevel,
+		Priority:      input.Priority,
+		Status:        input.Status,
+	}
+	for i, step := range input.Steps {
+		testCase.Steps = append(testCase.Steps, models.TestStep{
+			TestStepID:     uuid.New(),
+			TestCaseID:     testCase.TestCaseID,
+			Position:       i + 1,
+			Action:         step.Action,
+			ExpectedResult: step.ExpectedResult,
+		})
+	}
+	return testCase
+}
+
+// createTestCase inserts a test case built by newTestCase with the named
+// tags, creating missing tags, and records its first revision. Steps are
+// created together with the case as a GORM association.
+func createTestCase(tx *gorm.DB, testCase *models.TestCase, tagNames []string) error {
+	tags, err := resolveTags(tx, testCase.ProjectID, tagNames)
+	if err != nil {
+		return err
+	}
+	testCase.Tags = tags
+	if err := tx.Create(testCase).Error; err != nil {
+		return err
+	}
+	return recordRevision(tx, testCase.TestCaseID, testCase.AuthorID, nil)
+}
+
+func orderSteps(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func orderTags(db *gorm.DB) *gorm.DB {
+	return db.Order("name ASC")
+}
+
+// withTags restricts a test case query to the cases tagged with any, or with
+// matchAll every, one of the tag names. It does nothing without tags.
+func withTags(projectID uuid.UUID, tags []string, matchAll bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(tags) == 0 {
+			return db
+		}
+		tagged := db.Session(&gorm.Session{NewDB: true}).
+			Table("test_case_tags").
+			Select("test_case_tags.test_case_id").
+			Joins("JOIN tags ON tags.tag_id = test_case_tags.tag_id").
+			Where("tags.project_id = ? AND tags.name IN ?", projectID, tags)
+		if matchAll {
+			tagged = tagged.
+				Group("test_case_tags.test_case_id").
+				Having("COUNT(DISTINCT tags.tag_id) = ?", len(uniqueFold(tags)))
+		}
+		return db.Where("test_cases.test_case_id IN (?)", tagged)
+	}
+}
+
+// fullTextQuery turns free text into a MySQL boolean mode query that
+// requires every word, matching word prefixes. Operators in the input are
+// dropped so user text cannot produce a syntax error.
+func fullTextQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, "+"+word+"*")
+	}
+	return strings.Join(terms, " ")
+}
+
+// uniqueFold removes case-insensitive duplicates, matching how MySQL compares tag names.
+func uniqueFold(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		key := strings.ToLower(v)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+func findStep(testCase *models.TestCase, stepID uuid.UUID) (*models.TestStep, error) {
+	for i := range testCase.Steps {
+		if testCase.Steps[i].TestStepID == stepID {
+			return &testCase.Steps[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: step", ErrNotFound)
+}
+
+// touchTestCase bumps the updated_at timestamp of a test case after one of
+// its steps changed.
+func touchTestCase(tx *gorm.DB, testCaseID uuid.UUID) error {
+	return tx.Model(&models.TestCase{}).
+		Where("test_case_id = ?", testCaseID).
+		Update("updated_at", time.Now()).Error
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"TestAlchemy/internal/models"
+)
+
+func TestValidateTestCase(t *testing.T) {
+	s := &TestCaseService{}
+	position := 0
+
+	tests := []struct {
+		name    string
+		input   TestCaseInput
+		wantErr bool
+	}{
+		{name: "valid", input: TestCaseInput{Title: "Login", Priority: models.PriorityHigh}},
+		{name: "missing title", input: TestCaseInput{Title: "   "}, wantErr: true},
+		{name: "unknown priority", input: TestCaseInput{Title: "Login", Priority: "urgent"}, wantErr: true},
+		{name: "unknown status", input: TestCaseInput{Title: "Login", Status: "done"}, wantErr: true},
+		{name: "step without action", input: TestCaseInput{Title: "Login", Steps: []TestStepInput{{ExpectedResult: "ok"}}}, wantErr: true},
+		{name: "step with bad position", input: TestCaseInput{Title: "Login", Steps: []TestStepInput{{Action: "click", Position: &position}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.ValidateTestCase(&tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("expected ErrInvalidInput, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTestCaseDefaults(t *testing.T) {
+	s := &TestCaseService{}
+	input := TestCaseInput{Title: "  Login  "}
+
+	if err := s.ValidateTestCase(&input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if input.Title != "Login" {
+		t.Errorf("expected title to be trimmed, got %q", input.Title)
+	}
+	if input.Priority != models.PriorityMedium {
+		t.Errorf("expected default priority %q, got %q", models.PriorityMedium, input.Priority)
+	}
+	if input.Status != models.StatusDraft {
+		t.Errorf("expected default status %q, got %q", models.StatusDraft, input.Status)
+	}
+}
+
+func TestFullTextQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"login", "+login*"},
+		{"  login   page ", "+login* +page*"},
+		{"+drop -table \"quoted\" (x*)", "+drop* +table* +quoted* +x*"},
+		{"***", ""},
+	}
+
+	for _, tt := range tests {
+		if got := fullTextQuery(tt.text); got != tt.want {
+			t.Errorf("fullTextQuery(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestListTestCasesByTags(t *testing.T) {
+	db := startDB(t)
+	ctx := context.Background()
+	s := NewTestCaseService(db, NewProjectService(db))
+	owner := createUser(t, db)
+	project := createProject(t, db, owner, nil)
+	for title, tags := range map[string][]string{
+		"A": {"api", "smoke"},
+		"B": {"api"},
+		"C": {"smoke"},
+		"D": nil,
+	} {
+		if _, err := s.CreateTestCase(ctx, owner.UserID, project.ProjectID, TestCaseInput{Title: title, Tags: tags}); err != nil {
+			t.Fatalf("CreateTestCase() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter TestCaseFilter
+		want   []string
+	}{
+		{"no tags", TestCaseFilter{}, []string{"A", "B", "C", "D"}},
+		{"any tag", TestCaseFilter{Tags: []string{"api", "smoke"}}, []string{"A", "B", "C"}},
+		{"every tag", TestCaseFilter{Tags: []string{"api", "smoke"}, MatchAll: true}, []string{"A"}},
+		{"every tag, repeated in another case", TestCaseFilter{Tags: []string{"API", "api", "smoke"}, MatchAll: true}, []string{"A"}},
+		{"every tag, one unknown", TestCaseFilter{Tags: []string{"api", "ui"}, MatchAll: true}, []string{}},
+		{"unknown tag", TestCaseFilter{Tags: []string{"ui"}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCases, err := s.ListTestCases(ctx, owner.UserID, project.ProjectID, tt.filter)
+			if err != nil {
+				t.Fatalf("ListTestCases() error = %v", err)
+			}
+			got := []string{}
+			for _, testCase := range testCases {
+				got = append(got, testCase.Title)
+			}
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
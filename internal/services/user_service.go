@@ -2,26 +2,76 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/mail"
+	"net/url"
 	"strings"
+	"time"
 
 	"TestAlchemy/internal/database"
+	"TestAlchemy/internal/mailer"
 	"TestAlchemy/internal/models"
 	"TestAlchemy/internal/session"
+	"TestAlchemy/internal/throttle"
+	"TestAlchemy/internal/tokens"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	mfaPendingTTL        = 5 * time.Minute
+	// maxMFAAttempts is the number of wrong codes after which an MFA
+	// pending token is discarded and the login must start over
+	maxMFAAttempts = 5
+	// verificationInterval is the minimum time between two verification
+	// emails sent to the same address
+	verificationInterval = time.Minute
+)
+
+// Failed logins are counted per account and per client address. An
+// address gets more attempts, as it may be shared by many users.
+var (
+	accountLoginPolicy = throttle.Policy{
+		Limit:      5,
+		Lockout:    time.Minute,
+		MaxLockout: time.Hour,
+		Window:     24 * time.Hour,
+	}
+	addressLoginPolicy = throttle.Policy{
+		Limit:      20,
+		Lockout:    time.Minute,
+		MaxLockout: time.Hour,
+		Window:     time.Hour,
+	}
 )
 
 type UserService struct {
-	db      database.Service
-	session *session.Store
+	db          database.Service
+	keydb       database.KeyDBService
+	session     *session.Store
+	mailer      mailer.Mailer
+	invitations *InvitationService
+	mfa         *MFAService
+	audit       *AuditService
+	accounts    *throttle.Throttle
+	addresses   *throttle.Throttle
 }
 
-func NewUserService(db database.Service, sessionStore *session.Store) *UserService {
+func NewUserService(db database.Service, keydb database.KeyDBService, sessionStore *session.Store, m mailer.Mailer, invitations *InvitationService, mfa *MFAService, audit *AuditService) *UserService {
 	return &UserService{
-		db:      db,
-		session: sessionStore,
+		db:          db,
+		keydb:       keydb,
+		session:     sessionStore,
+		mailer:      m,
+		invitations: invitations,
+		mfa:         mfa,
+		audit:       audit,
+		accounts:    throttle.New(keydb.Client(), "login_failures:account", accountLoginPolicy),
+		addresses:   throttle.New(keydb.Client(), "login_failures:ip", addressLoginPolicy),
 	}
 }
 
@@ -33,6 +83,46 @@ type RegisterUserInput struct {
 type LoginUserInput struct {
 	Email    string
 	Password string
+	// SessionID is the session the client already has, if any. It is
+	// replaced by the new session.
+	SessionID string `json:"-"`
+}
+
+// LoginResult is the outcome of a successful password check. Users with
+// two-factor authentication get an MFA token to pass to CompleteMFALogin
+// instead of a session.
+type LoginResult struct {
+	SessionID   string `json:"-"`
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+	// SessionID is the session the client already has, if any. It is
+	// replaced by the new session.
+	SessionID string `json:"-"`
+}
+
+// mfaPending is a login waiting for a second factor. Only the hash of its
+// token is stored, as the key of the record. The codes tried are counted
+// under a separate key, see mfaAttemptsKey.
+type mfaPending struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
 }
 
 func (s *UserService) ValidateRegistration(input RegisterUserInput) error {
@@ -68,20 +158,23 @@ func (s *UserService) ValidateRegistration(input RegisterUserInput) error {
 		return errors.New("invalid top-level domain")
 	}
 
-	// Validate password
-	if len(input.Password) < 8 {
+	return validatePassword(input.Password)
+}
+
+func validatePassword(password string) error {
+	if len(password) < 8 {
 		return errors.New("password must be at least 8 characters long")
 	}
-	if len(input.Password) > 256 {
+	if len(password) > 256 {
 		return errors.New("password must be at most 256 characters long")
 	}
-	if !strings.ContainsAny(input.Password, "0123456789") {
+	if !strings.ContainsAny(password, "0123456789") {
 		return errors.New("password must contain at least one number")
 	}
-	if strings.ToLower(input.Password) == input.Password {
+	if strings.ToLower(password) == password {
 		return errors.New("password must contain at least one uppercase letter")
 	}
-	if !strings.ContainsAny(input.Password, "!@#$%^&*()_+-=[]{}|;:'\",<.>/?") {
+	if !strings.ContainsAny(password, "!@#$%^&*()_+-=[]{}|;:'\",<.>/?") {
 		return errors.New("password must contain at least one special character")
 	}
 
@@ -106,25 +199,481 @@ func (s *UserService) RegisterUser(ctx context.Context, input RegisterUserInput)
 	if err != nil {
 		return errors.New("registration failed")
 	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserRegister,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+	})
+
+	// The account cannot be used until the address is verified. A failure
+	// to send the email is not fatal, as the user can ask for another one.
+	if _, err := s.allowVerificationEmail(ctx, user.Email); err != nil {
+		log.Printf("failed to rate limit verification email for user %s: %v", user.UserID, err)
+	}
+	if err := s.sendVerification(ctx, user); err != nil {
+		log.Printf("failed to send verification email to user %s: %v", user.UserID, err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the address of a user as verified using a token sent
+// on registration, and adds the user to the projects they were invited to.
+// Invitations are only linked now, since they are matched by address.
+func (s *UserService) VerifyEmail(ctx context.Context, input VerifyEmailInput) error {
+	if input.Token == "" {
+		return fmt.Errorf("%w: token is required", ErrInvalidInput)
+	}
+	userID, err := takeKey(ctx, s.keydb, emailVerificationKey(tokens.Hash(input.Token)))
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get email verification: %v", err)
+	}
+
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", userID); err != nil {
+		return fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	user.EmailVerified = true
+	if err := s.db.Update(ctx, &user); err != nil {
+		return fmt.Errorf("failed to verify email: %v", err)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserVerifyEmail,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+	})
+
+	if err := s.invitations.LinkPendingInvitations(ctx, user.UserID, user.Email); err != nil {
+		log.Printf("failed to link pending invitations for user %s: %v", user.UserID, err)
+	}
+	return nil
+}
+
+// ResendVerification sends a new verification email to an unverified
+// user. At most one email is sent to an address per verificationInterval.
+// Unknown and already verified addresses are silently ignored, so the
+// response does not tell whether an account exists.
+func (s *UserService) ResendVerification(ctx context.Context, input ResendVerificationInput) error {
+	allowed, err := s.allowVerificationEmail(ctx, input.Email)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("%w: wait a minute before asking for another email", ErrRateLimited)
+	}
 
+	var user models.User
+	if err := s.db.Read(ctx, &user, "email = ?", input.Email); err != nil || user.EmailVerified {
+		return nil
+	}
+	return s.sendVerification(ctx, &user)
+}
+
+// allowVerificationEmail reports whether a verification email may be sent
+// to an address, and if so prevents another one for verificationInterval.
+func (s *UserService) allowVerificationEmail(ctx context.Context, email string) (bool, error) {
+	key := fmt.Sprintf("email_verification:sent:%s", strings.ToLower(email))
+	allowed, err := s.keydb.Client().SetNX(ctx, key, 1, verificationInterval).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to rate limit verification email: %v", err)
+	}
+	return allowed, nil
+}
+
+func (s *UserService) sendVerification(ctx context.Context, user *models.User) error {
+	token := tokens.Random(32)
+	hash := tokens.Hash(token)
+	if err := s.keydb.Set(ctx, emailVerificationKey(hash), user.UserID.String(), emailVerificationTTL); err != nil {
+		return fmt.Errorf("failed to store email verification: %v", err)
+	}
+	if err := s.mailer.Send(ctx, verificationMessage(user.Email, token)); err != nil {
+		s.keydb.Delete(ctx, emailVerificationKey(hash))
+		return fmt.Errorf("failed to send email verification: %v", err)
+	}
 	return nil
 }
 
-func (s *UserService) Login(ctx context.Context, input LoginUserInput) (string, error) {
+func (s *UserService) Login(ctx context.Context, input LoginUserInput) (*LoginResult, error) {
+	// Locked out attempts are refused before the password is checked, so
+	// that they cannot be used to keep guessing
+	if err := s.checkLockout(ctx, input.Email); err != nil {
+		return nil, err
+	}
+
 	var user models.User
 	err := s.db.Read(ctx, &user, "email = ?", input.Email)
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		s.audit.Record(ctx, AuditEntry{
+			Action:     models.AuditUserLoginFailed,
+			TargetType: "user",
+			Metadata:   map[string]interface{}{"email": input.Email, "reason": "unknown email"},
+		})
+		s.countLoginFailure(ctx, input.Email, uuid.Nil)
+		return nil, errors.New("invalid email or password")
 	}
 
 	if !user.ValidatePassword(input.Password) {
-		return "", errors.New("invalid email or password")
+		s.audit.Record(ctx, AuditEntry{
+			ActorID:    user.UserID,
+			Action:     models.AuditUserLoginFailed,
+			TargetType: "user",
+			TargetID:   user.UserID.String(),
+			Metadata:   map[string]interface{}{"email": input.Email, "reason": "wrong password"},
+		})
+		s.countLoginFailure(ctx, input.Email, user.UserID)
+		return nil, errors.New("invalid email or password")
+	}
+	if !user.EmailVerified {
+		s.audit.Record(ctx, AuditEntry{
+			ActorID:    user.UserID,
+			Action:     models.AuditUserLoginFailed,
+			TargetType: "user",
+			TargetID:   user.UserID.String(),
+			Metadata:   map[string]interface{}{"email": input.Email, "reason": "email not verified"},
+		})
+		return nil, fmt.Errorf("%w: email address is not verified", ErrForbidden)
+	}
+
+	if user.MFAEnabled {
+		token, err := s.startMFALogin(ctx, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFARequired: true, MFAToken: token}, nil
 	}
 
-	sessionID, err := s.session.CreateSession(ctx, user.UserID)
+	sessionID, err := s.startSession(ctx, &user, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{SessionID: sessionID}, nil
+}
+
+// CompleteMFALogin finishes the login of a user with two-factor
+// authentication, given the token returned by Login and a TOTP or
+// recovery code. It returns the ID of the new session.
+func (s *UserService) CompleteMFALogin(ctx context.Context, input MFALoginInput) (string, error) {
+	if input.MFAToken == "" {
+		return "", fmt.Errorf("%w: mfa_token is required", ErrInvalidInput)
+	}
+	key := mfaPendingKey(tokens.Hash(input.MFAToken))
+	data, err := s.keydb.Get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get pending login: %v", err)
+	}
+	var pending mfaPending
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return "", fmt.Errorf("failed to unmarshal pending login: %v", err)
+	}
+
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", pending.UserID); err != nil {
+		return "", fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	}
+	if err := s.checkLockout(ctx, user.Email); err != nil {
+		return "", err
+	}
+	// The attempt is counted before the code is checked, so that parallel
+	// requests cannot try more than maxMFAAttempts codes
+	attempts, err := s.countMFAAttempt(ctx, input.MFAToken)
+	if err != nil {
+		return "", err
+	}
+	if attempts > maxMFAAttempts {
+		s.discardMFALogin(ctx, input.MFAToken)
+		return "", fmt.Errorf("%w: too many wrong codes, log in again", ErrInvalidInput)
+	}
+	if err := s.mfa.Verify(ctx, &user, input.Code); err != nil {
+		if !errors.Is(err, ErrInvalidInput) {
+			return "", err
+		}
+		s.audit.Record(ctx, AuditEntry{
+			ActorID:    user.UserID,
+			Action:     models.AuditUserLoginFailed,
+			TargetType: "user",
+			TargetID:   user.UserID.String(),
+			Metadata:   map[string]interface{}{"email": user.Email, "reason": "wrong mfa code"},
+		})
+		if attempts == maxMFAAttempts {
+			s.discardMFALogin(ctx, input.MFAToken)
+		}
+		s.countLoginFailure(ctx, user.Email, user.UserID)
+		return "", err
+	}
+
+	// Only one request may turn the token into a session
+	if _, err := takeKey(ctx, s.keydb, key); errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("%w: invalid or expired token", ErrInvalidInput)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get pending login: %v", err)
+	}
+	s.keydb.Delete(ctx, mfaAttemptsKey(tokens.Hash(input.MFAToken)))
+	return s.startSession(ctx, &user, input.SessionID)
+}
+
+// checkLockout returns a LockedError while the account or the client
+// address is locked out.
+func (s *UserService) checkLockout(ctx context.Context, email string) error {
+	retryAfter, err := s.accounts.Check(ctx, strings.ToLower(email))
+	if err != nil {
+		return err
+	}
+	if ip := ClientFromContext(ctx).IP; ip != "" {
+		addressRetryAfter, err := s.addresses.Check(ctx, ip)
+		if err != nil {
+			return err
+		}
+		if addressRetryAfter > retryAfter {
+			retryAfter = addressRetryAfter
+		}
+	}
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// countLoginFailure counts a failed login against the account and the
+// client address, recording any lockout it starts in the audit log.
+// userID is uuid.Nil for unknown accounts.
+func (s *UserService) countLoginFailure(ctx context.Context, email string, userID uuid.UUID) {
+	failure, err := s.accounts.Fail(ctx, strings.ToLower(email))
+	if err != nil {
+		log.Printf("failed to count login failure of %s: %v", email, err)
+	}
+	if failure.LockedFor > 0 {
+		s.recordLockout(ctx, userID, "account", email, failure)
+	}
+
+	ip := ClientFromContext(ctx).IP
+	if ip == "" {
+		return
+	}
+	failure, err = s.addresses.Fail(ctx, ip)
+	if err != nil {
+		log.Printf("failed to count login failure from %s: %v", ip, err)
+	}
+	if failure.LockedFor > 0 {
+		s.recordLockout(ctx, userID, "ip", ip, failure)
+	}
+}
+
+// recordLockout records a lockout of an account or of a client address,
+// identified by key.
+func (s *UserService) recordLockout(ctx context.Context, userID uuid.UUID, scope, key string, failure throttle.Failure) {
+	field := "email"
+	if scope == "ip" {
+		field = "ip"
+	}
+	entry := AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditUserLockout,
+		TargetType: "user",
+		Metadata: map[string]interface{}{
+			"scope":      scope,
+			field:        key,
+			"failures":   failure.Failures,
+			"locked_for": int(failure.LockedFor.Seconds()),
+		},
+	}
+	if userID != uuid.Nil {
+		entry.TargetID = userID.String()
+	}
+	s.audit.Record(ctx, entry)
+}
+
+func (s *UserService) startMFALogin(ctx context.Context, userID uuid.UUID) (string, error) {
+	data, err := json.Marshal(mfaPending{UserID: userID})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pending login: %v", err)
+	}
+	token := tokens.Random(32)
+	if err := s.keydb.Set(ctx, mfaPendingKey(tokens.Hash(token)), data, mfaPendingTTL); err != nil {
+		return "", fmt.Errorf("failed to store pending login: %v", err)
+	}
+	return token, nil
+}
+
+// countMFAAttempt counts a code tried against a pending login and returns
+// the number of codes tried so far. The counter outlives the pending login
+// by at most mfaPendingTTL.
+func (s *UserService) countMFAAttempt(ctx context.Context, token string) (int64, error) {
+	attempts, err := countKey(ctx, s.keydb, mfaAttemptsKey(tokens.Hash(token)), mfaPendingTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count MFA attempt: %v", err)
+	}
+	return attempts, nil
+}
+
+// discardMFALogin deletes a pending login, which must then start over.
+func (s *UserService) discardMFALogin(ctx context.Context, token string) {
+	hash := tokens.Hash(token)
+	if err := s.keydb.Client().Del(ctx, mfaPendingKey(hash), mfaAttemptsKey(hash)).Err(); err != nil {
+		log.Printf("failed to delete pending login: %v", err)
+	}
+}
+
+// startSession creates a session for a user who passed every login step.
+// previousID is the session the client already had, if any.
+func (s *UserService) startSession(ctx context.Context, user *models.User, previousID string) (string, error) {
+	client := ClientFromContext(ctx)
+	sessionID, err := s.session.CreateSession(ctx, user.UserID, session.Client{IP: client.IP, UserAgent: client.UserAgent})
 	if err != nil {
 		return "", fmt.Errorf("failed to create session: %v", err)
 	}
+	if err := s.accounts.Reset(ctx, strings.ToLower(user.Email)); err != nil {
+		log.Printf("failed to reset login failures of user %s: %v", user.UserID, err)
+	}
+	// Never keep using a session ID from before the login, which could have
+	// been planted by someone else
+	if previousID != "" {
+		if err := s.session.DeleteSession(ctx, previousID); err != nil {
+			log.Printf("failed to delete previous session of user %s: %v", user.UserID, err)
+		}
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    user.UserID,
+		Action:     models.AuditUserLogin,
+		TargetType: "user",
+		TargetID:   user.UserID.String(),
+	})
 
 	return sessionID, nil
 }
+
+// Logout deletes a session. Unknown sessions are ignored, so logging out
+// twice is not an error.
+func (s *UserService) Logout(ctx context.Context, sessionID string) error {
+	sess, err := s.session.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if sess == nil {
+		return nil
+	}
+	if err := s.session.DeleteSession(ctx, sessionID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    sess.UserID,
+		Action:     models.AuditUserLogout,
+		TargetType: "user",
+		TargetID:   sess.UserID.String(),
+	})
+	return nil
+}
+
+// ListSessions returns the active sessions of a user. currentID is the
+// session making the request.
+func (s *UserService) ListSessions(ctx context.Context, userID uuid.UUID, currentID string) ([]session.Info, error) {
+	return s.session.ListSessions(ctx, userID, currentID)
+}
+
+// RevokeSession logs the user out of one of their sessions, identified by
+// its session.Info ID.
+func (s *UserService) RevokeSession(ctx context.Context, userID uuid.UUID, id string) error {
+	found, err := s.session.RevokeSession(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: session", ErrNotFound)
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditSessionRevoke,
+		TargetType: "session",
+		TargetID:   id,
+	})
+	return nil
+}
+
+// RevokeOtherSessions logs the user out everywhere but in the current
+// session, returning how many sessions were revoked.
+func (s *UserService) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, currentID string) (int, error) {
+	revoked, err := s.session.RevokeSessions(ctx, userID, currentID)
+	if err != nil {
+		return 0, err
+	}
+	if revoked > 0 {
+		s.audit.Record(ctx, AuditEntry{
+			ActorID:    userID,
+			Action:     models.AuditSessionRevoke,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			Metadata:   map[string]interface{}{"revoked": revoked},
+		})
+	}
+	return revoked, nil
+}
+
+// ChangePassword replaces the password of a user after checking the
+// current one. Every other session of the user is revoked and the current
+// session is rotated; the new session ID is returned.
+func (s *UserService) ChangePassword(ctx context.Context, userID uuid.UUID, sessionID string, input ChangePasswordInput) (string, error) {
+	var user models.User
+	if err := s.db.Read(ctx, &user, "user_id = ?", userID); err != nil {
+		return "", fmt.Errorf("failed to get user: %v", err)
+	}
+	if !user.ValidatePassword(input.CurrentPassword) {
+		return "", fmt.Errorf("%w: current password is incorrect", ErrInvalidInput)
+	}
+	if err := validatePassword(input.NewPassword); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	if err := user.HashPassword(input.NewPassword); err != nil {
+		return "", err
+	}
+	if err := s.db.Update(ctx, &user); err != nil {
+		return "", fmt.Errorf("failed to update password: %v", err)
+	}
+
+	if _, err := s.session.RevokeSessions(ctx, userID, sessionID); err != nil {
+		return "", err
+	}
+	newSessionID, err := s.session.RotateSession(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	s.audit.Record(ctx, AuditEntry{
+		ActorID:    userID,
+		Action:     models.AuditUserPasswordChange,
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+	return newSessionID, nil
+}
+
+func verificationMessage(email, token string) mailer.Message {
+	return mailer.Message{
+		To:      []string{email},
+		Subject: "Verify your Test Alchemy email address",
+		Body: fmt.Sprintf("Welcome to Test Alchemy! Verify your email address to start using your account:\n%s/verify?token=%s\n\n"+
+			"The link expires in %d hours. If you did not create an account, you can ignore this email.\n",
+			appBaseURL, url.QueryEscape(token), int(emailVerificationTTL.Hours())),
+	}
+}
+
+func mfaPendingKey(hash string) string {
+	return fmt.Sprintf("mfa_pending:%s", hash)
+}
+
+func mfaAttemptsKey(hash string) string {
+	return fmt.Sprintf("mfa_pending:%s:attempts", hash)
+}
+
+func emailVerificationKey(hash string) string {
+	return fmt.Sprintf("email_verification:%s", hash)
+}
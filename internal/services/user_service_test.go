@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		wantErr  bool
+	}{
+		{"Secret-123", false},
+		{"Se-1", true},
+		{"secret-123", true},
+		{"Secret-abc", true},
+		{"Secret1234", true},
+		{strings.Repeat("Aa1!", 65), true},
+	}
+
+	for _, tt := range tests {
+		if err := validatePassword(tt.password); (err != nil) != tt.wantErr {
+			t.Errorf("validatePassword(%q) = %v, want error %v", tt.password, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerificationMessage(t *testing.T) {
+	message := verificationMessage("user@example.com", "token-value")
+
+	if len(message.To) != 1 || message.To[0] != "user@example.com" {
+		t.Errorf("unexpected recipients %v", message.To)
+	}
+	if !strings.Contains(message.Body, appBaseURL+"/verify?token=token-value") {
+		t.Error("expected the body to link to the verification page with the token")
+	}
+}
+
+func TestLockedError(t *testing.T) {
+	err := fmt.Errorf("login: %w", &LockedError{RetryAfter: time.Minute})
+
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("expected a LockedError to match ErrRateLimited")
+	}
+	var locked *LockedError
+	if !errors.As(err, &locked) || locked.RetryAfter != time.Minute {
+		t.Errorf("expected to unwrap the retry delay, got %v", locked)
+	}
+}
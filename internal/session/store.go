@@ -6,24 +6,79 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"TestAlchemy/internal/tokens"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// CookieName is the name of the cookie holding the session ID
+	CookieName = "session_id"
+
+	DefaultIdleTimeout     = 2 * time.Hour
+	DefaultAbsoluteTimeout = 7 * 24 * time.Hour
+
+	// touchInterval is how often Touch writes the last activity of a
+	// session, so busy sessions are not rewritten on every request
+	touchInterval = time.Minute
+)
+
+// Store keeps sessions in KeyDB. A session expires after idleTimeout
+// without activity, and after absoluteTimeout in any case.
 type Store struct {
-	client *redis.Client
+	client          *redis.Client
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
 }
 
 type Session struct {
-	UserID    uuid.UUID `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+	UserID     uuid.UUID `json:"user_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Client describes the device a session was created from.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// Info describes an active session of a user. ID is a hash of the session
+// ID, so listing sessions does not reveal the secret IDs.
+type Info struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
 }
 
+// NewStore connects to KeyDB at KEYDB_ADDR. The timeouts are read from
+// SESSION_IDLE_TIMEOUT and SESSION_ABSOLUTE_TIMEOUT as Go durations, such
+// as "30m" or "12h".
 func NewStore() (*Store, error) {
+	idleTimeout, err := durationEnv("SESSION_IDLE_TIMEOUT", DefaultIdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	absoluteTimeout, err := durationEnv("SESSION_ABSOLUTE_TIMEOUT", DefaultAbsoluteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if idleTimeout > absoluteTimeout {
+		return nil, fmt.Errorf("SESSION_IDLE_TIMEOUT must not exceed SESSION_ABSOLUTE_TIMEOUT")
+	}
+
 	addr := os.Getenv("KEYDB_ADDR")
 	if addr == "" {
 		addr = "localhost:6379"
@@ -37,19 +92,37 @@ func NewStore() (*Store, error) {
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	
+
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to connect to KeyDB: %v", err)
 	}
 
-	return &Store{client: client}, nil
+	return &Store{
+		client:          client,
+		idleTimeout:     idleTimeout,
+		absoluteTimeout: absoluteTimeout,
+	}, nil
 }
 
-func (s *Store) CreateSession(ctx context.Context, userID uuid.UUID) (string, error) {
+// NewStoreWithClient returns a store using an existing client, for
+// instance one connected to a KeyDB test container.
+func NewStoreWithClient(client *redis.Client, idleTimeout, absoluteTimeout time.Duration) *Store {
+	return &Store{
+		client:          client,
+		idleTimeout:     idleTimeout,
+		absoluteTimeout: absoluteTimeout,
+	}
+}
+
+func (s *Store) CreateSession(ctx context.Context, userID uuid.UUID, client Client) (string, error) {
+	now := time.Now()
 	session := Session{
-		UserID:    userID,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(24 * time.Hour), // Sessions expire after 24 hours
+		UserID:     userID,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.absoluteTimeout),
 	}
 
 	sessionData, err := json.Marshal(session)
@@ -57,8 +130,15 @@ func (s *Store) CreateSession(ctx context.Context, userID uuid.UUID) (string, er
 		return "", fmt.Errorf("failed to marshal session: %v", err)
 	}
 
+	// Index the session under its user, so all sessions of a user can be
+	// listed and revoked. The index lives as long as the newest session.
 	sessionID := generateSessionID()
-	err = s.client.Set(ctx, sessionKey(sessionID), sessionData, 24*time.Hour).Err()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(sessionID), sessionData, s.ttl(&session, now))
+		pipe.SAdd(ctx, userSessionsKey(userID), sessionID)
+		pipe.Expire(ctx, userSessionsKey(userID), s.absoluteTimeout)
+		return nil
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to store session: %v", err)
 	}
@@ -80,7 +160,7 @@ func (s *Store) GetSession(ctx context.Context, sessionID string) (*Session, err
 		return nil, fmt.Errorf("failed to unmarshal session: %v", err)
 	}
 
-	if time.Now().After(session.ExpiresAt) {
+	if s.expired(&session, time.Now()) {
 		s.DeleteSession(ctx, sessionID)
 		return nil, nil
 	}
@@ -88,14 +168,278 @@ func (s *Store) GetSession(ctx context.Context, sessionID string) (*Session, err
 	return &session, nil
 }
 
+// Touch records activity on a session, sliding its idle expiry. It writes
+// at most once per touchInterval.
+//
+// Sessions created before sessions were indexed by user have no recorded
+// activity, so they are added to the index on their first refresh.
+func (s *Store) Touch(ctx context.Context, sessionID string, session *Session) error {
+	now := time.Now()
+	if now.Sub(session.lastSeen()) < touchInterval {
+		return nil
+	}
+
+	unindexed := session.LastSeenAt.IsZero()
+	session.LastSeenAt = now
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %v", err)
+	}
+	// SetXX only overwrites an existing key, so a session revoked in the
+	// meantime is not brought back. Its ID may still be indexed, which
+	// userSessions prunes.
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetXX(ctx, sessionKey(sessionID), sessionData, s.ttl(session, now))
+		if unindexed {
+			pipe.SAdd(ctx, userSessionsKey(session.UserID), sessionID)
+			pipe.Expire(ctx, userSessionsKey(session.UserID), s.absoluteTimeout)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refresh session: %v", err)
+	}
+	return nil
+}
+
+// RotateSession replaces a session with a new one for the same user and
+// client, returning the new session ID. Sessions are rotated whenever the
+// privileges behind them change, so an ID obtained before cannot be used
+// afterwards.
+func (s *Store) RotateSession(ctx context.Context, sessionID string) (string, error) {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if session == nil {
+		return "", fmt.Errorf("session not found")
+	}
+
+	newID, err := s.CreateSession(ctx, session.UserID, Client{IP: session.IP, UserAgent: session.UserAgent})
+	if err != nil {
+		return "", err
+	}
+	if err := s.DeleteSession(ctx, sessionID); err != nil {
+		return "", err
+	}
+	return newID, nil
+}
+
+// Cookie returns the cookie carrying a session ID. It lives as long as the
+// session can; the idle timeout is enforced by the store.
+func (s *Store) Cookie(sessionID string) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(s.absoluteTimeout.Seconds()),
+		Expires:  time.Now().Add(s.absoluteTimeout),
+	}
+}
+
+// ClearCookie returns a cookie that removes the session cookie.
+func (s *Store) ClearCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	}
+}
+
+// ttl returns how long a session is kept in KeyDB from now: until it is
+// idle for too long, but never beyond its absolute expiry.
+func (s *Store) ttl(session *Session, now time.Time) time.Duration {
+	ttl := s.idleTimeout
+	if remaining := session.ExpiresAt.Sub(now); remaining < ttl {
+		ttl = remaining
+	}
+	return ttl
+}
+
+func (s *Store) expired(session *Session, now time.Time) bool {
+	return now.After(session.ExpiresAt) || now.Sub(session.lastSeen()) > s.idleTimeout
+}
+
+// lastSeen returns the last activity on the session. Sessions created
+// before activity was tracked count from their creation.
+func (session *Session) lastSeen() time.Time {
+	if session.LastSeenAt.IsZero() {
+		return session.CreatedAt
+	}
+	return session.LastSeenAt
+}
+
+// DeleteSession deletes a session and removes it from the index of its user.
 func (s *Store) DeleteSession(ctx context.Context, sessionID string) error {
-	return s.client.Del(ctx, sessionKey(sessionID)).Err()
+	data, err := s.client.Get(ctx, sessionKey(sessionID)).Bytes()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get session: %v", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return s.client.Del(ctx, sessionKey(sessionID)).Err()
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(sessionID))
+		pipe.SRem(ctx, userSessionsKey(session.UserID), sessionID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %v", err)
+	}
+	return nil
+}
+
+// ListSessions returns the active sessions of a user, newest first.
+// currentID is the ID of the session making the request, which is flagged
+// as current.
+func (s *Store) ListSessions(ctx context.Context, userID uuid.UUID, currentID string) ([]Info, error) {
+	sessions, err := s.userSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(sessions))
+	for id, session := range sessions {
+		infos = append(infos, Info{
+			ID:         tokens.Hash(id),
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.lastSeen(),
+			ExpiresAt:  session.ExpiresAt,
+			Current:    id == currentID,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.After(infos[j].CreatedAt) })
+	return infos, nil
+}
+
+// RevokeSession deletes the session of a user with the given Info.ID. It
+// reports whether the session was found.
+func (s *Store) RevokeSession(ctx context.Context, userID uuid.UUID, infoID string) (bool, error) {
+	sessions, err := s.userSessions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for id := range sessions {
+		if tokens.Hash(id) == infoID {
+			return true, s.DeleteSession(ctx, id)
+		}
+	}
+	return false, nil
+}
+
+// RevokeSessions deletes every session of a user except keepID, which may
+// be empty to delete them all. It returns how many sessions were deleted.
+// Sessions are found through the index of the user, which sessions created
+// before the index existed only join once used; such sessions idle out
+// within the idle timeout, so flush the session keys on deploy when that
+// window matters.
+func (s *Store) RevokeSessions(ctx context.Context, userID uuid.UUID, keepID string) (int, error) {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %v", err)
+	}
+
+	var revoked []string
+	for _, id := range ids {
+		if id != keepID {
+			revoked = append(revoked, id)
+		}
+	}
+	if len(revoked) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(revoked))
+	members := make([]interface{}, len(revoked))
+	for i, id := range revoked {
+		keys[i] = sessionKey(id)
+		members[i] = id
+	}
+	var deleted *redis.IntCmd
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, keys...)
+		pipe.SRem(ctx, userSessionsKey(userID), members...)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	return int(deleted.Val()), nil
+}
+
+// userSessions returns the live sessions of a user by ID, dropping the IDs
+// of expired sessions from the index.
+func (s *Store) userSessions(ctx context.Context, userID uuid.UUID) (map[string]Session, error) {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	if len(ids) == 0 {
+		return map[string]Session{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %v", err)
+	}
+
+	sessions := make(map[string]Session, len(ids))
+	var expired []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		var session Session
+		if !ok || json.Unmarshal([]byte(data), &session) != nil || s.expired(&session, time.Now()) {
+			expired = append(expired, ids[i])
+			continue
+		}
+		sessions[ids[i]] = session
+	}
+	if len(expired) > 0 {
+		if err := s.client.SRem(ctx, userSessionsKey(userID), expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to prune sessions: %v", err)
+		}
+	}
+	return sessions, nil
 }
 
 func sessionKey(sessionID string) string {
 	return fmt.Sprintf("session:%s", sessionID)
 }
 
+func userSessionsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user_sessions:%s", userID)
+}
+
+func durationEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 30m or 12h", name)
+	}
+	return duration, nil
+}
+
 func generateSessionID() string {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
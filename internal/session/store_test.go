@@ -0,0 +1,284 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func skipWithoutDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+func startStore(t *testing.T) *Store {
+	t.Helper()
+	skipWithoutDocker(t)
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "eqalpha/keydb:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to start container: %v", err)
+	}
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: host + ":" + port.Port()})
+	t.Cleanup(func() { client.Close() })
+	return &Store{client: client, idleTimeout: time.Hour, absoluteTimeout: 24 * time.Hour}
+}
+
+func TestSessionIndex(t *testing.T) {
+	store := startStore(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	current, err := store.CreateSession(ctx, userID, Client{IP: "203.0.113.1", UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	other, err := store.CreateSession(ctx, userID, Client{IP: "203.0.113.2", UserAgent: "phone"})
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	third, err := store.CreateSession(ctx, userID, Client{IP: "203.0.113.3", UserAgent: "tablet"})
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if _, err := store.CreateSession(ctx, uuid.New(), Client{}); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	sessions, err := store.ListSessions(ctx, userID, current)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(sessions))
+	}
+	var currentInfo *Info
+	for i := range sessions {
+		if sessions[i].ID == current || sessions[i].ID == other {
+			t.Fatal("expected listed IDs not to reveal session IDs")
+		}
+		if sessions[i].Current {
+			currentInfo = &sessions[i]
+		}
+	}
+	if currentInfo == nil || currentInfo.UserAgent != "laptop" {
+		t.Fatalf("expected the laptop session to be current, got %+v", sessions)
+	}
+
+	// Revoke the phone session by its listed ID
+	for _, info := range sessions {
+		if info.UserAgent == "phone" {
+			found, err := store.RevokeSession(ctx, userID, info.ID)
+			if err != nil || !found {
+				t.Fatalf("RevokeSession() = %v, %v", found, err)
+			}
+		}
+	}
+	if sess, _ := store.GetSession(ctx, other); sess != nil {
+		t.Fatal("expected the revoked session to be gone")
+	}
+	if found, _ := store.RevokeSession(ctx, uuid.New(), currentInfo.ID); found {
+		t.Fatal("expected sessions of other users not to be revocable")
+	}
+
+	revoked, err := store.RevokeSessions(ctx, userID, current)
+	if err != nil || revoked != 1 {
+		t.Fatalf("RevokeSessions() = %d, %v, want 1", revoked, err)
+	}
+	if sess, _ := store.GetSession(ctx, third); sess != nil {
+		t.Fatal("expected other sessions to be revoked")
+	}
+	if sess, _ := store.GetSession(ctx, current); sess == nil {
+		t.Fatal("expected the current session to be kept")
+	}
+
+	if err := store.DeleteSession(ctx, current); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if sessions, _ := store.ListSessions(ctx, userID, ""); len(sessions) != 0 {
+		t.Fatalf("expected no sessions after logout, got %d", len(sessions))
+	}
+}
+
+func TestUnindexedSessionIsIndexedOnUse(t *testing.T) {
+	store := startStore(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// A session stored before sessions were indexed by user
+	created := time.Now().Add(-10 * time.Minute)
+	data, err := json.Marshal(Session{UserID: userID, CreatedAt: created, ExpiresAt: created.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := generateSessionID()
+	if err := store.client.Set(ctx, sessionKey(sessionID), data, time.Hour).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := store.GetSession(ctx, sessionID)
+	if err != nil || session == nil {
+		t.Fatalf("GetSession() = %+v, %v", session, err)
+	}
+	if err := store.Touch(ctx, sessionID, session); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	if sessions, _ := store.ListSessions(ctx, userID, sessionID); len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("expected the session to be listed once used, got %+v", sessions)
+	}
+	revoked, err := store.RevokeSessions(ctx, userID, "")
+	if err != nil || revoked != 1 {
+		t.Fatalf("RevokeSessions() = %d, %v, want 1", revoked, err)
+	}
+	if sess, _ := store.GetSession(ctx, sessionID); sess != nil {
+		t.Fatal("expected the session to be revoked")
+	}
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	store := &Store{idleTimeout: time.Hour, absoluteTimeout: 24 * time.Hour}
+	now := time.Now()
+	created := now.Add(-23*time.Hour - 30*time.Minute)
+	session := &Session{CreatedAt: created, LastSeenAt: now, ExpiresAt: created.Add(24 * time.Hour)}
+
+	if ttl := store.ttl(session, now); ttl != 30*time.Minute {
+		t.Errorf("expected the TTL to stop at the absolute expiry, got %v", ttl)
+	}
+	session.ExpiresAt = now.Add(10 * time.Hour)
+	if ttl := store.ttl(session, now); ttl != time.Hour {
+		t.Errorf("expected the TTL to be the idle timeout, got %v", ttl)
+	}
+
+	tests := []struct {
+		name     string
+		lastSeen time.Time
+		expires  time.Time
+		want     bool
+	}{
+		{"active", now.Add(-time.Minute), now.Add(time.Hour), false},
+		{"idle", now.Add(-2 * time.Hour), now.Add(time.Hour), true},
+		{"past absolute expiry", now, now.Add(-time.Second), true},
+	}
+	for _, tt := range tests {
+		session := &Session{CreatedAt: now.Add(-3 * time.Hour), LastSeenAt: tt.lastSeen, ExpiresAt: tt.expires}
+		if got := store.expired(session, now); got != tt.want {
+			t.Errorf("%s: expired() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	legacy := &Session{CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour)}
+	if !store.expired(legacy, now) {
+		t.Error("expected a session without activity to be idle since its creation")
+	}
+}
+
+func TestCookie(t *testing.T) {
+	store := &Store{idleTimeout: time.Hour, absoluteTimeout: 12 * time.Hour}
+
+	cookie := store.Cookie("abc")
+	if cookie.Name != CookieName || cookie.Value != "abc" || !cookie.HttpOnly || !cookie.Secure {
+		t.Errorf("unexpected cookie %+v", cookie)
+	}
+	if cookie.MaxAge != 12*60*60 {
+		t.Errorf("expected the cookie to live as long as the absolute timeout, got MaxAge %d", cookie.MaxAge)
+	}
+	if cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("expected a strict SameSite cookie, got %v", cookie.SameSite)
+	}
+	if cleared := store.ClearCookie(); cleared.MaxAge >= 0 || cleared.Value != "" {
+		t.Errorf("expected the cookie to be cleared, got %+v", cleared)
+	}
+}
+
+func TestDurationEnv(t *testing.T) {
+	t.Setenv("SESSION_TEST_TIMEOUT", "")
+	if d, err := durationEnv("SESSION_TEST_TIMEOUT", time.Hour); err != nil || d != time.Hour {
+		t.Errorf("expected the fallback, got %v, %v", d, err)
+	}
+	t.Setenv("SESSION_TEST_TIMEOUT", "90m")
+	if d, err := durationEnv("SESSION_TEST_TIMEOUT", time.Hour); err != nil || d != 90*time.Minute {
+		t.Errorf("expected 90m, got %v, %v", d, err)
+	}
+	for _, value := range []string{"soon", "-1h", "0s"} {
+		t.Setenv("SESSION_TEST_TIMEOUT", value)
+		if _, err := durationEnv("SESSION_TEST_TIMEOUT", time.Hour); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestTouchAndRotate(t *testing.T) {
+	store := startStore(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	sessionID, err := store.CreateSession(ctx, userID, Client{IP: "203.0.113.1", UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	// Pretend the session was last used a while ago
+	session, _ := store.GetSession(ctx, sessionID)
+	session.LastSeenAt = time.Now().Add(-30 * time.Minute)
+	store.client.Expire(ctx, sessionKey(sessionID), 30*time.Minute)
+	if err := store.Touch(ctx, sessionID, session); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	if ttl := store.client.TTL(ctx, sessionKey(sessionID)).Val(); ttl <= 59*time.Minute {
+		t.Errorf("expected Touch to slide the TTL back to the idle timeout, got %v", ttl)
+	}
+
+	rotated, err := store.RotateSession(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+	if rotated == sessionID {
+		t.Fatal("expected a new session ID")
+	}
+	if old, _ := store.GetSession(ctx, sessionID); old != nil {
+		t.Error("expected the old session to be gone")
+	}
+	session, _ = store.GetSession(ctx, rotated)
+	if session == nil || session.UserID != userID || session.UserAgent != "laptop" {
+		t.Fatalf("expected the new session to keep user and client, got %+v", session)
+	}
+
+	// A revoked session is not brought back by a late refresh
+	store.DeleteSession(ctx, rotated)
+	session.LastSeenAt = time.Now().Add(-10 * time.Minute)
+	if err := store.Touch(ctx, rotated, session); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	if revived, _ := store.GetSession(ctx, rotated); revived != nil {
+		t.Error("expected Touch not to recreate a deleted session")
+	}
+}
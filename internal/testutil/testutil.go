@@ -0,0 +1,127 @@
+// Package testutil starts the KeyDB and MySQL containers that tests run
+// against. Tests that need a container are skipped when Docker is not
+// available.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Credentials of the MySQL database started by StartMySQL.
+const (
+	MySQLDatabase = "database"
+	MySQLUser     = "user"
+	MySQLPassword = "password"
+)
+
+// SkipWithoutDocker skips tests that need containers when Docker is not
+// available. testcontainers panics when it cannot find a Docker host.
+func SkipWithoutDocker(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skipf("Docker is not available: %v", r)
+		}
+	}()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+// StartKeyDB starts a KeyDB container. It returns a function that
+// terminates the container, and the host and port it listens on.
+func StartKeyDB(ctx context.Context) (func(context.Context) error, string, string, error) {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "eqalpha/keydb:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to start container: %v", err)
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		return container.Terminate, "", "", fmt.Errorf("failed to get container host: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "6379")
+	if err != nil {
+		return container.Terminate, "", "", fmt.Errorf("failed to get container port: %v", err)
+	}
+	return container.Terminate, host, port.Port(), nil
+}
+
+// KeyDB starts an empty KeyDB for the test and returns its address. The
+// container is terminated when the test ends.
+func KeyDB(t *testing.T) string {
+	t.Helper()
+	SkipWithoutDocker(t)
+	terminate, host, port, err := StartKeyDB(context.Background())
+	if terminate != nil {
+		t.Cleanup(func() { terminate(context.Background()) })
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host + ":" + port
+}
+
+// StartMySQL starts a MySQL container with the MySQL* credentials. It
+// returns a function that terminates the container, and the host and port
+// it listens on.
+func StartMySQL(ctx context.Context) (func(context.Context) error, string, string, error) {
+	container, err := mysql.Run(ctx,
+		"mysql:8.0.36",
+		mysql.WithDatabase(MySQLDatabase),
+		mysql.WithUsername(MySQLUser),
+		mysql.WithPassword(MySQLPassword),
+		testcontainers.WithWaitStrategy(wait.ForLog("port: 3306  MySQL Community Server - GPL").WithStartupTimeout(30*time.Second)),
+	)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to start container: %v", err)
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		return container.Terminate, "", "", fmt.Errorf("failed to get container host: %v", err)
+	}
+	port, err := container.MappedPort(ctx, "3306/tcp")
+	if err != nil {
+		return container.Terminate, "", "", fmt.Errorf("failed to get container port: %v", err)
+	}
+	return container.Terminate, host, port.Port(), nil
+}
+
+var mysqlOnce struct {
+	sync.Once
+	dsn string
+	err error
+}
+
+// MySQL returns the DSN of a MySQL database for the test. MySQL is slow to
+// start, so one container is shared by the tests of a package; they must
+// not depend on the database being empty. The container is removed by the
+// testcontainers reaper when the tests end.
+func MySQL(t *testing.T) string {
+	t.Helper()
+	SkipWithoutDocker(t)
+	mysqlOnce.Do(func() {
+		var host, port string
+		_, host, port, mysqlOnce.err = StartMySQL(context.Background())
+		mysqlOnce.dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			MySQLUser, MySQLPassword, host, port, MySQLDatabase)
+	})
+	if mysqlOnce.err != nil {
+		t.Fatal(mysqlOnce.err)
+	}
+	return mysqlOnce.dsn
+}
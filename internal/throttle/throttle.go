@@ -0,0 +1,164 @@
+// Package throttle counts failed attempts in KeyDB and locks keys out for
+// exponentially longer periods once they fail too often.
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxRetries bounds the optimistic transaction of Fail under contention.
+const maxRetries = 10
+
+// Policy describes when and for how long a key is locked out.
+type Policy struct {
+	// Limit is the number of failures allowed before the first lockout
+	Limit int
+	// Lockout is the length of the first lockout. It doubles with every
+	// further failure, up to MaxLockout.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+}
+
+// Failure is the state of a key after a failed attempt.
+type Failure struct {
+	Failures int
+	// LockedFor is the lockout this failure started, zero if none
+	LockedFor time.Duration
+}
+
+type Throttle struct {
+	client *redis.Client
+	prefix string
+	policy Policy
+	// Now returns the current time. Tests replace it with a fake clock.
+	Now func() time.Time
+}
+
+// New returns a throttle that stores its counters under prefix.
+func New(client *redis.Client, prefix string, policy Policy) *Throttle {
+	return &Throttle{
+		client: client,
+		prefix: prefix,
+		policy: policy,
+		Now:    time.Now,
+	}
+}
+
+// state is stored as a hash, with times in Unix nanoseconds.
+type state struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Check returns how long the key remains locked out, zero if it is not.
+func (t *Throttle) Check(ctx context.Context, key string) (time.Duration, error) {
+	st, err := t.load(ctx, t.client, t.key(key))
+	if err != nil {
+		return 0, err
+	}
+	if remaining := st.LockedUntil.Sub(t.Now()); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// Fail counts a failed attempt of a key, locking it out once it has failed
+// more than Policy.Limit times within Policy.Window.
+func (t *Throttle) Fail(ctx context.Context, key string) (Failure, error) {
+	k := t.key(key)
+	var failure Failure
+	update := func(tx *redis.Tx) error {
+		st, err := t.load(ctx, tx, k)
+		if err != nil {
+			return err
+		}
+		now := t.Now()
+		if now.Sub(st.LastFailure) > t.policy.Window {
+			st = state{}
+		}
+		st.Failures++
+		st.LastFailure = now
+		failure = Failure{Failures: st.Failures}
+		if lockout := t.policy.lockout(st.Failures); lockout > 0 {
+			st.LockedUntil = now.Add(lockout)
+			failure.LockedFor = lockout
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, k,
+				"failures", st.Failures,
+				"last_failure", st.LastFailure.UnixNano(),
+				"locked_until", st.LockedUntil.UnixNano())
+			pipe.Expire(ctx, k, t.policy.Window+t.policy.MaxLockout)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxRetries; i++ {
+		err := t.client.Watch(ctx, update, k)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return Failure{}, fmt.Errorf("failed to count failure: %v", err)
+		}
+		return failure, nil
+	}
+	return Failure{}, errors.New("failed to count failure: too much contention")
+}
+
+// Reset forgets the failures of a key, lifting any lockout.
+func (t *Throttle) Reset(ctx context.Context, key string) error {
+	return t.client.Del(ctx, t.key(key)).Err()
+}
+
+func (t *Throttle) load(ctx context.Context, client redis.Cmdable, k string) (state, error) {
+	values, err := client.HGetAll(ctx, k).Result()
+	if err != nil {
+		return state{}, fmt.Errorf("failed to get failures: %v", err)
+	}
+	failures, _ := strconv.Atoi(values["failures"])
+	return state{
+		Failures:    failures,
+		LastFailure: unixNano(values["last_failure"]),
+		LockedUntil: unixNano(values["locked_until"]),
+	}, nil
+}
+
+func (t *Throttle) key(key string) string {
+	return t.prefix + ":" + key
+}
+
+// lockout returns the lockout that the given number of failures starts.
+func (p Policy) lockout(failures int) time.Duration {
+	excess := failures - p.Limit
+	if excess <= 0 {
+		return 0
+	}
+	lockout := p.Lockout
+	for i := 1; i < excess && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+func unixNano(value string) time.Time {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
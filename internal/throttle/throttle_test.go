@@ -0,0 +1,119 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"TestAlchemy/internal/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+var testPolicy = Policy{
+	Limit:      3,
+	Lockout:    time.Minute,
+	MaxLockout: 10 * time.Minute,
+	Window:     time.Hour,
+}
+
+func TestLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, time.Minute},
+		{5, 2 * time.Minute},
+		{6, 4 * time.Minute},
+		{7, 8 * time.Minute},
+		{8, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy.lockout(tt.failures); got != tt.want {
+			t.Errorf("lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func startThrottle(t *testing.T) (*Throttle, *fakeClock) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: testutil.KeyDB(t)})
+	t.Cleanup(func() { client.Close() })
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	throttle := New(client, "test", testPolicy)
+	throttle.Now = clock.Now
+	return throttle, clock
+}
+
+func TestFailAndCheck(t *testing.T) {
+	throttle, clock := startThrottle(t)
+	ctx := context.Background()
+
+	for i := 1; i <= testPolicy.Limit; i++ {
+		failure, err := throttle.Fail(ctx, "user@example.com")
+		if err != nil {
+			t.Fatalf("Fail() error = %v", err)
+		}
+		if failure.Failures != i || failure.LockedFor != 0 {
+			t.Fatalf("unexpected failure %+v after %d attempts", failure, i)
+		}
+	}
+
+	failure, err := throttle.Fail(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+	if failure.LockedFor != time.Minute {
+		t.Fatalf("expected a one minute lockout, got %v", failure.LockedFor)
+	}
+	if remaining, _ := throttle.Check(ctx, "user@example.com"); remaining != time.Minute {
+		t.Errorf("expected one minute remaining, got %v", remaining)
+	}
+	if remaining, _ := throttle.Check(ctx, "other@example.com"); remaining != 0 {
+		t.Errorf("expected other keys not to be locked, got %v", remaining)
+	}
+
+	clock.Advance(45 * time.Second)
+	if remaining, _ := throttle.Check(ctx, "user@example.com"); remaining != 15*time.Second {
+		t.Errorf("expected 15 seconds remaining, got %v", remaining)
+	}
+
+	// The next failure doubles the lockout
+	clock.Advance(time.Minute)
+	if remaining, _ := throttle.Check(ctx, "user@example.com"); remaining != 0 {
+		t.Errorf("expected the lockout to be over, got %v", remaining)
+	}
+	if failure, _ := throttle.Fail(ctx, "user@example.com"); failure.LockedFor != 2*time.Minute {
+		t.Errorf("expected a two minute lockout, got %v", failure.LockedFor)
+	}
+
+	// Failures are forgotten after the window
+	clock.Advance(testPolicy.Window + time.Minute)
+	if failure, _ := throttle.Fail(ctx, "user@example.com"); failure.Failures != 1 || failure.LockedFor != 0 {
+		t.Errorf("expected the count to start over, got %+v", failure)
+	}
+
+	if err := throttle.Reset(ctx, "user@example.com"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if failure, _ := throttle.Fail(ctx, "user@example.com"); failure.Failures != 1 {
+		t.Errorf("expected Reset to clear the count, got %+v", failure)
+	}
+}
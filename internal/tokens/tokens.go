@@ -0,0 +1,50 @@
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Random returns a URL-safe random token built from n random bytes.
+func Random(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Sign returns value followed by an HMAC-SHA256 signature of it, in the
+// form "<value>.<signature>". value must not contain a dot.
+func Sign(secret []byte, value string) string {
+	return value + "." + signature(secret, value)
+}
+
+// Verify checks a token produced by Sign and returns the signed value.
+func Verify(secret []byte, token string) (string, bool) {
+	value, sig, ok := strings.Cut(token, ".")
+	if !ok || value == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, value))) {
+		return "", false
+	}
+	return value, true
+}
+
+// Hash returns the hex encoded SHA-256 hash of a token, for storing tokens
+// without keeping the secret value around.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signature(secret []byte, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
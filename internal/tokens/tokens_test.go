@@ -0,0 +1,36 @@
+package tokens
+
+import "testing"
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("secret")
+	token := Sign(secret, "invitation-id")
+
+	value, ok := Verify(secret, token)
+	if !ok {
+		t.Fatal("expected token to verify")
+	}
+	if value != "invitation-id" {
+		t.Fatalf("expected value 'invitation-id', got %q", value)
+	}
+
+	if _, ok := Verify([]byte("other"), token); ok {
+		t.Fatal("expected token signed with another secret to be rejected")
+	}
+	if _, ok := Verify(secret, "other-id"+token[len("invitation-id"):]); ok {
+		t.Fatal("expected tampered token to be rejected")
+	}
+	if _, ok := Verify(secret, "no-signature"); ok {
+		t.Fatal("expected token without signature to be rejected")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	a, b := Random(32), Random(32)
+	if a == b {
+		t.Fatal("expected random tokens to differ")
+	}
+	if len(a) != 43 {
+		t.Fatalf("expected 43 characters, got %d", len(a))
+	}
+}
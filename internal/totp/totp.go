@@ -0,0 +1,94 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as used
+// by authenticator apps: HMAC-SHA1, 6 digits and a 30 second period.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// Skew is the number of periods before and after the current one whose
+	// codes are also accepted, to allow for clock drift
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random 160-bit secret, base32 encoded.
+func GenerateSecret() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return encoding.EncodeToString(b)
+}
+
+// URI returns the otpauth URI of a secret, which authenticator apps read
+// from a QR code.
+func URI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Counter returns the number of periods elapsed at t.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Code returns the code of a secret for a counter.
+func Code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %v", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1000000), nil
+}
+
+// Validate checks a code against the periods around t and returns the
+// counter it matched. Codes for counters up to after are rejected, so that
+// a code cannot be used twice when the caller stores the last counter.
+func Validate(secret, code string, t time.Time, after int64) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Counter(t)
+	for counter := current - Skew; counter <= current+Skew; counter++ {
+		if counter <= after {
+			continue
+		}
+		expected, err := Code(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return counter, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// The SHA1 test vectors of RFC 6238, appendix B, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(secret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := GenerateSecret()
+	now := time.Now()
+	counter := Counter(now)
+	code, err := Code(secret, counter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, ok := Validate(secret, code, now, 0); !ok || got != counter {
+		t.Errorf("expected the current code to be valid at counter %d, got %d %v", counter, got, ok)
+	}
+	if _, ok := Validate(secret, code, now.Add(Period), 0); !ok {
+		t.Error("expected the previous code to be accepted")
+	}
+	if _, ok := Validate(secret, code, now.Add(3*Period), 0); ok {
+		t.Error("expected an old code to be rejected")
+	}
+	if _, ok := Validate(secret, code, now, counter); ok {
+		t.Error("expected a used code to be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now, 0); ok {
+		t.Error("expected a short code to be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Test Alchemy", "user@example.com", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+	if uri.Path != "/Test Alchemy:user@example.com" {
+		t.Errorf("unexpected label %q", uri.Path)
+	}
+	if uri.Query().Get("secret") != "JBSWY3DPEHPK3PXP" || uri.Query().Get("issuer") != "Test Alchemy" {
+		t.Errorf("unexpected query %s", uri.RawQuery)
+	}
+}